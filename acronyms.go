@@ -0,0 +1,179 @@
+package struct2env
+
+import (
+	"sort"
+	"sync"
+	"unicode"
+)
+
+// Acronyms are mixed case words (e.g. OAuth2, IPv6, iOS) kept whole when splitting CamelCase names, where
+// the default rules of SplitByCase give O/Auth2/Token for OAuth2Token and I/Pv6/Addr for IPv6Addr.
+// A word matches where a word can start (not in the middle of an all caps one) when not followed by a
+// lowercase letter, the longest one first. See RegisterAcronyms for changing the default key names.
+type Acronyms []string
+
+var (
+	acronymsMutex sync.RWMutex
+	acronyms      [][]rune // Registered acronyms, longest first.
+)
+
+// RegisterAcronyms adds words to the acronyms SplitByCase, and so the CamelCaseTo... conversions and the
+// default key names, keep whole. Best called from an init function: the key names already computed for
+// struct types are recomputed.
+func RegisterAcronyms(words ...string) {
+	acronymsMutex.Lock()
+	// A new slice as the readers (see registeredAcronyms) keep using the current one unlocked.
+	updated := make([][]rune, len(acronyms), len(acronyms)+len(words))
+	copy(updated, acronyms)
+	for _, w := range words {
+		if w != "" {
+			updated = append(updated, []rune(w))
+		}
+	}
+	sort.SliceStable(updated, func(i, j int) bool { return len(updated[i]) > len(updated[j]) })
+	acronyms = updated
+	acronymsMutex.Unlock()
+	structInfos.Range(func(key, _ interface{}) bool {
+		structInfos.Delete(key)
+		return true
+	})
+}
+
+// registeredAcronyms returns the registered acronyms, nil when there are none. The returned slice
+// is never modified (RegisterAcronyms replaces it).
+func registeredAcronyms() [][]rune {
+	acronymsMutex.RLock()
+	defer acronymsMutex.RUnlock()
+	return acronyms
+}
+
+// SplitByCase is SplitByCase also keeping the acronyms a (and not the registered ones) whole.
+func (a Acronyms) SplitByCase(input string) []string {
+	return CaseSplitter{Acronyms: a}.SplitByCase(input)
+}
+
+// UpperSnakeCase is CamelCaseToUpperSnakeCase keeping the acronyms a whole (OAuth2Token gives OAUTH2_TOKEN),
+// e.g. for a KeyMapper{Field: acronyms.UpperSnakeCase, Sep: "_"} not depending on the registered ones.
+func (a Acronyms) UpperSnakeCase(s string) string {
+	return CaseSplitter{Acronyms: a}.UpperSnakeCase(s)
+}
+
+// runes returns the acronyms as runes, longest first.
+func (a Acronyms) runes() [][]rune {
+	res := make([][]rune, 0, len(a))
+	for _, w := range a {
+		if w != "" {
+			res = append(res, []rune(w))
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return len(res[i]) > len(res[j]) })
+	return res
+}
+
+// CaseSplitter is a SplitByCase with additional word break rules, the zero value splitting like SplitByCase
+// (without the registered acronyms).
+type CaseSplitter struct {
+	// Acronyms are kept whole, see Acronyms.
+	Acronyms Acronyms
+	// Digits makes digit/letter boundaries word breaks: http2Server gives http/2/Server.
+	Digits bool
+	// Unicode makes title case letters (e.g. ǅ) start words like uppercase ones and letters without case
+	// (e.g. CJK ideographs) continue words like lowercase ones.
+	Unicode bool
+}
+
+// SplitByCase splits input into words per the SplitByCase rules and the CaseSplitter ones.
+func (c CaseSplitter) SplitByCase(input string) []string {
+	return splitWords(input, c.splitter())
+}
+
+// UpperSnakeCase is CamelCaseToUpperSnakeCase with the CaseSplitter rules, e.g. for a KeyMapper (see KeyMapper).
+func (c CaseSplitter) UpperSnakeCase(s string) string {
+	return convertWords(s, '_', unicode.ToUpper, c.splitter())
+}
+
+// LowerSnakeCase is CamelCaseToLowerSnakeCase with the CaseSplitter rules.
+func (c CaseSplitter) LowerSnakeCase(s string) string {
+	return convertWords(s, '_', unicode.ToLower, c.splitter())
+}
+
+// KeyMapper returns the UPPER_SNAKE_CASE KeyMapper (like UpperSnakeKeys) using the CaseSplitter rules.
+func (c CaseSplitter) KeyMapper() KeyMapper {
+	return KeyMapper{Field: c.UpperSnakeCase, Sep: "_"}
+}
+
+func (c CaseSplitter) splitter() splitter {
+	return splitter{acronyms: c.Acronyms.runes(), digits: c.Digits, unicode: c.Unicode}
+}
+
+// splitter holds the word break rules in addition to the default SplitByCase ones.
+type splitter struct {
+	acronyms [][]rune // Longest first.
+	digits   bool
+	unicode  bool
+}
+
+// wordStarts returns, for each rune, whether a word starts there (besides the first one) per the SplitByCase
+// rules and the splitter ones. Returns nil when there are only the default rules (see isWordStart).
+func (sp splitter) wordStarts(runes []rune) []bool {
+	if len(sp.acronyms) == 0 && !sp.digits && !sp.unicode {
+		return nil
+	}
+	res := make([]bool, len(runes))
+	for i := 0; i < len(runes); {
+		start := sp.isWordStart(runes, i)
+		if n := matchAcronym(runes, i, start, sp.acronyms); n > 0 {
+			res[i] = i > 0
+			if i+n < len(runes) {
+				res[i+n] = true
+			}
+			i += n
+			continue
+		}
+		res[i] = res[i] || start
+		i++
+	}
+	return res
+}
+
+// isWordStart is isWordStart with the digits and unicode rules.
+func (sp splitter) isWordStart(runes []rune, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev, r := runes[i-1], runes[i]
+	if sp.digits && unicode.IsDigit(prev) != unicode.IsDigit(r) && (unicode.IsLetter(prev) || unicode.IsLetter(r)) {
+		return true
+	}
+	if !sp.unicode {
+		return isWordStart(runes, i)
+	}
+	last := (i == len(runes)-1)
+	return isUpperOrTitle(r) && (!last && isLowerOrCaseless(runes[i+1]) || isLowerOrCaseless(prev))
+}
+
+func isUpperOrTitle(r rune) bool {
+	return unicode.IsUpper(r) || unicode.IsTitle(r)
+}
+
+// isLowerOrCaseless returns whether r is a lowercase letter or a letter without case.
+func isLowerOrCaseless(r rune) bool {
+	return unicode.IsLower(r) || (unicode.IsLetter(r) && !isUpperOrTitle(r))
+}
+
+// matchAcronym returns the length of the acronym found at i, 0 if none is.
+func matchAcronym(runes []rune, i int, wordStart bool, acronyms [][]rune) int {
+	if i > 0 && !wordStart && unicode.IsUpper(runes[i-1]) {
+		return 0 // Inside an all caps word.
+	}
+	for _, acronym := range acronyms {
+		n := len(acronym)
+		if i+n > len(runes) || (i+n < len(runes) && unicode.IsLower(runes[i+n])) {
+			continue
+		}
+		if string(runes[i:i+n]) == string(acronym) {
+			return n
+		}
+	}
+	return 0
+}
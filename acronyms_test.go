@@ -0,0 +1,119 @@
+package struct2env
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAcronyms(t *testing.T) {
+	a := Acronyms{"IPv6", "OAuth2", "OAuth", "iOS"}
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"OAuth2Token", []string{"OAuth2", "Token"}},
+		{"OAuthToken", []string{"OAuth", "Token"}},
+		{"IPv6Addr", []string{"IPv6", "Addr"}},
+		{"ListenIPv6", []string{"Listen", "IPv6"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"iOSVersion", []string{"iOS", "Version"}},
+		{"MyOAuthor", []string{"My", "O", "Author"}}, // Followed by lowercase: not the acronym.
+		{"XIPv6", []string{"XI", "Pv6"}},             // Inside an all caps word: default rules.
+		{"http2OAuth2", []string{"http2", "OAuth2"}},
+	}
+	for _, test := range tests {
+		if got := a.SplitByCase(test.in); !reflect.DeepEqual(got, test.out) {
+			t.Errorf("mismatch for %q: got %v expected %v", test.in, got, test.out)
+		}
+	}
+	if got := a.UpperSnakeCase("ClientOAuth2Token"); got != "CLIENT_OAUTH2_TOKEN" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := CamelCaseToUpperSnakeCase("OAuth2Token"); got != "O_AUTH2_TOKEN" {
+		t.Errorf("unexpected default %q", got)
+	}
+}
+
+func TestCaseSplitter(t *testing.T) {
+	tests := []struct {
+		splitter CaseSplitter
+		in       string
+		out      []string
+	}{
+		{CaseSplitter{}, "http2Server", []string{"http2", "Server"}},
+		{CaseSplitter{Digits: true}, "http2Server", []string{"http", "2", "Server"}},
+		{CaseSplitter{Digits: true}, "ListenPort8080", []string{"Listen", "Port", "8080"}},
+		{CaseSplitter{Digits: true}, "Base64URL", []string{"Base", "64", "URL"}},
+		{CaseSplitter{Digits: true, Acronyms: Acronyms{"OAuth2"}}, "MyOAuth2Token", []string{"My", "OAuth2", "Token"}},
+		{CaseSplitter{}, "ÉtéÉté", []string{"Été", "Été"}},
+		{CaseSplitter{}, "ǅemalǅem", []string{"ǅemalǅem"}},
+		{CaseSplitter{Unicode: true}, "ǅemalǅem", []string{"ǅemal", "ǅem"}},
+		{CaseSplitter{}, "名前URL", []string{"名前URL"}},
+		{CaseSplitter{Unicode: true}, "名前URL", []string{"名前", "URL"}},
+		{CaseSplitter{Unicode: true}, "HTTPServer", []string{"HTTP", "Server"}},
+	}
+	for _, test := range tests {
+		if got := test.splitter.SplitByCase(test.in); !reflect.DeepEqual(got, test.out) {
+			t.Errorf("mismatch for %+v %q: got %v expected %v", test.splitter, test.in, got, test.out)
+		}
+	}
+	c := CaseSplitter{Digits: true}
+	if got := c.UpperSnakeCase("http2Server"); got != "HTTP_2_SERVER" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := c.LowerSnakeCase("Listen6Port"); got != "listen_6_port" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := CamelCaseToUpperSnakeCase("http2Server"); got != "HTTP2_SERVER" {
+		t.Errorf("unexpected default %q", got)
+	}
+	type Cfg struct {
+		Http2Port int
+	}
+	enc := NewEncoder()
+	enc.KeyMapper = c.KeyMapper()
+	kvl, errs := enc.StructToEnvVars(Cfg{Http2Port: 8080})
+	if len(errs) != 0 || len(kvl) != 1 || kvl[0].Key != "HTTP_2_PORT" {
+		t.Errorf("unexpected %v %v", kvl, errs)
+	}
+}
+
+func TestRegisterAcronyms(t *testing.T) {
+	defer func() {
+		acronymsMutex.Lock()
+		acronyms = nil
+		acronymsMutex.Unlock()
+		structInfos.Range(func(key, _ interface{}) bool {
+			structInfos.Delete(key)
+			return true
+		})
+	}()
+	type Cfg struct {
+		OAuth2Token string
+		IPv6Addr    string
+	}
+	kvl, _ := StructToEnvVars(Cfg{}) // Caches the default names.
+	if kvl[0].Key != "O_AUTH2_TOKEN" || kvl[1].Key != "I_PV6_ADDR" {
+		t.Errorf("unexpected default keys %v", kvl)
+	}
+	RegisterAcronyms("OAuth2", "IPv6")
+	if got := CamelCaseToLowerKebabCase("OAuth2Token"); got != "oauth2-token" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := CamelCaseToTrainCase("IPv6Addr"); got != "Ipv6-Addr" {
+		t.Errorf("unexpected %q", got)
+	}
+	kvl, _ = StructToEnvVars(Cfg{})
+	if kvl[0].Key != "OAUTH2_TOKEN" || kvl[1].Key != "IPV6_ADDR" {
+		t.Errorf("unexpected keys %v", kvl)
+	}
+	// The slice in use by the readers isn't changed by later registrations.
+	current := registeredAcronyms()
+	RegisterAcronyms("HTTPSProxy")
+	if len(current) != 2 || string(current[0]) != "OAuth2" || string(current[1]) != "IPv6" {
+		t.Errorf("registered acronyms modified in place: %q", current)
+	}
+	if got := registeredAcronyms(); len(got) != 3 || string(got[0]) != "HTTPSProxy" {
+		t.Errorf("unexpected %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package struct2env
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isTextArray returns whether t is an array type we know how to serialize as text: either one implementing
+// encoding.TextMarshaler or an array of bytes ([16]byte being treated as an UUID, others as base64).
+func isTextArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && (t.Implements(textMarshalerType) || t.Elem().Kind() == reflect.Uint8)
+}
+
+// isUUIDArray returns whether t is a [16]byte (or named equivalent).
+func isUUIDArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// appendUUID appends the canonical 8-4-4-4-12 lowercase hex form of u to dst.
+func appendUUID(dst []byte, u [16]byte) []byte {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return append(dst, buf[:]...)
+}
+
+// FormatUUID returns the canonical 8-4-4-4-12 lowercase hex form of u,
+// e.g. "f81d4fae-7dec-11d0-a765-00a0c91e6bf6".
+func FormatUUID(u [16]byte) string {
+	return string(appendUUID(nil, u))
+}
+
+// ParseUUID parses the canonical 8-4-4-4-12 hex form (in either case) of an UUID.
+func ParseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q: expecting xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx", s)
+	}
+	src := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(src)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	return u, nil
+}
+
+// serializeArray serializes the array v (for which isTextArray is true).
+func serializeArray(result *KeyValue, v reflect.Value) error {
+	t := v.Type()
+	if t.Implements(textMarshalerType) {
+		if !v.CanInterface() {
+			return fmt.Errorf("can't interface %v value", t)
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		return setString(result, string(text))
+	}
+	data := make([]byte, v.Len())
+	for i := range data {
+		data[i] = byte(v.Index(i).Uint())
+	}
+	if isUUIDArray(t) {
+		var u [16]byte
+		copy(u[:], data)
+		setSafeString(result, appendUUID(make([]byte, 0, 38), u))
+		return nil
+	}
+	setSafeString(result, []byte(base64.StdEncoding.EncodeToString(data)))
+	return nil
+}
+
+// setArray sets the array fieldValue from envVal: using its encoding.TextUnmarshaler implementation when
+// there is one, otherwise parsing an UUID for [16]byte and base64 for other byte arrays.
+func setArray(fieldValue reflect.Value, envName, envVal string) error {
+	t := fieldValue.Type()
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) && fieldValue.CanAddr() {
+		return fieldValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(envVal))
+	}
+	if t.Elem().Kind() != reflect.Uint8 {
+		return fmt.Errorf("unsupported array of %v to set from %s=%q", t.Elem().Kind(), envName, envVal)
+	}
+	if isUUIDArray(t) {
+		u, err := ParseUUID(envVal)
+		if err != nil {
+			return err
+		}
+		setBytes(fieldValue, u[:])
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(envVal)
+	if err != nil {
+		return err
+	}
+	if len(data) != t.Len() {
+		return fmt.Errorf("can't set %s: expecting %d bytes, got %d (found %s=%q)",
+			envName, t.Len(), len(data), envName, envVal)
+	}
+	setBytes(fieldValue, data)
+	return nil
+}
+
+// setBytes copies data into the byte array v (whose elements can be of a named byte type).
+func setBytes(v reflect.Value, data []byte) {
+	for i, b := range data {
+		v.Index(i).SetUint(uint64(b))
+	}
+}
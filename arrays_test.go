@@ -0,0 +1,81 @@
+package struct2env
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// KeyID is a fixed size id with its own text form.
+type KeyID [4]byte
+
+func (k KeyID) MarshalText() ([]byte, error) {
+	return []byte("kid-" + hex.EncodeToString(k[:])), nil
+}
+
+func (k *KeyID) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "kid-") {
+		return fmt.Errorf("invalid key id %q", s)
+	}
+	_, err := hex.Decode(k[:], []byte(s[4:]))
+	return err
+}
+
+type UUID [16]byte
+
+type ArraysConfig struct {
+	ID     UUID
+	Parent *[16]byte
+	Key    KeyID
+	Hash   [8]byte
+	Counts [2]int
+}
+
+func TestArrays(t *testing.T) {
+	id, err := ParseUUID("F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	parent := [16]byte{15: 1}
+	cfg := ArraysConfig{ID: id, Parent: &parent, Key: KeyID{1, 2, 3, 0xff}, Hash: [8]byte{'a', 'b', 'c'}}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("A_", kvl, false)
+	expected := `A_ID='f81d4fae-7dec-11d0-a765-00a0c91e6bf6'
+A_PARENT='00000000-0000-0000-0000-000000000001'
+A_KEY='kid-010203ff'
+A_HASH='YWJjAAAAAAA='
+export A_ID A_PARENT A_KEY A_HASH
+`
+	if str != expected {
+		t.Errorf("unexpected:\n%s\nvs:\n%s", str, expected)
+	}
+	env := map[string]string{}
+	for _, kv := range kvl {
+		env[kv.Key] = kv.Value
+	}
+	res := ArraysConfig{}
+	if errors = SetFrom(MapLookup(env), "", &res); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if res.ID != cfg.ID || *res.Parent != parent || res.Key != cfg.Key || res.Hash != cfg.Hash {
+		t.Errorf("unexpected %+v vs %+v", res, cfg)
+	}
+	bad := map[string]string{
+		"ID":     "f81d4fae7dec11d0a76500a0c91e6bf6",
+		"PARENT": "f81d4fae-7dec-11d0-a765-00a0c91e6bfg",
+		"KEY":    "010203ff",
+		"HASH":   "YWJj",
+		"COUNTS": "1,2",
+	}
+	if errors = SetFrom(MapLookup(bad), "", &res); len(errors) != len(bad) {
+		t.Errorf("expected %d errors, got %v", len(bad), errors)
+	}
+	if FormatUUID(id) != "f81d4fae-7dec-11d0-a765-00a0c91e6bf6" {
+		t.Errorf("unexpected %s", FormatUUID(id))
+	}
+}
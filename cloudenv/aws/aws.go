@@ -0,0 +1,108 @@
+// Package aws provides cloudenv Stores for AWS SSM Parameter Store and AWS Secrets Manager.
+// It is its own module so the AWS SDK isn't a dependency of struct2env.
+//
+//	store := aws.SSMStore(ssm.NewFromConfig(cfg))
+//	d, err := cloudenv.NewDecoder(ctx, store, "/myapp/prod/", cloudenv.SSMSep)
+//	errs := d.SetFrom("", &cfg)
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fortio.org/struct2env/cloudenv"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMStore returns a cloudenv.Store listing the (decrypted) SSM parameters under the prefix path, recursively.
+// client is usually a *ssm.Client.
+func SSMStore(client ssm.GetParametersByPathAPIClient) cloudenv.Store {
+	return cloudenv.StoreFunc(func(ctx context.Context, prefix string) (map[string]string, error) {
+		path := prefix
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, cloudenv.SSMSep)
+		}
+		recursive, decrypt := true, true
+		p := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
+			Path: &path, Recursive: &recursive, WithDecryption: &decrypt,
+		})
+		res := map[string]string{}
+		for p.HasMorePages() {
+			out, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, param := range out.Parameters {
+				if param.Name != nil && param.Value != nil {
+					res[*param.Name] = *param.Value
+				}
+			}
+		}
+		return res, nil
+	})
+}
+
+// SecretValueClient is the subset of *secretsmanager.Client used by LoadSecret.
+type SecretValueClient interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput,
+		optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SecretsManagerClient is the subset of *secretsmanager.Client used by SecretsManagerStore.
+type SecretsManagerClient interface {
+	secretsmanager.ListSecretsAPIClient
+	SecretValueClient
+}
+
+// SecretsManagerStore returns a cloudenv.Store listing the secrets whose name starts with the prefix
+// (e.g. /myapp/prod/db/password under /myapp/prod/), with their string values.
+func SecretsManagerStore(client SecretsManagerClient) cloudenv.Store {
+	return cloudenv.StoreFunc(func(ctx context.Context, prefix string) (map[string]string, error) {
+		p := secretsmanager.NewListSecretsPaginator(client, &secretsmanager.ListSecretsInput{
+			Filters: []smtypes.Filter{{Key: smtypes.FilterNameStringTypeName, Values: []string{prefix}}},
+		})
+		res := map[string]string{}
+		for p.HasMorePages() {
+			out, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range out.SecretList {
+				// The service side name filter isn't a strict prefix match.
+				if entry.Name == nil || !strings.HasPrefix(*entry.Name, prefix) {
+					continue
+				}
+				value, err := secretString(ctx, client, *entry.Name)
+				if err != nil {
+					return nil, err
+				}
+				res[*entry.Name] = value
+			}
+		}
+		return res, nil
+	})
+}
+
+// LoadSecret reads the key/value (JSON object) secret secretID and returns it as an environment
+// variable map usable with struct2env.MapLookup (see cloudenv.SecretJSON).
+func LoadSecret(ctx context.Context, client SecretValueClient, secretID string) (map[string]string, error) {
+	value, err := secretString(ctx, client, secretID)
+	if err != nil {
+		return nil, err
+	}
+	return cloudenv.SecretJSON(value)
+}
+
+func secretString(ctx context.Context, client SecretValueClient, id string) (string, error) {
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &id})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", id)
+	}
+	return *out.SecretString, nil
+}
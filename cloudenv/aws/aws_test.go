@@ -0,0 +1,122 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"fortio.org/struct2env/cloudenv"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type fakeSSM struct {
+	pages [][]ssmtypes.Parameter
+	paths []string
+}
+
+func (f *fakeSSM) GetParametersByPath(_ context.Context, in *ssm.GetParametersByPathInput,
+	_ ...func(*ssm.Options),
+) (*ssm.GetParametersByPathOutput, error) {
+	f.paths = append(f.paths, *in.Path)
+	if *in.Path == "/fail" {
+		return nil, errors.New("access denied")
+	}
+	i := 0
+	if in.NextToken != nil {
+		i = 1
+	}
+	out := &ssm.GetParametersByPathOutput{Parameters: f.pages[i]}
+	if i+1 < len(f.pages) {
+		next := "next"
+		out.NextToken = &next
+	}
+	return out, nil
+}
+
+func param(name, value string) ssmtypes.Parameter {
+	return ssmtypes.Parameter{Name: &name, Value: &value}
+}
+
+type Config struct {
+	Name string
+	DB   struct {
+		Host     string
+		MaxConns int
+	}
+}
+
+func TestSSMStore(t *testing.T) {
+	client := &fakeSSM{pages: [][]ssmtypes.Parameter{
+		{param("/myapp/prod/name", "app"), param("/myapp/prod/db/host", "db.local")},
+		{param("/myapp/prod/db/max-conns", "10")},
+	}}
+	ctx := context.Background()
+	d, err := cloudenv.NewDecoder(ctx, SSMStore(client), "/myapp/prod/", cloudenv.SSMSep)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var cfg Config
+	if errs := d.SetFrom("", &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Name != "app" || cfg.DB.Host != "db.local" || cfg.DB.MaxConns != 10 {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if len(client.paths) != 2 || client.paths[0] != "/myapp/prod" {
+		t.Errorf("unexpected paths %v", client.paths)
+	}
+	if _, err = SSMStore(client).List(ctx, "/fail/"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+type fakeSecrets struct {
+	secrets map[string]string
+}
+
+func (f *fakeSecrets) ListSecrets(_ context.Context, _ *secretsmanager.ListSecretsInput,
+	_ ...func(*secretsmanager.Options),
+) (*secretsmanager.ListSecretsOutput, error) {
+	out := &secretsmanager.ListSecretsOutput{}
+	for name := range f.secrets {
+		name := name
+		out.SecretList = append(out.SecretList, smtypes.SecretListEntry{Name: &name})
+	}
+	return out, nil
+}
+
+func (f *fakeSecrets) GetSecretValue(_ context.Context, in *secretsmanager.GetSecretValueInput,
+	_ ...func(*secretsmanager.Options),
+) (*secretsmanager.GetSecretValueOutput, error) {
+	value, found := f.secrets[*in.SecretId]
+	if !found {
+		return nil, errors.New("not found")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: &value}, nil
+}
+
+func TestSecretsManagerStore(t *testing.T) {
+	client := &fakeSecrets{secrets: map[string]string{
+		"/myapp/prod/db/password": "s3cr3t",
+		"/other/myapp/prod/token": "x",
+		"/myapp/prod/kv":          `{"USER":"admin","PORT":5432}`,
+	}}
+	ctx := context.Background()
+	env, err := cloudenv.Load(ctx, SecretsManagerStore(client), "/myapp/prod/", cloudenv.SSMSep)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(env) != 2 || env["DB_PASSWORD"] != "s3cr3t" {
+		t.Errorf("unexpected %v", env)
+	}
+	kv, err := LoadSecret(ctx, client, "/myapp/prod/kv")
+	if err != nil || kv["USER"] != "admin" || kv["PORT"] != "5432" {
+		t.Errorf("unexpected %v %v", kv, err)
+	}
+	if _, err = LoadSecret(ctx, client, "missing"); err == nil {
+		t.Errorf("expected error")
+	}
+}
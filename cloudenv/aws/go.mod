@@ -0,0 +1,20 @@
+module fortio.org/struct2env/cloudenv/aws
+
+// Separate module so the AWS SDK isn't a dependency of struct2env itself.
+go 1.20
+
+require (
+	fortio.org/struct2env v0.4.0
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.32.3
+	github.com/aws/aws-sdk-go-v2/service/ssm v1.52.3
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.30.3 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.15 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.15 // indirect
+	github.com/aws/smithy-go v1.20.3 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+)
+
+replace fortio.org/struct2env => ../..
@@ -0,0 +1,40 @@
+// Package azure provides a cloudenv Store for Azure App Configuration.
+// It is its own module so the Azure SDK isn't a dependency of struct2env.
+//
+//	client, err := azappconfig.NewClient(endpoint, credential, nil)
+//	d, err := cloudenv.NewDecoder(ctx, azure.Store(client, "prod"), "myapp:", cloudenv.AzureSep)
+//	errs := d.SetFrom("", &cfg)
+package azure
+
+import (
+	"context"
+
+	"fortio.org/struct2env/cloudenv"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+)
+
+// Store returns a cloudenv.Store listing the settings whose key starts with the prefix.
+// A non empty label only lists the settings with that label.
+func Store(client *azappconfig.Client, label string) cloudenv.Store {
+	return cloudenv.StoreFunc(func(ctx context.Context, prefix string) (map[string]string, error) {
+		keyFilter := prefix + "*"
+		selector := azappconfig.SettingSelector{KeyFilter: &keyFilter}
+		if label != "" {
+			selector.LabelFilter = &label
+		}
+		pager := client.NewListSettingsPager(selector, nil)
+		res := map[string]string{}
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, setting := range page.Settings {
+				if setting.Key != nil && setting.Value != nil {
+					res[*setting.Key] = *setting.Value
+				}
+			}
+		}
+		return res, nil
+	})
+}
@@ -0,0 +1,74 @@
+package azure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"fortio.org/struct2env/cloudenv"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+)
+
+// fakeTransport answers the list settings requests with two pages of settings.
+type fakeTransport struct {
+	queries []string
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	f.queries = append(f.queries, req.URL.RawQuery)
+	body := `{"items":[{"key":"myapp:name","value":"app"},{"key":"myapp:db:host","value":"db.local"}],` +
+		`"@nextLink":"/kv?after=x&api-version=2023-10-01"}`
+	if req.URL.Query().Get("after") != "" {
+		body = `{"items":[{"key":"myapp:db:max-conns","value":"10"},{"key":"myapp:nil"}]}`
+	}
+	if req.URL.Query().Get("label") == "fail" {
+		return &http.Response{
+			StatusCode: http.StatusForbidden, Request: req, Header: http.Header{},
+			Body: io.NopCloser(strings.NewReader(`{"title":"denied"}`)),
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK, Request: req,
+		Header: http.Header{"Content-Type": []string{"application/json"}, "Sync-Token": []string{"id=1;sn=1"}},
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+type Config struct {
+	Name string
+	DB   struct {
+		Host     string
+		MaxConns int
+	}
+}
+
+func TestStore(t *testing.T) {
+	transport := &fakeTransport{}
+	client, err := azappconfig.NewClientFromConnectionString("Endpoint=https://test.azconfig.io;Id=id;Secret=c2VjcmV0",
+		&azappconfig.ClientOptions{ClientOptions: azcore.ClientOptions{Transport: transport}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ctx := context.Background()
+	d, err := cloudenv.NewDecoder(ctx, Store(client, "prod"), "myapp:", cloudenv.AzureSep)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var cfg Config
+	if errs := d.SetFrom("", &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Name != "app" || cfg.DB.Host != "db.local" || cfg.DB.MaxConns != 10 {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if len(transport.queries) != 2 || !strings.Contains(transport.queries[0], "key=myapp%3A%2A") ||
+		!strings.Contains(transport.queries[0], "label=prod") {
+		t.Errorf("unexpected queries %v", transport.queries)
+	}
+	if _, err = Store(client, "fail").List(ctx, "myapp:"); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,18 @@
+module fortio.org/struct2env/cloudenv/azure
+
+// Separate module so the Azure SDK isn't a dependency of struct2env itself.
+go 1.20
+
+require (
+	fortio.org/struct2env v0.4.0
+	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.9.1
+	github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig v1.1.0
+)
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/internal v1.5.1 // indirect
+	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace fortio.org/struct2env => ../..
@@ -0,0 +1,122 @@
+// Package cloudenv drives struct2env decoding from cloud configuration stores: AWS SSM Parameter Store,
+// AWS Secrets Manager and Azure App Configuration. The store names under a path prefix are mapped to
+// environment variable names (/myapp/prod/db/host under /myapp/prod/ gives DB_HOST) so the same structs
+// and SetFrom work with either the environment or the store.
+//
+// To keep struct2env dependency-free, it doesn't import the cloud SDKs: the store adapters are in
+// their own modules, fortio.org/struct2env/cloudenv/aws (SSMStore, SecretsManagerStore, LoadSecret)
+// and fortio.org/struct2env/cloudenv/azure (Store), e.g. for SSM
+//
+//	d, err := cloudenv.NewDecoder(ctx, aws.SSMStore(ssm.NewFromConfig(cfg)), "/myapp/prod/", cloudenv.SSMSep)
+//	errs := d.SetFrom("", &config)
+//
+// Any other store can be connected by wrapping its listing call in a StoreFunc.
+package cloudenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fortio.org/struct2env"
+)
+
+const (
+	// SSMSep is the AWS SSM Parameter Store hierarchy separator.
+	SSMSep = "/"
+	// AzureSep is the Azure App Configuration conventional key separator.
+	AzureSep = ":"
+)
+
+// Store lists the configuration entries of a cloud store.
+type Store interface {
+	// List returns all the entries (full name to value) whose name starts with prefix.
+	List(ctx context.Context, prefix string) (map[string]string, error)
+}
+
+// StoreFunc adapts a function to the Store interface.
+type StoreFunc func(ctx context.Context, prefix string) (map[string]string, error)
+
+// List calls f.
+func (f StoreFunc) List(ctx context.Context, prefix string) (map[string]string, error) {
+	return f(ctx, prefix)
+}
+
+// EnvName maps a store entry name under prefix to an environment variable name: the prefix is
+// removed, the sep separated segments are joined with _ and uppercased, - and . are replaced by _.
+// E.g. EnvName("/myapp/prod/db/max-conns", "/myapp/prod/", "/") is "DB_MAX_CONNS".
+// Returns false if name isn't under prefix.
+func EnvName(name, prefix, sep string) (string, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	name = strings.Trim(name[len(prefix):], sep)
+	if name == "" {
+		return "", false
+	}
+	name = strings.ReplaceAll(name, sep, "_")
+	name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	return strings.ToUpper(name), true
+}
+
+// Load lists the entries of s under prefix and returns them keyed by their environment
+// variable names (see EnvName). Two entries mapping to the same name are an error.
+func Load(ctx context.Context, s Store, prefix, sep string) (map[string]string, error) {
+	entries, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make(map[string]string, len(entries))
+	from := make(map[string]string, len(entries))
+	for _, name := range names {
+		envName, ok := EnvName(name, prefix, sep)
+		if !ok {
+			continue
+		}
+		if other, found := from[envName]; found {
+			return nil, fmt.Errorf("%s and %s both map to %s", other, name, envName)
+		}
+		from[envName] = name
+		res[envName] = entries[name]
+	}
+	return res, nil
+}
+
+// NewDecoder returns a struct2env.Decoder over the entries of s under prefix, loaded once (see Load),
+// with its Keys set so map fields work too.
+func NewDecoder(ctx context.Context, s Store, prefix, sep string) (*struct2env.Decoder, error) {
+	env, err := Load(ctx, s, prefix, sep)
+	if err != nil {
+		return nil, err
+	}
+	d := struct2env.NewDecoder(struct2env.MapLookup(env))
+	d.Keys = struct2env.MapKeys(env)
+	return d, nil
+}
+
+// SecretJSON decodes the usual AWS Secrets Manager key/value secret (a JSON object, as stored
+// by the console) into an environment variable map usable with struct2env.MapLookup. Non string
+// values are kept in their JSON form.
+func SecretJSON(secret string) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(secret), &raw); err != nil {
+		return nil, fmt.Errorf("invalid key/value secret: %w", err)
+	}
+	res := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			res[k] = s
+		} else {
+			res[k] = string(v)
+		}
+	}
+	return res, nil
+}
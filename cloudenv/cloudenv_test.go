@@ -0,0 +1,89 @@
+package cloudenv
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type DB struct {
+	Host     string
+	MaxConns int
+}
+
+type Config struct {
+	Name   string
+	DB     DB
+	Labels map[string]string
+}
+
+func fakeStore(entries map[string]string) Store {
+	return StoreFunc(func(_ context.Context, prefix string) (map[string]string, error) {
+		if prefix == "/fail/" {
+			return nil, errors.New("access denied")
+		}
+		return entries, nil
+	})
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		name, prefix, sep, expected string
+	}{
+		{"/myapp/prod/db/max-conns", "/myapp/prod/", SSMSep, "DB_MAX_CONNS"},
+		{"/myapp/prod/name", "/myapp/prod", SSMSep, "NAME"},
+		{"myapp:db:host", "myapp:", AzureSep, "DB_HOST"},
+		{"/other/name", "/myapp/", SSMSep, ""},
+		{"/myapp/", "/myapp/", SSMSep, ""},
+	}
+	for _, test := range tests {
+		got, ok := EnvName(test.name, test.prefix, test.sep)
+		if got != test.expected || ok != (test.expected != "") {
+			t.Errorf("EnvName(%q, %q) got %q %v, expected %q", test.name, test.prefix, got, ok, test.expected)
+		}
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	store := fakeStore(map[string]string{
+		"/myapp/prod/name":          "app",
+		"/myapp/prod/db/host":       "db.local",
+		"/myapp/prod/db/max-conns":  "10",
+		"/myapp/prod/labels/team":   "infra",
+		"/myapp/staging/db/host":    "other",
+		"/myapp/prod/unknown/thing": "ignored",
+	})
+	ctx := context.Background()
+	d, err := NewDecoder(ctx, store, "/myapp/prod/", SSMSep)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cfg := Config{}
+	if errs := d.SetFrom("", &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Name != "app" || cfg.DB.Host != "db.local" || cfg.DB.MaxConns != 10 || cfg.Labels["team"] != "infra" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if _, err = NewDecoder(ctx, store, "/fail/", SSMSep); err == nil {
+		t.Errorf("expected error")
+	}
+	dup := fakeStore(map[string]string{"app:db:host": "a", "app:db.host": "b"})
+	_, err = Load(ctx, dup, "app:", AzureSep)
+	if err == nil || err.Error() != "app:db.host and app:db:host both map to DB_HOST" {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestSecretJSON(t *testing.T) {
+	env, err := SecretJSON(`{"DB_PASSWORD": "s3cr3t", "DB_PORT": 5432}`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if env["DB_PASSWORD"] != "s3cr3t" || env["DB_PORT"] != "5432" {
+		t.Errorf("unexpected %v", env)
+	}
+	if _, err = SecretJSON("not json"); err == nil {
+		t.Errorf("expected error")
+	}
+}
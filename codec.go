@@ -0,0 +1,80 @@
+package struct2env
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Codec is a custom representation for the fields tagged with the `codec=name` option, for one-off
+// fields (e.g. weird legacy formats) without having to introduce a type. See Options.RegisterCodec.
+type Codec struct {
+	// Format returns the string representation of the field's value, passed as is (pointers included).
+	// Used by the Encoder.
+	Format func(v interface{}) (string, error)
+	// Parse returns the value (assignable or convertible to the field's type) represented by s.
+	// Used by the Decoder.
+	Parse func(s string) (interface{}, error)
+}
+
+// RegisterCodec makes c available to the fields tagged `codec=name`.
+func (o *Options) RegisterCodec(name string, c Codec) {
+	if o.Codecs == nil {
+		o.Codecs = make(map[string]Codec)
+	}
+	o.Codecs[name] = c
+}
+
+// codec returns the Codec named name, erroring out if there is none.
+func (o *Options) codec(name, fieldName string) (Codec, error) {
+	c, found := o.Codecs[name]
+	if !found {
+		return c, fmt.Errorf("unknown codec %q for %s", name, fieldName)
+	}
+	return c, nil
+}
+
+// formatCodec sets result to the representation of v using the named codec.
+func (e *Encoder) formatCodec(result *KeyValue, name, fieldName string, v reflect.Value) error {
+	c, err := e.codec(name, fieldName)
+	if err != nil {
+		return err
+	}
+	if c.Format == nil {
+		return fmt.Errorf("codec %q can't format %s", name, fieldName)
+	}
+	if !v.CanInterface() {
+		return fmt.Errorf("can't interface %s", fieldName)
+	}
+	str, err := c.Format(v.Interface())
+	if err != nil {
+		return fmt.Errorf("codec %q error formatting %s: %w", name, fieldName, err)
+	}
+	return setString(result, str)
+}
+
+// setCodec sets fieldValue from envVal using the named codec.
+func (d *Decoder) setCodec(name, fieldName string, fieldValue reflect.Value, envName, envVal string) error {
+	c, err := d.codec(name, fieldName)
+	if err != nil {
+		return err
+	}
+	if c.Parse == nil {
+		return fmt.Errorf("codec %q can't parse %s", name, fieldName)
+	}
+	res, err := c.Parse(envVal)
+	if err != nil {
+		return fmt.Errorf("can't set %s using codec %q (found %s=%q): %w", fieldName, name, envName, envVal, err)
+	}
+	v := reflect.ValueOf(res)
+	switch {
+	case !v.IsValid():
+		fieldValue.Set(reflect.Zero(fieldValue.Type()))
+	case v.Type().AssignableTo(fieldValue.Type()):
+		fieldValue.Set(v)
+	case v.Type().ConvertibleTo(fieldValue.Type()):
+		fieldValue.Set(v.Convert(fieldValue.Type()))
+	default:
+		return fmt.Errorf("codec %q returned a %v, not usable for %s (%v)", name, v.Type(), fieldName, fieldValue.Type())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package struct2env
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type Point struct {
+	X, Y int
+}
+
+type CodecConfig struct {
+	Mask   int    `env:",codec=hex"`
+	Origin Point  `env:",codec=point"`
+	Ports  []int  `env:",codec=ports"`
+	Name   string `env:",codec=nope"`
+}
+
+func codecOptions() Options {
+	o := Options{}
+	o.RegisterCodec("hex", Codec{
+		Format: func(v interface{}) (string, error) { return fmt.Sprintf("0x%x", v), nil },
+		Parse: func(s string) (interface{}, error) {
+			return strconv.ParseInt(strings.TrimPrefix(s, "0x"), 16, 64) // int64 converted to int.
+		},
+	})
+	o.RegisterCodec("point", Codec{
+		Format: func(v interface{}) (string, error) {
+			p := v.(Point)
+			return fmt.Sprintf("%d:%d", p.X, p.Y), nil
+		},
+		Parse: func(s string) (interface{}, error) {
+			p := Point{}
+			_, err := fmt.Sscanf(s, "%d:%d", &p.X, &p.Y)
+			return p, err
+		},
+	})
+	o.RegisterCodec("ports", Codec{
+		Parse: func(s string) (interface{}, error) {
+			var res []int
+			for _, p := range strings.Split(s, ",") {
+				port, err := strconv.Atoi(p)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, port)
+			}
+			return res, nil
+		},
+	})
+	return o
+}
+
+func TestCodec(t *testing.T) {
+	e := NewEncoder()
+	e.Options = codecOptions()
+	cfg := CodecConfig{Mask: 255, Origin: Point{3, -4}, Ports: []int{80}}
+	kvl, errs := e.StructToEnvVars(cfg)
+	if len(errs) != 2 { // ports can't format and nope doesn't exist.
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+	if str := ToShellWithPrefix("", kvl, true); str != "MASK='0xff'\nORIGIN='3:-4'\n" {
+		t.Errorf("unexpected %q", str)
+	}
+	d := NewDecoder(MapLookup(map[string]string{"MASK": "0x10", "ORIGIN": "1:2", "PORTS": "80,443", "NAME": "x"}))
+	d.Options = codecOptions()
+	res := CodecConfig{}
+	errs = d.SetFrom("", &res)
+	if len(errs) != 1 || errs[0].Error() != `unknown codec "nope" for Name` {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if res.Mask != 16 || res.Origin != (Point{1, 2}) || len(res.Ports) != 2 || res.Ports[1] != 443 {
+		t.Errorf("unexpected %+v", res)
+	}
+	d.Lookup = MapLookup(map[string]string{"MASK": "0xzz", "PORTS": "80,x"})
+	errs = d.SetFrom("", &res)
+	var numErr *strconv.NumError
+	if len(errs) != 2 || !errors.As(errs[0], &numErr) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	d.RegisterCodec("hex", Codec{Parse: func(s string) (interface{}, error) { return s, nil }})
+	d.Lookup = MapLookup(map[string]string{"MASK": "10"})
+	if errs = d.SetFrom("", &res); len(errs) != 1 { // string can't be used for int.
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
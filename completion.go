@@ -0,0 +1,82 @@
+package struct2env
+
+import (
+	"strings"
+)
+
+// completionFuncName returns a shell function name derived from name, e.g. _myapp_env_vars.
+func completionFuncName(name string) string {
+	var sb strings.Builder
+	sb.WriteString("_")
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	sb.WriteString("_env_vars")
+	return sb.String()
+}
+
+// BashCompletion returns a bash snippet (to source, e.g. from ~/.bashrc) completing the names of vars,
+// followed by =, as arguments of the given commands (defaults to env); so `env MYAPP_<TAB>` offers the
+// supported variables. Other arguments get the default (file names) completion. name is the application
+// name, used to name the completion function.
+func BashCompletion(name string, vars []EnvVarInfo, commands ...string) string {
+	if len(commands) == 0 {
+		commands = []string{"env"}
+	}
+	fn := completionFuncName(name)
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		names = append(names, v.Name+"=")
+	}
+	var sb strings.Builder
+	sb.WriteString("# bash completion of the " + name + " environment variables\n")
+	sb.WriteString(fn + "() {\n")
+	sb.WriteString("  local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	sb.WriteString("  COMPREPLY=($(compgen -W " + shellQuoteOrEmpty(strings.Join(names, " ")) + " -- \"$cur\"))\n")
+	sb.WriteString("  if [ ${#COMPREPLY[@]} -gt 0 ]; then compopt -o nospace; fi\n")
+	sb.WriteString("}\n")
+	sb.WriteString("complete -o default -F " + fn + " " + strings.Join(commands, " ") + "\n")
+	return sb.String()
+}
+
+// ZshCompletion returns a zsh snippet (to source after compinit) completing the names of vars, with their
+// description, as arguments of the given commands (defaults to env). See BashCompletion.
+func ZshCompletion(name string, vars []EnvVarInfo, commands ...string) string {
+	if len(commands) == 0 {
+		commands = []string{"env"}
+	}
+	fn := completionFuncName(name)
+	var sb strings.Builder
+	sb.WriteString("# zsh completion of the " + name + " environment variables\n")
+	sb.WriteString(fn + "() {\n")
+	sb.WriteString("  local -a vars\n")
+	sb.WriteString("  vars=(\n")
+	for _, v := range vars {
+		entry := strings.ReplaceAll(v.Name, ":", "\\:")
+		if v.Help != "" {
+			entry += ":" + strings.Join(strings.Fields(v.Help), " ")
+		}
+		sb.WriteString("    " + shellQuoteOrEmpty(entry) + "\n")
+	}
+	sb.WriteString("  )\n")
+	sb.WriteString("  _describe -t " + fn[1:] + " " + shellQuoteOrEmpty(name+" environment variables") +
+		" vars -S '=' && return 0\n")
+	sb.WriteString("  _default\n")
+	sb.WriteString("}\n")
+	sb.WriteString("compdef " + fn + " " + strings.Join(commands, " ") + "\n")
+	return sb.String()
+}
+
+// shellQuoteOrEmpty is ShellQuote for values known not to contain NUL (names and descriptions
+// from struct tags); it returns an empty quoted string otherwise.
+func shellQuoteOrEmpty(s string) string {
+	q, err := ShellQuote(s)
+	if err != nil {
+		return "''"
+	}
+	return q
+}
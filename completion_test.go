@@ -0,0 +1,47 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	type Cfg struct {
+		Port  int    `help:"Port to listen on."`
+		Token string `env:",secret" help:"It's a\n secret."`
+	}
+	vars, errs := DescribeEnvVars("MY_APP_", Cfg{Port: 8080, Token: "abc"})
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if vars[0].Name != "MY_APP_PORT" || vars[0].Value != "8080" || !vars[0].Set || vars[1].Value != SecretMask {
+		t.Errorf("unexpected description %+v", vars)
+	}
+	bash := BashCompletion("my-app", vars)
+	expected := `# bash completion of the my-app environment variables
+_my_app_env_vars() {
+  local cur="${COMP_WORDS[COMP_CWORD]}"
+  COMPREPLY=($(compgen -W 'MY_APP_PORT= MY_APP_TOKEN=' -- "$cur"))
+  if [ ${#COMPREPLY[@]} -gt 0 ]; then compopt -o nospace; fi
+}
+complete -o default -F _my_app_env_vars env
+`
+	if bash != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, bash)
+	}
+	zsh := ZshCompletion("my-app", vars, "env", "my-app")
+	expected = `# zsh completion of the my-app environment variables
+_my_app_env_vars() {
+  local -a vars
+  vars=(
+    'MY_APP_PORT:Port to listen on.'
+    'MY_APP_TOKEN:It'\''s a secret.'
+  )
+  _describe -t my_app_env_vars 'my-app environment variables' vars -S '=' && return 0
+  _default
+}
+compdef _my_app_env_vars env my-app
+`
+	if zsh != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, zsh)
+	}
+}
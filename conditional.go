@@ -0,0 +1,81 @@
+package struct2env
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// fieldsOrder returns the indexes of the fields (parsed tags of a struct type), the ones with a `when=`
+// guard last so the guards are set before being evaluated.
+func fieldsOrder(fields []fieldInfo) []int {
+	res := make([]int, 0, len(fields))
+	var conditional []int
+	for i := range fields {
+		if _, found := fields[i].opts.Get("when"); found {
+			conditional = append(conditional, i)
+		} else {
+			res = append(res, i)
+		}
+	}
+	return append(res, conditional...)
+}
+
+// findField returns the field of the struct v whose Go name or env name (without prefix) is name.
+func findField(v reflect.Value, o *Options, name string) (reflect.Value, bool) {
+	t := v.Type()
+	info := cachedStructInfo(t)
+	for i := 0; i < t.NumField(); i++ {
+		if info.fields[i].tag == "-" {
+			continue
+		}
+		fieldName := t.Field(i).Name
+		if fieldName == name || info.key(i, fieldName, o) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// isSet returns whether v is true/non zero, for pointers whether they point to such a value.
+func isSet(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	return !v.IsZero()
+}
+
+// guardActive returns whether the guard field of v (see the `when=` option) is set.
+func guardActive(v reflect.Value, o *Options, guard string) (bool, error) {
+	guardValue, found := findField(v, o, guard)
+	if !found {
+		return false, fmt.Errorf("when guard field %s not found", guard)
+	}
+	return isSet(guardValue), nil
+}
+
+// checkRequires reports the fields of v (a struct) which are set while the fields listed
+// in their `requires=` options are not, i.e. partially configured feature blocks.
+func checkRequires(allErrors []error, path string, v reflect.Value, o *Options) []error {
+	t := v.Type()
+	info := cachedStructInfo(t)
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		requires := info.fields[i].opts.Values("requires")
+		if len(requires) == 0 || !isSet(v.Field(i)) {
+			continue
+		}
+		for _, name := range requires {
+			other, found := findField(v, o, name)
+			switch {
+			case !found:
+				allErrors = append(allErrors, fmt.Errorf("%s%s: required field %s not found", path, fieldType.Name, name))
+			case !isSet(other):
+				allErrors = append(allErrors, fmt.Errorf("%s%s is set so %s%s is required", path, fieldType.Name, path, name))
+			}
+		}
+	}
+	return allErrors
+}
@@ -0,0 +1,69 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+type TLSConfig struct {
+	Cert string `env:",when=TLS_ENABLED"`
+	Key  string `env:",when=Enabled"`
+	// Declared after the fields it guards on purpose.
+	Enabled bool `env:"TLS_ENABLED,requires=Cert,requires=Key"`
+}
+
+type CondConfig struct {
+	TLS   TLSConfig
+	Debug *bool
+	Level string `env:",when=Debug"`
+	Bad   string `env:",when=Nope"`
+}
+
+func TestConditional(t *testing.T) {
+	env := map[string]string{"TLS_TLS_ENABLED": "false", "TLS_CERT": "c.pem", "LEVEL": "verbose"}
+	cfg := CondConfig{}
+	errors := SetFrom(MapLookup(env), "", &cfg)
+	if len(errors) != 1 || errors[0].Error() != "Bad: when guard field Nope not found" {
+		t.Errorf("expected 1 guard error, got %v", errors)
+	}
+	if cfg.TLS.Cert != "" || cfg.Level != "" {
+		t.Errorf("guarded fields should not be read: %+v", cfg)
+	}
+	env["TLS_TLS_ENABLED"] = "true"
+	env["DEBUG"] = "false" // non nil pointer to false is not set.
+	errors = SetFrom(MapLookup(env), "", &cfg)
+	if len(errors) != 2 || errors[0].Error() != "TLS.Enabled is set so TLS.Key is required" {
+		t.Errorf("expected missing key error, got %v", errors)
+	}
+	if cfg.TLS.Cert != "c.pem" || cfg.Level != "" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	env["TLS_KEY"] = "k.pem"
+	env["DEBUG"] = "true"
+	errors = SetFrom(MapLookup(env), "", &cfg)
+	if len(errors) != 1 || cfg.TLS.Key != "k.pem" || cfg.Level != "verbose" {
+		t.Errorf("unexpected %v %+v", errors, cfg)
+	}
+	type Wrong struct {
+		A bool `env:",requires=Missing"`
+	}
+	if errors := SetFrom(MapLookup(map[string]string{"A": "true"}), "", &Wrong{}); len(errors) != 1 {
+		t.Errorf("expected error for unknown requires, got %v", errors)
+	}
+}
+
+func TestConditionalKeyMapper(t *testing.T) {
+	type Cfg struct {
+		Host    string `env:",when=proxy_on"`
+		ProxyOn bool   `env:",requires=host"`
+	}
+	d := NewDecoder(MapLookup(map[string]string{"proxy_on": "true", "host": "h"}))
+	d.KeyMapper = LowerSnakeKeys
+	cfg := Cfg{}
+	if errors := d.SetFrom("", &cfg); len(errors) != 0 || cfg.Host != "h" || !cfg.ProxyOn {
+		t.Errorf("unexpected %v %+v", errors, cfg)
+	}
+	d.Lookup = MapLookup(map[string]string{"proxy_on": "true"})
+	if errors := d.SetFrom("", &Cfg{}); len(errors) != 1 {
+		t.Errorf("expected 1 requires error, got %v", errors)
+	}
+}
@@ -0,0 +1,212 @@
+package struct2env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContractHashKey is the name (before prefixes) of the variable holding the ContractHash, see
+// Encoder.EmitContractHash and Decoder.CheckContractHash.
+const ContractHashKey = "CONFIG_SCHEMA_HASH"
+
+// ErrContractMismatch is wrapped by the error SetFrom returns with the CheckContractHash option when
+// the environment was generated for a different version of the struct.
+var ErrContractMismatch = errors.New("environment generated for a different config schema")
+
+// contractOptions are the tag options changing the representation of the values, part of the contract.
+// The `precision=N` tag option, like the DurationPrecision and FloatFormat Options, isn't: it only changes
+// the digits the Encoder writes, which read back with or without it.
+var contractOptions = []string{"size", "percent", "unix", "unixmilli", "duration", "inline", "keepcase", "noprefix"}
+
+// contractValueOptions are the key=value tag options part of the contract.
+var contractValueOptions = []string{"sep", "codec", "quote"}
+
+// ContractHash returns a stable hash of the env contract of s (struct or pointer to struct): the names of
+// its variables (map fields as KEY_*), their types and the options changing their representation (including
+// the DurationFormat for duration fields), so generator and consumer binaries can detect they disagree.
+// Field order, help, defaults and the output only options (precision, FloatFormat...) don't matter.
+func ContractHash(s interface{}) string {
+	return NewEncoder().ContractHash(s)
+}
+
+// ContractHash is ContractHash using the Options (KeyMapper and AutoPrefix).
+func (o *Options) ContractHash(s interface{}) string {
+	t := reflect.TypeOf(s)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var lines []string
+	if t != nil && t.Kind() == reflect.Struct {
+		lines = o.contract(lines, "", t)
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// contract appends to lines one "KEY type options" entry per variable of the struct type t.
+func (o *Options) contract(lines []string, prefix string, t reflect.Type) []string {
+	keys := o.keyMapper()
+	info := cachedStructInfo(t)
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		f := info.fields[i]
+		if f.tag == "-" {
+			continue
+		}
+		if fieldType.Anonymous && isEmbeddedStruct(fieldType.Type) {
+			ft := fieldType.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			lines = o.contract(lines, prefix, ft)
+			continue
+		}
+		key := prefix + info.key(i, fieldType.Name, o)
+		_, hasCodec := f.opts.Get("codec")
+		ft := fieldType.Type
+		switch {
+		case ft.Kind() == reflect.Struct && !isTimeType(ft) && !hasCodec:
+			lines = o.contract(lines, key+keys.Sep, ft)
+			continue
+		case ft.Kind() == reflect.Map && !f.opts.Contains("inline") && !hasCodec:
+			key += keys.Sep + "*"
+		}
+		line := []string{key, ft.String()}
+		for _, opt := range contractOptions {
+			if f.opts.Contains(opt) {
+				line = append(line, opt)
+			}
+		}
+		for _, opt := range contractValueOptions {
+			if v, found := f.opts.Get(opt); found {
+				line = append(line, opt+"="+v)
+			}
+		}
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if o.DurationFormat != DurationSeconds && isDurationType(ft, f.opts) {
+			line = append(line, "durationformat="+strconv.Itoa(int(o.DurationFormat)))
+		}
+		lines = append(lines, strings.Join(line, " "))
+	}
+	return lines
+}
+
+// contractHashVar returns the ContractHashKey variable for s.
+func (e *Encoder) contractHashVar(s interface{}) KeyValue {
+	kv := KeyValue{Key: ContractHashKey}
+	if e.AutoPrefix {
+		kv.Key = e.typePrefix(s) + kv.Key
+	}
+	_ = setString(&kv, e.ContractHash(s))
+	return kv
+}
+
+// checkContractHash returns an ErrContractMismatch error if the ContractHashKey variable is set to a
+// different value than the ContractHash of s. Environments without it are accepted.
+func (d *Decoder) checkContractHash(allErrors []error, prefix string, s interface{}) []error {
+	name := prefix + ContractHashKey
+	found, _, ok := d.lookup(name)
+	if !ok {
+		return allErrors
+	}
+	if expected := d.ContractHash(s); found != expected {
+		err := fmt.Errorf("%w: %s=%s, expected %s", ErrContractMismatch, name, found, expected)
+		allErrors = append(allErrors, err)
+	}
+	return allErrors
+}
+
+// ChangeKind classifies a Change between two env contracts.
+type ChangeKind int
+
+const (
+	// VarAdded is a new variable, compatible for the consumers.
+	VarAdded ChangeKind = iota
+	// VarRemoved is a variable no longer read, breaking for the environments still setting it.
+	VarRemoved
+	// VarTypeChanged is a variable whose Go type changed, breaking as its old values may not parse.
+	VarTypeChanged
+	// VarRenamed is a variable renamed with its old name kept as an `alias`, compatible.
+	VarRenamed
+)
+
+var changeKindNames = []string{"added", "removed", "type changed", "renamed"}
+
+func (k ChangeKind) String() string {
+	if k < 0 || int(k) >= len(changeKindNames) {
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+	return changeKindNames[k]
+}
+
+// Change is a difference between two env contracts, see CompatibleContracts.
+type Change struct {
+	Kind    ChangeKind
+	Name    string // Variable name (the old one for VarRemoved).
+	OldName string // Previous name for VarRenamed.
+	OldType string // Previous Go type for VarTypeChanged.
+	NewType string // New Go type for VarTypeChanged.
+}
+
+// Breaking returns whether the change can break existing environments (removed or type changed variables).
+func (c Change) Breaking() bool {
+	return c.Kind == VarRemoved || c.Kind == VarTypeChanged
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case VarRenamed:
+		return fmt.Sprintf("%s renamed from %s", c.Name, c.OldName)
+	case VarTypeChanged:
+		return fmt.Sprintf("%s type changed from %s to %s", c.Name, c.OldType, c.NewType)
+	default:
+		return c.Name + " " + c.Kind.String()
+	}
+}
+
+// CompatibleContracts returns the differences between two DescribeEnvVars outputs of (versions of) a struct,
+// the changes to the new variables first in their order, then the removed ones: so CI can block breaking
+// env contract changes (see Change.Breaking). Variables whose aliases include an old name are renamed ones.
+// Use zero values to describe the structs, as map entries depend on the content.
+func CompatibleContracts(oldDesc, newDesc []EnvVarInfo) []Change {
+	old := make(map[string]int, len(oldDesc))
+	for i, v := range oldDesc {
+		old[v.Name] = i
+	}
+	matched := make([]bool, len(oldDesc))
+	var changes []Change
+	for _, v := range newDesc {
+		change := Change{Kind: VarAdded, Name: v.Name}
+		idx, found := old[v.Name]
+		for j := 0; j < len(v.Aliases) && !found; j++ {
+			if idx, found = old[v.Aliases[j]]; found {
+				change = Change{Kind: VarRenamed, Name: v.Name, OldName: v.Aliases[j]}
+			}
+		}
+		if found {
+			matched[idx] = true
+			if o := oldDesc[idx]; o.Type != v.Type {
+				changes = append(changes, Change{Kind: VarTypeChanged, Name: v.Name, OldType: o.Type, NewType: v.Type})
+			}
+			if change.Kind != VarRenamed {
+				continue
+			}
+		}
+		changes = append(changes, change)
+	}
+	for i, v := range oldDesc {
+		if !matched[i] {
+			changes = append(changes, Change{Kind: VarRemoved, Name: v.Name})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,132 @@
+package struct2env
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContractHash(t *testing.T) {
+	type Sub struct {
+		Host string
+	}
+	type V1 struct {
+		Name    string `env:",secret" help:"the name"`
+		Port    int
+		Sub     Sub
+		Labels  map[string]string
+		Skipped string `env:"-"`
+	}
+	type V1Reordered struct {
+		Port    int
+		Labels  map[string]string
+		Sub     Sub
+		Name    string
+		Skipped int `env:"-"`
+	}
+	type V2 struct {
+		Name   string
+		Port   string
+		Sub    Sub
+		Labels map[string]string
+	}
+	h := ContractHash(V1{})
+	if len(h) != 16 {
+		t.Errorf("unexpected hash %q", h)
+	}
+	if h2 := ContractHash(&V1Reordered{}); h2 != h {
+		t.Errorf("order, help, secret and excluded fields shouldn't change the hash: %q vs %q", h2, h)
+	}
+	if h2 := ContractHash(V2{}); h2 == h {
+		t.Errorf("type change should change the hash %q", h2)
+	}
+	e := NewEncoder()
+	e.EmitContractHash = true
+	kvl, errs := e.StructToEnvVars(V1{Name: "a"})
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	last := kvl[len(kvl)-1]
+	if last.Key != ContractHashKey || last.Value != h {
+		t.Errorf("unexpected hash variable %+v", last)
+	}
+	env := map[string]string{"APP_NAME": "x", "APP_" + ContractHashKey: h}
+	d := NewDecoder(MapLookup(env))
+	d.CheckContractHash = true
+	if errs = d.SetFrom("APP_", &V1Reordered{}); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	errs = d.SetFrom("APP_", &V2{})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrContractMismatch) {
+		t.Errorf("expected a mismatch error, got %v", errs)
+	}
+	delete(env, "APP_"+ContractHashKey)
+	if errs = d.SetFrom("APP_", &V2{}); len(errs) != 0 {
+		t.Errorf("missing hash should be accepted, got %v", errs)
+	}
+	type Timeouts struct {
+		Read  time.Duration `env:",precision=3"`
+		Write *time.Duration
+	}
+	h = ContractHash(Timeouts{})
+	o := Options{FloatFormat: FloatDecimal, DurationPrecision: 9}
+	if h2 := o.ContractHash(Timeouts{}); h2 != h {
+		t.Errorf("output only options shouldn't change the hash: %q vs %q", h2, h)
+	}
+	o.DurationFormat = DurationString
+	if h2 := o.ContractHash(Timeouts{}); h2 == h {
+		t.Errorf("duration format should change the hash %q", h2)
+	}
+	if h2 := o.ContractHash(V1{}); h2 != ContractHash(V1{}) {
+		t.Errorf("duration format shouldn't change the hash without durations: %q", h2)
+	}
+}
+
+func TestCompatibleContracts(t *testing.T) {
+	type V1 struct {
+		Name    string
+		Port    int
+		Timeout int
+		Old     string
+	}
+	type V2 struct {
+		Name    string
+		Port    string
+		Timeout int    `env:"TIMEOUT_MS,alias=TIMEOUT"`
+		Extra   bool   `env:",alias=NOPE"`
+		Gone    string `env:"-"`
+	}
+	oldDesc, _ := DescribeEnvVars("APP_", V1{})
+	newDesc, _ := DescribeEnvVars("APP_", V2{})
+	changes := CompatibleContracts(oldDesc, newDesc)
+	expected := []string{
+		"APP_PORT type changed from int to string",
+		"APP_TIMEOUT_MS renamed from APP_TIMEOUT",
+		"APP_EXTRA added",
+		"APP_OLD removed",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	breaking := 0
+	for i, c := range changes {
+		if c.String() != expected[i] {
+			t.Errorf("change %d: got %q expected %q", i, c, expected[i])
+		}
+		if c.Breaking() {
+			breaking++
+		}
+	}
+	if breaking != 2 {
+		t.Errorf("expected 2 breaking changes, got %d", breaking)
+	}
+	if changes = CompatibleContracts(newDesc, newDesc); len(changes) != 0 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	// The Decoder still reads the old name.
+	cfg := V2{}
+	errs := SetFrom(MapLookup(map[string]string{"APP_TIMEOUT": "5"}), "APP_", &cfg)
+	if len(errs) != 0 || cfg.Timeout != 5 {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+}
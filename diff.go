@@ -0,0 +1,39 @@
+package struct2env
+
+import (
+	"reflect"
+)
+
+// DiffEnvVars is like StructToEnvVars but only returns the entries whose value differ from the ones of
+// defaults (a struct of the same type, or nil for the zero value), e.g. to produce minimal override files.
+func DiffEnvVars(s, defaults interface{}) ([]KeyValue, []error) {
+	return NewEncoder().DiffEnvVars(s, defaults)
+}
+
+// DiffEnvVars is the Encoder version of DiffEnvVars, using the Encoder's Options for both structs.
+func (e *Encoder) DiffEnvVars(s, defaults interface{}) ([]KeyValue, []error) {
+	if defaults == nil {
+		if t := reflect.TypeOf(s); t != nil {
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			defaults = reflect.Zero(t).Interface()
+		}
+	}
+	kvl, allErrors := e.StructToEnvVars(s)
+	defaultKvl, _ := e.toEnvVars(defaults) // fields failing in defaults are just considered different.
+	defaultValues := make(map[string]KeyValue, len(defaultKvl))
+	for _, kv := range defaultKvl {
+		defaultValues[kv.Key] = kv
+	}
+	res := make([]KeyValue, 0, len(kvl))
+	for _, kv := range kvl {
+		def, found := defaultValues[kv.Key]
+		// YamlQuotedVal distinguishes nil pointers from empty values.
+		if found && def.Value == kv.Value && def.YamlQuotedVal == kv.YamlQuotedVal {
+			continue
+		}
+		res = append(res, kv)
+	}
+	return res, allErrors
+}
@@ -0,0 +1,41 @@
+package struct2env
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffEnvVars(t *testing.T) {
+	type Cfg struct {
+		Name    string
+		Port    int
+		Timeout time.Duration
+		Ptr     *string
+		Labels  map[string]string
+	}
+	defaults := Cfg{Name: "app", Port: 8080, Timeout: 5 * time.Second}
+	empty := ""
+	cfg := defaults
+	cfg.Port = 9090
+	cfg.Ptr = &empty
+	cfg.Labels = map[string]string{"team": "infra"}
+	kvl, errors := DiffEnvVars(cfg, &defaults)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("D_", kvl, true)
+	expected := "D_PORT='9090'\nD_PTR=''\nD_LABELS_TEAM='infra'\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	kvl, _ = DiffEnvVars(&cfg, nil)
+	str = ToShellWithPrefix("", kvl, true)
+	expected = "NAME='app'\nPORT='9090'\nTIMEOUT=5\nPTR=''\nLABELS_TEAM='infra'\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	kvl, _ = DiffEnvVars(defaults, defaults)
+	if len(kvl) != 0 {
+		t.Errorf("expected no difference, got %v", kvl)
+	}
+}
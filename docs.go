@@ -0,0 +1,121 @@
+package struct2env
+
+import (
+	"strings"
+)
+
+// Documentation tables of the variables (name, default and description), from DescribeEnvVars
+// called on a struct holding the defaults. Also registered as the markdown, asciidoc and rst formats.
+
+// ToMarkdownTable returns a GitHub flavored Markdown table documenting vars.
+func ToMarkdownTable(vars []EnvVarInfo) string {
+	var sb strings.Builder
+	sb.WriteString("| Variable | Default | Description |\n")
+	sb.WriteString("|----------|---------|-------------|\n")
+	for _, v := range vars {
+		sb.WriteString("| `")
+		sb.WriteString(v.Name)
+		sb.WriteString("` | ")
+		if v.Set && v.Value != "" {
+			sb.WriteString(markdownCode(markdownEscape(v.Value)))
+		}
+		sb.WriteString(" | ")
+		sb.WriteString(markdownEscape(v.Help))
+		sb.WriteString(" |\n")
+	}
+	return sb.String()
+}
+
+// ToMarkdownSections returns one Markdown table (see ToMarkdownTable) per section (see KeyValue.Section)
+// of vars, each nested struct's one preceded by a `### Section` heading.
+func ToMarkdownSections(vars []EnvVarInfo) string {
+	if len(vars) == 0 {
+		return ToMarkdownTable(vars)
+	}
+	var sb strings.Builder
+	start := 0
+	for i := 1; i <= len(vars); i++ {
+		if i < len(vars) && vars[i].Section == vars[start].Section {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteRune('\n')
+		}
+		if section := vars[start].Section; section != "" {
+			sb.WriteString("### ")
+			sb.WriteString(section)
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(ToMarkdownTable(vars[start:i]))
+		start = i
+	}
+	return sb.String()
+}
+
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// markdownCode returns s as inline code, using double backticks if s contains some.
+func markdownCode(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// ToAsciiDocTable returns an AsciiDoc (e.g. Antora) table documenting vars.
+func ToAsciiDocTable(vars []EnvVarInfo) string {
+	var sb strings.Builder
+	sb.WriteString("[cols=\"2,1,3\",options=\"header\"]\n")
+	sb.WriteString("|===\n")
+	sb.WriteString("|Variable |Default |Description\n")
+	for _, v := range vars {
+		sb.WriteString("\n|`")
+		sb.WriteString(v.Name)
+		sb.WriteString("`\n|")
+		if v.Set && v.Value != "" {
+			sb.WriteString("`+")
+			sb.WriteString(asciiDocEscape(v.Value))
+			sb.WriteString("+`")
+		}
+		sb.WriteString("\n|")
+		sb.WriteString(asciiDocEscape(v.Help))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("|===\n")
+	return sb.String()
+}
+
+func asciiDocEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ToRSTTable returns a reStructuredText (e.g. Sphinx) list-table documenting vars.
+func ToRSTTable(vars []EnvVarInfo) string {
+	var sb strings.Builder
+	sb.WriteString(".. list-table::\n")
+	sb.WriteString("   :header-rows: 1\n\n")
+	sb.WriteString("   * - Variable\n")
+	sb.WriteString("     - Default\n")
+	sb.WriteString("     - Description\n")
+	for _, v := range vars {
+		sb.WriteString("   * - ``")
+		sb.WriteString(v.Name)
+		sb.WriteString("``\n     -")
+		if v.Set && v.Value != "" {
+			sb.WriteString(" ``")
+			sb.WriteString(strings.Join(strings.Fields(v.Value), " "))
+			sb.WriteString("``")
+		}
+		sb.WriteString("\n     -")
+		if v.Help != "" {
+			sb.WriteString(" ")
+			sb.WriteString(strings.Join(strings.Fields(v.Help), " "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package struct2env
+
+import (
+	"strings"
+	"testing"
+)
+
+type DocConfig struct {
+	Port    int    `help:"Port to listen on."`
+	Mode    string `help:"One of a|b."`
+	Token   string `env:",secret" help:"API token."`
+	Timeout *int
+}
+
+func docVars(t *testing.T) []EnvVarInfo {
+	t.Helper()
+	vars, errs := DescribeEnvVars("APP_", DocConfig{Port: 8080, Mode: "a", Token: "s3cr3t"})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	return vars
+}
+
+func TestMarkdownTable(t *testing.T) {
+	expected := "| Variable | Default | Description |\n" +
+		"|----------|---------|-------------|\n" +
+		"| `APP_PORT` | `8080` | Port to listen on. |\n" +
+		"| `APP_MODE` | `a` | One of a\\|b. |\n" +
+		"| `APP_TOKEN` | `********` | API token. |\n" +
+		"| `APP_TIMEOUT` |  |  |\n"
+	if str := ToMarkdownTable(docVars(t)); str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	if str := markdownCode("a`b"); str != "`` a`b ``" {
+		t.Errorf("unexpected code escape %s", str)
+	}
+}
+
+func TestAsciiDocTable(t *testing.T) {
+	expected := `[cols="2,1,3",options="header"]
+|===
+|Variable |Default |Description
+
+|` + "`APP_PORT`" + `
+|` + "`+8080+`" + `
+|Port to listen on.
+
+|` + "`APP_MODE`" + `
+|` + "`+a+`" + `
+|One of a\|b.
+
+|` + "`APP_TOKEN`" + `
+|` + "`+********+`" + `
+|API token.
+
+|` + "`APP_TIMEOUT`" + `
+|
+|
+|===
+`
+	if str := ToAsciiDocTable(docVars(t)); str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
+
+func TestRSTTable(t *testing.T) {
+	expected := `.. list-table::
+   :header-rows: 1
+
+   * - Variable
+     - Default
+     - Description
+   * - ` + "``APP_PORT``" + `
+     - ` + "``8080``" + `
+     - Port to listen on.
+   * - ` + "``APP_MODE``" + `
+     - ` + "``a``" + `
+     - One of a|b.
+   * - ` + "``APP_TOKEN``" + `
+     - ` + "``********``" + `
+     - API token.
+   * - ` + "``APP_TIMEOUT``" + `
+     -
+     -
+`
+	if str := ToRSTTable(docVars(t)); str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	kvl, _ := StructToEnvVars(DocConfig{Port: 8080, Mode: "a", Token: "s3cr3t"})
+	var sb strings.Builder
+	if err := Render(&sb, "rst", kvl, FormatOptions{Prefix: "APP_"}); err != nil || sb.String() != expected {
+		t.Errorf("unexpected rst format output %v\n%s", err, sb.String())
+	}
+}
@@ -0,0 +1,204 @@
+package struct2env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// ParseError is the error for a malformed line of a .env file, see ParseDotEnv.
+type ParseError struct {
+	File    string // File name, when known (see ReadDotEnvFile).
+	Line    int    // Line number, starting at 1.
+	Column  int    // Column (in bytes, starting at 1) of the problem in the line.
+	Content string // The offending line.
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %v (in %q)", e.File, e.Line, e.Column, e.Err, e.Content)
+	}
+	return fmt.Sprintf("line %d, column %d: %v (in %q)", e.Line, e.Column, e.Err, e.Content)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// DotEnvOptions tune ParseDotEnvWithOptions, the zero value gives the ParseDotEnv behavior.
+type DotEnvOptions struct {
+	// Strict rejects the syntax read differently, or not at all, by other dotenv implementations:
+	// keys other than [A-Za-z_][A-Za-z0-9_]*, quotes and backslashes in unquoted values and backslash
+	// escapes other than \n, \r, \" and \\ in double quoted values (e.g. \t or \$).
+	Strict bool
+}
+
+// errUnterminatedQuote is the error for a quoted value not closed by the end of the line (nor the file).
+var errUnterminatedQuote = errors.New("unterminated quoted value")
+
+// ParseDotEnv reads a .env file: one KEY=value per line, optionally preceded by `export `, with blank
+// lines and # comments ignored. Values are unquoted with DotEnvUnquote (so what ToDotEnvWithPrefix
+// writes reads back identically) and unquoted values can be followed by a ` # comment`. Like with
+// docker compose and ruby dotenv, quoted values can span multiple lines (the newlines being kept),
+// but variable references ($VAR) are not expanded.
+// When a key is present more than once, the last value wins. Malformed lines give a *ParseError.
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	return ParseDotEnvWithOptions(r, DotEnvOptions{})
+}
+
+// ParseDotEnvWithOptions is ParseDotEnv with the given options, e.g. DotEnvOptions{Strict: true}.
+func ParseDotEnvWithOptions(r io.Reader, opts DotEnvOptions) (map[string]string, error) {
+	res := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		startLine := lineNum
+		line := scanner.Text()
+		key, value, ok, col, err := parseDotEnvLine(line, opts.Strict)
+		for errors.Is(err, errUnterminatedQuote) && scanner.Scan() {
+			// Multi-line quoted value: retry with the next line.
+			lineNum++
+			line += "\n" + scanner.Text()
+			key, value, ok, col, err = parseDotEnvLine(line, opts.Strict)
+		}
+		if err != nil {
+			return nil, newParseError(startLine, line, col, err)
+		}
+		if ok {
+			res[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// newParseError returns the ParseError for the problem at column col of the (possibly multi-line)
+// entry starting at line lineNum, pointing at the line of the entry where the problem is.
+func newParseError(lineNum int, entry string, col int, err error) *ParseError {
+	for {
+		idx := strings.IndexByte(entry, '\n')
+		if idx < 0 || idx >= col-1 {
+			if idx >= 0 {
+				entry = entry[:idx]
+			}
+			return &ParseError{Line: lineNum, Column: col, Content: entry, Err: err}
+		}
+		entry = entry[idx+1:]
+		col -= idx + 1
+		lineNum++
+	}
+}
+
+// parseDotEnvLine returns the key and value of line, ok being false for blank and comment lines.
+// Errors come with the column of the problem.
+func parseDotEnvLine(line string, strict bool) (string, string, bool, int, error) {
+	rest := strings.TrimLeftFunc(line, unicode.IsSpace)
+	pos := len(line) - len(rest) // Offset of rest in line.
+	rest = strings.TrimRightFunc(rest, unicode.IsSpace)
+	if rest == "" || rest[0] == '#' {
+		return "", "", false, 0, nil
+	}
+	if strings.HasPrefix(rest, "export ") {
+		n := len(rest)
+		rest = strings.TrimLeft(rest[len("export "):], " \t")
+		pos += n - len(rest)
+	}
+	idx := strings.IndexByte(rest, '=')
+	if idx <= 0 {
+		return "", "", false, pos + 1, fmt.Errorf("expecting KEY=value")
+	}
+	key := strings.TrimRight(rest[:idx], " \t")
+	if bad := strings.IndexAny(key, " \t'\""); bad >= 0 {
+		return "", "", false, pos + bad + 1, fmt.Errorf("invalid key %q", key)
+	}
+	if strict && !isPortableName(key) {
+		return "", "", false, pos + 1, fmt.Errorf("invalid key %q", key)
+	}
+	value := strings.TrimLeft(rest[idx+1:], " \t")
+	pos += len(rest) - len(value) // Offset of value.
+	if value == "" || (value[0] != '\'' && value[0] != '"') {
+		if idx = strings.Index(value, " #"); idx >= 0 {
+			value = value[:idx]
+		}
+		if bad := strings.IndexAny(value, "'\"\\"); strict && bad >= 0 {
+			return "", "", false, pos + bad + 1, fmt.Errorf("unexpected %q in the unquoted value of %s", value[bad], key)
+		}
+		return key, strings.TrimRight(value, " \t"), true, 0, nil
+	}
+	end := closingQuote(value)
+	if end < 0 {
+		return "", "", false, pos + 1, fmt.Errorf("%w for %s", errUnterminatedQuote, key)
+	}
+	if after := strings.TrimLeft(value[end+1:], " \t"); after != "" && after[0] != '#' {
+		err := fmt.Errorf("unexpected %q after the quoted value of %s", after, key)
+		return "", "", false, pos + len(value) - len(after) + 1, err
+	}
+	if bad := unknownEscape(value[:end+1]); strict && bad >= 0 {
+		return "", "", false, pos + bad + 1, fmt.Errorf("unsupported escape %q in the value of %s", value[bad:bad+2], key)
+	}
+	value, err := DotEnvUnquote(value[:end+1])
+	return key, value, err == nil, pos + 1, err
+}
+
+// isPortableName returns whether name is a [A-Za-z_][A-Za-z0-9_]* variable name.
+func isPortableName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// unknownEscape returns the index of the first backslash escape DotEnvUnquote doesn't interpret in the
+// double quoted value, or -1.
+func unknownEscape(value string) int {
+	if value[0] != '"' {
+		return -1
+	}
+	for i := 1; i < len(value)-1; i++ {
+		if value[i] != '\\' {
+			continue
+		}
+		i++
+		if strings.IndexByte(`nr"\`, value[i]) < 0 {
+			return i - 1
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote closing the one starting value, or -1.
+// Backslashes escape the next character in double quoted values.
+func closingQuote(value string) int {
+	q := value[0]
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if q == '"' {
+				i++
+			}
+		case q:
+			return i
+		}
+	}
+	return -1
+}
+
+// SetFromDotEnvReader sets the fields of s (pointer to a struct) from the .env file content read from
+// r (see ParseDotEnv), with the same conversions as SetFromEnv; map fields included.
+func SetFromDotEnvReader(r io.Reader, prefix string, s interface{}) []error {
+	m, err := ParseDotEnv(r)
+	if err != nil {
+		return []error{err}
+	}
+	return setFromMap(m, prefix, s)
+}
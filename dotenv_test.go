@@ -0,0 +1,152 @@
+package struct2env
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	input := `# comment
+APP_NAME=bare value # inline comment
+export APP_PORT = 8080
+
+APP_QUOTED='it has # and "quotes"' # comment
+APP_ESCAPED="line1\nit's \"x\""
+APP_EMPTY=
+APP_HASH=a#b
+APP_NAME=last wins
+`
+	m, err := ParseDotEnv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := map[string]string{
+		"APP_NAME": "last wins", "APP_PORT": "8080", "APP_QUOTED": `it has # and "quotes"`,
+		"APP_ESCAPED": "line1\nit's \"x\"", "APP_EMPTY": "", "APP_HASH": "a#b",
+	}
+	if len(m) != len(expected) {
+		t.Errorf("unexpected %v", m)
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("for %s got %q expected %q", k, m[k], v)
+		}
+	}
+	for _, test := range []struct {
+		bad    string
+		column int
+	}{
+		{"NOEQUAL", 1},
+		{"=value", 1},
+		{"  export A B=c", 11},
+		{"A = 'unterminated", 5},
+		{`A="x"  y`, 8},
+	} {
+		_, err = ParseDotEnv(strings.NewReader("# ok\n" + test.bad + "\n"))
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != 2 || parseErr.Column != test.column ||
+			parseErr.Content != test.bad || !strings.HasPrefix(err.Error(), "line 2, column ") {
+			t.Errorf("expected line 2 column %d error for %q, got %v", test.column, test.bad, err)
+		}
+	}
+}
+
+func TestParseDotEnvMultiLine(t *testing.T) {
+	input := `A="line1
+  line2 # not a comment"
+B='single
+quoted' # comment
+C=after
+`
+	m, err := ParseDotEnv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if m["A"] != "line1\n  line2 # not a comment" || m["B"] != "single\nquoted" || m["C"] != "after" || len(m) != 3 {
+		t.Errorf("unexpected %q", m)
+	}
+	for _, test := range []struct {
+		bad     string
+		line    int
+		column  int
+		content string
+	}{
+		{"A='unterminated\nB=x", 1, 3, "A='unterminated"},
+		{"A=\"multi\nline\"  junk", 2, 8, "line\"  junk"},
+		{"A=\"multi\nsecond \" third\n B=x", 2, 10, "second \" third"},
+	} {
+		_, err = ParseDotEnv(strings.NewReader(test.bad))
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != test.line || parseErr.Column != test.column ||
+			parseErr.Content != test.content {
+			t.Errorf("expected line %d column %d error for %q, got %v", test.line, test.column, test.bad, err)
+		}
+	}
+}
+
+func TestParseDotEnvStrict(t *testing.T) {
+	valid := "export A_1='x y'\nB=\"it's\\n \\\"q\\\" $HOME\"\nC=bare value # comment\n"
+	for _, strict := range []bool{false, true} {
+		m, err := ParseDotEnvWithOptions(strings.NewReader(valid), DotEnvOptions{Strict: strict})
+		if err != nil || m["A_1"] != "x y" || m["B"] != "it's\n \"q\" $HOME" || m["C"] != "bare value" {
+			t.Errorf("unexpected %q, %v for strict %v", m, err, strict)
+		}
+	}
+	for _, test := range []struct {
+		bad    string
+		column int
+	}{
+		{"A.B=x", 1},
+		{"1A=x", 1},
+		{"A=it's", 5},
+		{`A=a\b`, 4},
+		{`A="tab\there"`, 7},
+		{`A="\$5"`, 4},
+	} {
+		if _, err := ParseDotEnv(strings.NewReader(test.bad)); err != nil {
+			t.Errorf("unexpected non strict error for %q: %v", test.bad, err)
+		}
+		_, err := ParseDotEnvWithOptions(strings.NewReader(test.bad), DotEnvOptions{Strict: true})
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Column != test.column {
+			t.Errorf("expected column %d strict error for %q, got %v", test.column, test.bad, err)
+		}
+	}
+	// What ToDotEnvWithPrefix writes is always valid.
+	kvl := []KeyValue{{Key: "A"}}
+	for _, v := range []string{"it's", "a\\b\tc\nd", `"q"`, "$x"} {
+		kvl[0].Value = v
+		m, err := ParseDotEnvWithOptions(strings.NewReader(ToDotEnvWithPrefix("", kvl)), DotEnvOptions{Strict: true})
+		if err != nil || m["A"] != v {
+			t.Errorf("unexpected %q, %v for %q", m, err, v)
+		}
+	}
+}
+
+func TestSetFromDotEnvReader(t *testing.T) {
+	type Cfg struct {
+		Name    string
+		Timeout time.Duration
+		Tags    []string
+		Labels  map[string]string
+	}
+	cfg := Cfg{Name: "it's\na \"test\"", Timeout: 1500 * time.Millisecond, Tags: []string{"a", "b"},
+		Labels: map[string]string{"env": "prod"}}
+	kvl, errs := StructToEnvVars(cfg)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	res := Cfg{}
+	errs = SetFromDotEnvReader(strings.NewReader(ToDotEnvWithPrefix("APP_", kvl)), "APP_", &res)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if res.Name != cfg.Name || res.Timeout != cfg.Timeout || len(res.Tags) != 2 || res.Labels["env"] != "prod" {
+		t.Errorf("unexpected %+v", res)
+	}
+	if errs = SetFromDotEnvReader(strings.NewReader("BAD"), "", &res); len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}
@@ -19,7 +19,10 @@ package struct2env
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"reflect"
 	"strconv"
@@ -29,22 +32,25 @@ import (
 )
 
 // Split strings into words, using CamelCase/camelCase/CAMELCase rules.
+// The registered acronyms (see RegisterAcronyms) are kept whole.
 func SplitByCase(input string) []string {
+	return splitWords(input, splitter{acronyms: registeredAcronyms()})
+}
+
+// splitWords is SplitByCase with the given splitter.
+func splitWords(input string, sp splitter) []string {
 	if input == "" {
 		return nil
 	}
 	var words []string
 	var buffer strings.Builder
 	runes := []rune(input)
+	starts := sp.wordStarts(runes)
 
 	for i := 0; i < len(runes); i++ {
-		first := (i == 0)
-		last := (i == len(runes)-1)
-		if !first && unicode.IsUpper(runes[i]) {
-			if !last && unicode.IsLower(runes[i+1]) || unicode.IsLower(runes[i-1]) {
-				words = append(words, buffer.String())
-				buffer.Reset()
-			}
+		if (starts == nil && isWordStart(runes, i)) || (starts != nil && starts[i]) {
+			words = append(words, buffer.String())
+			buffer.Reset()
 		}
 		buffer.WriteRune(runes[i])
 	}
@@ -52,42 +58,166 @@ func SplitByCase(input string) []string {
 	return words
 }
 
+// isWordStart returns true if a new word starts at index i (excluding the first one) per SplitByCase rules.
+func isWordStart(runes []rune, i int) bool {
+	first := (i == 0)
+	last := (i == len(runes)-1)
+	return !first && unicode.IsUpper(runes[i]) && (!last && unicode.IsLower(runes[i+1]) || unicode.IsLower(runes[i-1]))
+}
+
+// convertCase is the single allocation equivalent of mapping each rune of the SplitByCase words and
+// joining them with sep.
+func convertCase(s string, sep rune, mapping func(rune) rune) string {
+	return convertWords(s, sep, mapping, splitter{acronyms: registeredAcronyms()})
+}
+
+// convertWords is convertCase with the given splitter.
+func convertWords(s string, sep rune, mapping func(rune) rune, sp splitter) string {
+	if s == "" {
+		return ""
+	}
+	runes := []rune(s)
+	starts := sp.wordStarts(runes)
+	var sb strings.Builder
+	sb.Grow(len(s) + len(runes)/2)
+	for i, r := range runes {
+		if (starts == nil && isWordStart(runes, i)) || (starts != nil && starts[i]) {
+			sb.WriteRune(sep)
+		}
+		sb.WriteRune(mapping(r))
+	}
+	return sb.String()
+}
+
 // CamelCaseToUpperSnakeCase converts a string from camelCase or CamelCase
 // to UPPER_SNAKE_CASE. Handles cases like HTTPServer -> HTTP_SERVER and
 // httpServer -> HTTP_SERVER. Good for environment variables.
 func CamelCaseToUpperSnakeCase(s string) string {
-	if s == "" {
-		return ""
-	}
-	words := SplitByCase(s)
 	// ToUpper + Join by _
-	return strings.ToUpper(strings.Join(words, "_"))
+	return convertCase(s, '_', unicode.ToUpper)
 }
 
 // CamelCaseToLowerSnakeCase converts a string from camelCase or CamelCase
 // to lower_snake_case. Handles cases like HTTPServer -> http_server.
 // Good for JSON tags for instance.
 func CamelCaseToLowerSnakeCase(s string) string {
-	if s == "" {
-		return ""
-	}
-	words := SplitByCase(s)
 	// ToLower + Join by _
-	return strings.ToLower(strings.Join(words, "_"))
+	return convertCase(s, '_', unicode.ToLower)
 }
 
 // CamelCaseToLowerKebabCase converts a string from camelCase or CamelCase
 // to lower-kebab-case. Handles cases like HTTPServer -> http-server.
 // Good for command line flags for instance.
 func CamelCaseToLowerKebabCase(s string) string {
-	if s == "" {
-		return ""
-	}
-	words := SplitByCase(s)
 	// ToLower and join by -
-	return strings.ToLower(strings.Join(words, "-"))
+	return convertCase(s, '-', unicode.ToLower)
 }
 
+// CamelCaseToUpperKebabCase converts a string from camelCase or CamelCase
+// to SCREAMING-KEBAB-CASE. Handles cases like HTTPServer -> HTTP-SERVER.
+func CamelCaseToUpperKebabCase(s string) string {
+	return convertCase(s, '-', unicode.ToUpper)
+}
+
+// CamelCaseToLowerCamelCase converts a string from CamelCase (or camelCase) to
+// lowerCamelCase, lowering the acronyms: HTTPServer -> httpServer, XRequestID -> xRequestId.
+func CamelCaseToLowerCamelCase(s string) string {
+	return joinCapitalized(SplitByCase(s), true)
+}
+
+// CamelCaseToLowerDotCase converts a string from camelCase or CamelCase
+// to lower.dot.case. Handles cases like HTTPServer -> http.server.
+func CamelCaseToLowerDotCase(s string) string {
+	return convertCase(s, '.', unicode.ToLower)
+}
+
+// CamelCaseToLowerPathCase converts a string from camelCase or CamelCase
+// to lower/path/case. Handles cases like HTTPServer -> http/server.
+func CamelCaseToLowerPathCase(s string) string {
+	return convertCase(s, '/', unicode.ToLower)
+}
+
+// CamelCaseToTrainCase converts a string from camelCase or CamelCase
+// to Train-Case (each word capitalized, joined by -). Handles cases like
+// HTTPServer -> Http-Server and XRequestID -> X-Request-Id. Good for HTTP header names.
+func CamelCaseToTrainCase(s string) string {
+	words := SplitByCase(s)
+	for i, w := range words {
+		words[i] = joinCapitalized([]string{w}, false)
+	}
+	return strings.Join(words, "-")
+}
+
+// UpperSnakeCaseToCamelCase converts a string from UPPER_SNAKE_CASE to CamelCase, e.g.
+// HTTP_SERVER -> HttpServer; the reverse of CamelCaseToUpperSnakeCase (minus the
+// acronyms casing which is lost in UPPER_SNAKE_CASE). Useful to map env variable names
+// back to Go field names.
+func UpperSnakeCaseToCamelCase(s string) string {
+	return joinCapitalized(strings.Split(s, "_"), false)
+}
+
+// UpperSnakeCaseToLowerCamelCase converts a string from UPPER_SNAKE_CASE to
+// lowerCamelCase, e.g. HTTP_SERVER -> httpServer.
+func UpperSnakeCaseToLowerCamelCase(s string) string {
+	return joinCapitalized(strings.Split(s, "_"), true)
+}
+
+// LowerSnakeCaseToCamelCase converts a string from lower_snake_case to CamelCase, e.g.
+// http_server -> HttpServer; the reverse of CamelCaseToLowerSnakeCase (minus the acronyms casing).
+func LowerSnakeCaseToCamelCase(s string) string {
+	return joinCapitalized(strings.Split(s, "_"), false)
+}
+
+// KebabCaseToCamelCase converts a string from kebab-case (lower, upper or Train-Case) to CamelCase, e.g.
+// http-server -> HttpServer; the reverse of CamelCaseToLowerKebabCase (minus the acronyms casing).
+// Useful to map command line flag or HTTP header names back to Go field names.
+func KebabCaseToCamelCase(s string) string {
+	return joinCapitalized(strings.Split(s, "-"), false)
+}
+
+// joinCapitalized joins the (non empty) words, lower cased, with their first letter
+// capitalized - except for the first word if lowerFirst is true.
+func joinCapitalized(words []string, lowerFirst bool) string {
+	var sb strings.Builder
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		runes := []rune(strings.ToLower(w))
+		if !lowerFirst || sb.Len() > 0 {
+			runes[0] = unicode.ToTitle(runes[0])
+		}
+		sb.WriteString(string(runes))
+	}
+	return sb.String()
+}
+
+// KeyMapper controls how the keys are derived from the struct field names. Fields with an
+// explicit `env:"NAME"` tag use that NAME as is.
+type KeyMapper struct {
+	Field func(fieldName string) string // Converts a (tag-less) field name into a key segment.
+	Sep   string                        // Joins the segments of nested structs.
+}
+
+var (
+	// UpperSnakeKeys is the default KeyMapper, giving SERVER_HTTP_PORT style keys.
+	UpperSnakeKeys = KeyMapper{Field: CamelCaseToUpperSnakeCase, Sep: "_"}
+	// LowerSnakeKeys gives server_http_port style keys.
+	LowerSnakeKeys = KeyMapper{Field: CamelCaseToLowerSnakeCase, Sep: "_"}
+	// UpperKebabKeys gives SERVER-HTTP-PORT style keys.
+	UpperKebabKeys = KeyMapper{Field: CamelCaseToUpperKebabCase, Sep: "-"}
+	// LowerCamelKeys gives lowerCamelCase keys, e.g. hostName, for systems using such variable names,
+	// with nested structs' segments joined by _ (server_hostName).
+	LowerCamelKeys = KeyMapper{Field: CamelCaseToLowerCamelCase, Sep: "_"}
+	// DotKeys gives server.http.port style keys, for viper style loaders for instance.
+	DotKeys = KeyMapper{Field: CamelCaseToLowerDotCase, Sep: "."}
+	// PathKeys gives server/http/port style keys, for consul KV trees for instance.
+	PathKeys = KeyMapper{Field: CamelCaseToLowerPathCase, Sep: "/"}
+	// VerbatimKeys uses the Go field names as is, giving Server_HTTP_Port style keys, for systems
+	// whose variable names are intentionally mixed case (e.g. GoMaxProcs, JavaOpts).
+	VerbatimKeys = KeyMapper{Field: func(fieldName string) string { return fieldName }, Sep: "_"}
+)
+
 // Intermediate result list from StructToEnvVars(), both the Key and QuotedValue
 // must be shell safe/non adversarial as they are emitted as is by String() with = in between.
 // Using StructToEnvVars produces safe values even with adversarial input (length and future
@@ -97,6 +227,34 @@ type KeyValue struct {
 	Key            string // Must be safe (is when coming from Go struct names but could be bad with env:).
 	ShellQuotedVal string // (Must be) Already quoted/escaped ('' style).
 	YamlQuotedVal  string // (Must be) Already quoted/escaped for yaml ("" with \ style).
+	Value          string // Raw (unquoted) value, for output formats doing their own escaping.
+	NoPrefix       bool   // Global key which output prefixes don't apply to (`noprefix` tag option).
+	Secret         bool   // Value is sensitive and masked in HelpText (`secret` tag option).
+	Help           string // Description from the field's `help` tag.
+	Section        string // Key prefix (without the trailing separator) of the nested struct the field is from.
+	Type           string // Go type of the field, e.g. int or map[string]string (empty for EnvValuer ones).
+	// Aliases are the previous keys of the variable (`alias` tag options), which SetFrom still reads.
+	Aliases []string
+	// Index is the field index path in the struct (as for reflect.Value.FieldByIndex), the position of the
+	// variable in its struct. Map entries share their field's, EnvValuer ones come after all the fields.
+	// See CompareKeyValues.
+	Index []int
+}
+
+// prefixedKey returns the key with the output prefix prepended, unless the key is global (NoPrefix).
+func (kv KeyValue) prefixedKey(prefix string) string {
+	if kv.NoPrefix {
+		return kv.Key
+	}
+	return prefix + kv.Key
+}
+
+// writePrefixedKey is prefixedKey writing to sb, without allocating.
+func writePrefixedKey(sb *strings.Builder, prefix string, kv KeyValue) {
+	if !kv.NoPrefix {
+		sb.WriteString(prefix)
+	}
+	sb.WriteString(kv.Key)
 }
 
 // Escape characters such as the result string can be embedded as a single argument in a shell fragment
@@ -117,7 +275,38 @@ func YamlQuote(input string) string {
 }
 
 func (kv KeyValue) ToShell() string {
-	return fmt.Sprintf("%s=%s", kv.Key, kv.ShellQuotedVal)
+	return kv.Key + "=" + kv.ShellQuotedVal
+}
+
+// String returns the shell form KEY='value' (see ToShell), for logging.
+func (kv KeyValue) String() string {
+	return kv.ToShell()
+}
+
+// keyValueJSON is the (raw) form of KeyValue on the wire.
+type keyValueJSON struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// MarshalJSON serializes the KeyValue as {"name": "KEY", "value": "raw value"}.
+func (kv KeyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(keyValueJSON{Name: kv.Key, Value: kv.Value})
+}
+
+// UnmarshalJSON is the reverse of MarshalJSON, the quoted values are recomputed
+// from the raw value (as a string).
+func (kv *KeyValue) UnmarshalJSON(data []byte) error {
+	var raw keyValueJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	res := KeyValue{Key: raw.Name}
+	if err := setString(&res, raw.Value); err != nil {
+		return err
+	}
+	*kv = res
+	return nil
 }
 
 func ToShell(kvl []KeyValue) string {
@@ -126,21 +315,9 @@ func ToShell(kvl []KeyValue) string {
 
 // This convert the key value pairs to bourne shell syntax (vs newer bash export FOO=bar).
 // If skipExport is true the last line export VAR1 VAR2... is omitted.
+// See ToShellWithOptions for more control over the output.
 func ToShellWithPrefix(prefix string, kvl []KeyValue, skipExport bool) string {
-	var sb strings.Builder
-	keys := make([]string, 0, len(kvl))
-	for _, kv := range kvl {
-		sb.WriteString(prefix)
-		sb.WriteString(kv.ToShell())
-		sb.WriteRune('\n')
-		keys = append(keys, prefix+kv.Key)
-	}
-	if !skipExport {
-		sb.WriteString("export ")
-		sb.WriteString(strings.Join(keys, " "))
-		sb.WriteRune('\n')
-	}
-	return sb.String()
+	return ToShellWithOptions(kvl, ShellOptions{Prefix: prefix, SkipExport: skipExport})
 }
 
 func ToYamlWithPrefix(indent int, prefix string, kvl []KeyValue) string {
@@ -148,8 +325,7 @@ func ToYamlWithPrefix(indent int, prefix string, kvl []KeyValue) string {
 	for _, kv := range kvl {
 		sb.WriteString(strings.Repeat(" ", indent))
 		sb.WriteString("- name: ")
-		sb.WriteString(prefix)
-		sb.WriteString(kv.Key)
+		sb.WriteString(kv.prefixedKey(prefix))
 		sb.WriteRune('\n')
 		sb.WriteString(strings.Repeat(" ", indent))
 		sb.WriteString("  value: ")
@@ -163,32 +339,297 @@ func SerializeValue(result *KeyValue, value interface{}) error {
 	var err error
 	switch v := value.(type) {
 	case bool:
-		res := "false"
-		if v {
-			res = "true"
-		}
-		result.ShellQuotedVal = res
-		result.YamlQuotedVal = res
+		setBool(result, v)
 		return nil
 	case []byte:
-		result.ShellQuotedVal, err = ShellQuote(base64.StdEncoding.EncodeToString(v))
+		result.Value = base64.StdEncoding.EncodeToString(v)
+		result.ShellQuotedVal, err = ShellQuote(result.Value)
 		result.YamlQuotedVal = result.ShellQuotedVal // same single quoting works for yaml when no special chars is in
 		return err
 	case string:
-		result.ShellQuotedVal, err = ShellQuote(v)
-		result.YamlQuotedVal = YamlQuote(v)
-		return err
+		return setString(result, v)
 	case time.Duration:
-		str := fmt.Sprintf("%g", v.Seconds())
-		result.ShellQuotedVal = str
-		result.YamlQuotedVal = str
+		setDuration(result, v)
 		return nil
 	default:
-		str := fmt.Sprint(value)
-		result.ShellQuotedVal, err = ShellQuote(str)
-		result.YamlQuotedVal = YamlQuote(str)
-		return err
+		return setString(result, fmt.Sprint(value))
+	}
+}
+
+var (
+	boolType     = reflect.TypeOf(false)
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	// Types implementing these have their own fmt.Sprint output, so can't use the fast path.
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	formatterType = reflect.TypeOf((*fmt.Formatter)(nil)).Elem()
+)
+
+// isTimeType reports whether t is time.Time or a struct only embedding it, like `type Timestamp struct{ time.Time }`.
+func isTimeType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	return t.Kind() == reflect.Struct && t.NumField() == 1 && t.Field(0).Anonymous && t.Field(0).Type == timeType
+}
+
+// isEmbeddedStruct returns whether the anonymous field type t is a struct (or pointer to struct) whose fields
+// are flattened at the level of the embedding one. Other embedded types (e.g. time.Duration or a named string)
+// are regular fields named after their type.
+func isEmbeddedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isTimeType(t)
+}
+
+// unexportedEmbed is the embedError message for embedded non struct types which aren't exported (so neither
+// is the field named after them); unlike for structs there are no promoted exported fields to use instead.
+const unexportedEmbed = "is of an unexported non struct type, its value can't be accessed (exclude it with env:\"-\")"
+
+// embedError returns an error about the i-th field of the struct type t, an embedded (anonymous) one,
+// identified by its type and position.
+func embedError(t reflect.Type, i int, msg string) error {
+	return fmt.Errorf("embedded %v (field #%d of %v) %s", t.Field(i).Type, i, t, msg)
+}
+
+// timeValue returns the time.Time of v, whose type must satisfy isTimeType.
+func timeValue(v reflect.Value) reflect.Value {
+	if v.Type() == timeType {
+		return v
+	}
+	return v.Field(0)
+}
+
+// isDurationType reports whether t is time.Duration or, with the `duration` tag option, any int64 based type
+// (reflection can't tell a `type Timeout time.Duration` from an int64 based one).
+func isDurationType(t reflect.Type, opts tagOptions) bool {
+	return t == durationType || (t.Kind() == reflect.Int64 && opts.Contains("duration"))
+}
+
+// serializeValue is the reflect.Value version of SerializeValue, producing the same results
+// but with fast paths for the common kinds that avoid boxing through Interface() and fmt.
+func serializeValue(result *KeyValue, v reflect.Value) error {
+	t := v.Type()
+	if t == durationType {
+		setDuration(result, time.Duration(v.Int()))
+		return nil
 	}
+	if isTextArray(t) {
+		return serializeArray(result, v)
+	}
+	if !t.Implements(stringerType) && !t.Implements(errorType) && !t.Implements(formatterType) {
+		var scratch [32]byte
+		switch v.Kind() { //nolint: exhaustive // we have the fallback below for the other cases
+		case reflect.String:
+			return setString(result, v.String())
+		case reflect.Bool:
+			if t == boolType {
+				setBool(result, v.Bool())
+				return nil
+			}
+			return setString(result, strconv.FormatBool(v.Bool()))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			setSafeString(result, strconv.AppendInt(scratch[:0], v.Int(), 10))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			setSafeString(result, strconv.AppendUint(scratch[:0], v.Uint(), 10))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			setSafeString(result, strconv.AppendFloat(scratch[:0], v.Float(), 'g', -1, t.Bits()))
+			return nil
+		}
+	}
+	if !v.CanInterface() {
+		return fmt.Errorf("can't interface %v value", t)
+	}
+	return SerializeValue(result, v.Interface())
+}
+
+// serializeTime formats time.Time using the first of the TimeLayouts or as a unix timestamp
+// if the unix (seconds) or unixmilli tag options are set.
+func (e *Encoder) serializeTime(result *KeyValue, t time.Time, opts tagOptions) error {
+	var scratch [24]byte
+	switch {
+	case opts.Contains("unix"):
+		setSafeString(result, strconv.AppendInt(scratch[:0], t.Unix(), 10))
+		return nil
+	case opts.Contains("unixmilli"):
+		setSafeString(result, strconv.AppendInt(scratch[:0], t.UnixMilli(), 10))
+		return nil
+	default:
+		if e.TimeUTC {
+			t = t.UTC()
+		}
+		return setString(result, t.Format(e.timeLayouts()[0]))
+	}
+}
+
+// serializeField applies the field's tag options, if any, instead of the default serializeValue.
+func (e *Encoder) serializeField(result *KeyValue, v reflect.Value, opts tagOptions) error {
+	if opts.Contains("size") {
+		switch v.Kind() { //nolint: exhaustive // only integers are valid for size
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return setString(result, FormatByteSize(v.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if u := v.Uint(); u <= math.MaxInt64 {
+				return setString(result, FormatByteSize(int64(u)))
+			}
+			return serializeValue(result, v)
+		default:
+			return fmt.Errorf("size option is only valid for integer types, not %v", v.Type())
+		}
+	}
+	if isDurationType(v.Type(), opts) {
+		d := time.Duration(v.Int())
+		switch e.DurationFormat {
+		case DurationString:
+			return setString(result, d.String()) // non numeric so quoted, for yaml too.
+		case DurationMilliseconds:
+			str := strconv.FormatInt(d.Milliseconds(), 10)
+			result.ShellQuotedVal, result.YamlQuotedVal, result.Value = str, str, str
+		default:
+			if precision, found := opts.Get("precision"); found || e.DurationPrecision > 0 {
+				return formatSeconds(result, d, precision, found, e.DurationPrecision)
+			}
+			setDuration(result, d)
+		}
+		return nil
+	}
+	if opts.Contains("duration") {
+		return fmt.Errorf("duration option is only valid for int64 types, not %v", v.Type())
+	}
+	if opts.Contains("percent") && v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+		return fmt.Errorf("percent option is only valid for float types, not %v", v.Type())
+	}
+	if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+		if precision, found := opts.Get("precision"); found || e.FloatFormat == FloatDecimal {
+			return formatFloat(result, v, precision, found)
+		}
+	} else if _, found := opts.Get("precision"); found {
+		return fmt.Errorf("precision option is only valid for float and duration types, not %v", v.Type())
+	}
+	if isStringSlice(v.Type()) {
+		return joinStrings(result, v, separator(opts))
+	}
+	if isNumberSlice(v.Type()) {
+		return joinNumbers(result, v, separator(opts), e.FloatFormat)
+	}
+	if isStringMap(v.Type()) {
+		return joinMap(result, v, separator(opts))
+	}
+	return serializeValue(result, v)
+}
+
+// formatFloat writes the float v without exponent, with the given number of decimals when hasPrecision
+// and otherwise the minimum needed to read back the same value.
+func formatFloat(result *KeyValue, v reflect.Value, precision string, hasPrecision bool) error {
+	prec := -1
+	if hasPrecision {
+		var err error
+		if prec, err = parsePrecision(precision); err != nil {
+			return err
+		}
+	}
+	var scratch [32]byte
+	setSafeString(result, strconv.AppendFloat(scratch[:0], v.Float(), 'f', prec, v.Type().Bits()))
+	return nil
+}
+
+// parsePrecision parses the value of the precision tag option.
+func parsePrecision(precision string) (int, error) {
+	prec, err := strconv.Atoi(precision)
+	if err != nil || prec < 0 {
+		return 0, fmt.Errorf("invalid precision %q, must be a non negative integer", precision)
+	}
+	return prec, nil
+}
+
+// formatSeconds writes d in seconds without exponent and with the given number of decimals (the tag's
+// precision when hasPrecision, otherwise defaultPrecision), capped to 9: the nanoseconds. Unlike going
+// through a float64, it is exact (rounding half away from zero to the last decimal).
+func formatSeconds(result *KeyValue, d time.Duration, precision string, hasPrecision bool, defaultPrecision int) error {
+	decimals := defaultPrecision
+	if hasPrecision {
+		var err error
+		if decimals, err = parsePrecision(precision); err != nil {
+			return err
+		}
+	}
+	if decimals > 9 {
+		decimals = 9
+	}
+	unit := time.Duration(1)
+	for i := decimals; i < 9; i++ {
+		unit *= 10
+	}
+	d = d.Round(unit)
+	var scratch [32]byte
+	b := scratch[:0]
+	u := uint64(d)
+	if d < 0 {
+		b = append(b, '-')
+		u = -u
+	}
+	b = strconv.AppendUint(b, u/uint64(time.Second), 10)
+	if decimals > 0 {
+		var digits [9]byte
+		frac := u % uint64(time.Second)
+		for i := len(digits) - 1; i >= 0; i-- {
+			digits[i] = byte('0' + frac%10)
+			frac /= 10
+		}
+		b = append(b, '.')
+		b = append(b, digits[:decimals]...)
+	}
+	setSafeString(result, b)
+	return nil
+}
+
+func setBool(result *KeyValue, v bool) {
+	res := "false"
+	if v {
+		res = "true"
+	}
+	result.ShellQuotedVal = res
+	result.YamlQuotedVal = res
+	result.Value = res
+}
+
+func setDuration(result *KeyValue, v time.Duration) {
+	str := strconv.FormatFloat(v.Seconds(), 'g', -1, 64)
+	result.ShellQuotedVal = str
+	result.YamlQuotedVal = str
+	result.Value = str
+}
+
+func setString(result *KeyValue, v string) error {
+	var err error
+	if strings.IndexByte(v, '\'') < 0 && strings.IndexByte(v, 0) < 0 {
+		// Common case: nothing to escape for the shell, single allocation and Value shares the memory.
+		result.ShellQuotedVal = "'" + v + "'"
+	} else {
+		result.ShellQuotedVal, err = ShellQuote(v)
+	}
+	result.YamlQuotedVal = YamlQuote(v)
+	if err == nil {
+		result.Value = v
+	}
+	return err
+}
+
+// setSafeString is for values known to need no escaping (numbers): the buffer is converted to a
+// string only twice, once with each style of quotes around it, Value being a slice of the first one.
+func setSafeString(result *KeyValue, buf []byte) {
+	n := len(buf)
+	buf = append(buf, 0, 0)
+	copy(buf[1:], buf[:n])
+	buf[0], buf[n+1] = '\'', '\''
+	result.ShellQuotedVal = string(buf)
+	buf[0], buf[n+1] = '"', '"'
+	result.YamlQuotedVal = string(buf)
+	result.Value = result.ShellQuotedVal[1 : n+1]
 }
 
 // StructToEnvVars converts a struct to a map of environment variables.
@@ -198,51 +639,264 @@ func SerializeValue(result *KeyValue, value interface{}) error {
 // If the field is exportable and the tag is missing we'll use the field name
 // converted to UPPER_SNAKE_CASE (using CamelCaseToUpperSnakeCase()) as the
 // environment variable name.
+// Options can follow the name after a comma, e.g. `env:"HTTP_PROXY,noprefix"` where noprefix
+// makes that (non struct) field a global variable: neither the parent structs' nor the output prefix apply,
+// `size` makes an integer field a number of bytes in human readable form (see FormatByteSize)
+// `percent` makes a float field also accept percentages (75% is read as 0.75, see ParsePercent),
+// `precision=N` writes a float field (or a duration in seconds) without exponent and with N decimals
+// (see also Options.FloatFormat and Options.DurationPrecision),
+// `unix` or `unixmilli` make a time.Time field an epoch timestamp in seconds or milliseconds,
+// `readonly` fields are only read by SetFrom and never emitted (e.g. secrets not to be re-exported) while
+// `writeonly` ones are only emitted (e.g. computed values) and never read,
+// `duration` makes an int64 based type (e.g. `type Timeout time.Duration`) a time.Duration,
+// `secret` marks the value as sensitive (masked by HelpText), `keepcase` keeps the case of map keys
+// and `template` makes SetFrom expand a string field as a text/template over its sibling fields
+// (e.g. "http://{{.Host}}:{{.Port}}"), whether its value came from the environment or was a default.
+// `when=Guard` makes SetFrom only read the field when the sibling field Guard (Go or env name) is set
+// (true/non zero) and `requires=Other` makes it an error for the field to be set while Other isn't.
+// `default=value` makes SetFrom use value (which can't contain commas) when the variable isn't set.
+// `quote=single|ansi|bare` forces the shell quoting of the value (see ANSICQuote for ansi).
+// `codec=name` uses the Codec registered under that name in the Options for the field.
+// `omitempty` skips the field when it has its zero value, `required` makes SetFrom return a MissingError
+// when the variable is unset or empty. See TagOptions for the list of all the options.
+// A `help:"description"` tag documents the variable, see HelpText.
+// map[string]string fields are expanded to one PREFIX_FIELD_<KEY> variable per entry (key uppercased), or
+// with the `inline` option are a single k1=v1,k2=v2 variable. []string and slices of integers or floats
+// (e.g. []int, []float64) are joined with commas, or with the separator set by the `sep=` option (which also
+// applies to inline maps).
 // []byte are encoded as base64, time.Time are formatted as RFC3339, time.Duration are in (floating point) seconds.
+// [16]byte arrays are formatted as UUIDs (see FormatUUID), other byte arrays as base64 and arrays implementing
+// encoding.TextMarshaler using it (and encoding.TextUnmarshaler, on their pointer, when decoding).
+// Structs only embedding a time.Time (e.g. `type Timestamp struct{ time.Time }`) are handled like time.Time.
+// Embedded structs' fields are at the same level as the embedding struct's, other embedded types (e.g. an
+// embedded time.Duration) are regular fields named after their type (DURATION).
+// Structs implementing EnvValuer add the variables it returns after their fields'.
 func StructToEnvVars(s interface{}) ([]KeyValue, []error) {
+	return NewEncoder().StructToEnvVars(s)
+}
+
+// Encoder converts structs to KeyValue lists (see StructToEnvVars), with the behavior tuned by its Options.
+// An Encoder can be used concurrently as long as it isn't modified meanwhile. The information about the struct
+// types is computed on first use and cached (for all Encoders and Decoders), see Warmup.
+type Encoder struct {
+	Options
+	skipped *[]SkippedField // When not nil, collects the skipped fields (see StructToEnvVarsReport).
+	// When not nil, results are passed to yield instead of being accumulated (see EnvVars),
+	// until it returns false which sets stopped.
+	yield func(KeyValue, error) bool
+	// stopped is set when yield returned false or when MaxErrors is reached.
+	stopped bool
+	// EmitContractHash makes StructToEnvVars add a ContractHashKey variable with the ContractHash of the struct,
+	// for Decoders with CheckContractHash to detect they were built with a different version of it.
+	EmitContractHash bool
+	// Groups, when not empty, restricts the output to the fields whose `groups` tag has one of them
+	// (and all the fields of the struct fields that do), see StructToEnvVarsGroups.
+	Groups  []string
+	inGroup bool // Whether the struct being converted is in the Groups.
+	// inputs makes the conversion list the variables SetFrom reads (for DescribeEnvVars): with the
+	// readonly fields and without the writeonly ones.
+	inputs bool
+	// inReadOnly is set, in inputs mode, while converting a readonly field (or the fields of a readonly
+	// struct): its values are withheld, like unset ones.
+	inReadOnly bool
+	// skipValuer is set for the embedded structs whose EnvValues is also the embedding struct's
+	// (see addEnvValues).
+	skipValuer bool
+	// keepEmpty makes the conversion ignore the `omitempty` tag option, to find all the keys (see SetFromScanner).
+	keepEmpty bool
+}
+
+// clone returns a copy of e for the conversion state not to be shared (e.g. between goroutines).
+func (e *Encoder) clone() *Encoder {
+	res := *e
+	return &res
+}
+
+// NewEncoder returns an Encoder with default options.
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// StructToEnvVars converts s (struct or pointer to struct) to a list of KeyValue
+// using the Encoder's Options. See the StructToEnvVars function for details.
+func (e *Encoder) StructToEnvVars(s interface{}) ([]KeyValue, []error) {
+	kvl, allErrors := e.toEnvVars(s)
+	if e.EmitContractHash {
+		kvl = append(kvl, e.contractHashVar(s))
+	}
+	return kvl, e.limitErrors(e.checkNames(allErrors, "", kvl))
+}
+
+// toEnvVars is StructToEnvVars without the NamePolicy check.
+func (e *Encoder) toEnvVars(s interface{}) ([]KeyValue, []error) {
+	e = e.clone()
 	var allErrors []error
-	var allKeyValVals []KeyValue
-	return structToEnvVars(allKeyValVals, allErrors, "", s)
+	v := reflect.ValueOf(s)
+	// Most configs are mostly flat: start with room for one entry per top level field.
+	allKeyValVals := make([]KeyValue, 0, numFields(v))
+	prefix := ""
+	if e.AutoPrefix {
+		prefix = e.typePrefix(s)
+	}
+	return e.structToEnvVars(allKeyValVals, allErrors, prefix, nil, v)
+}
+
+// numFields returns the number of fields of v (struct or pointer to struct), 0 for other kinds.
+func numFields(v reflect.Value) int {
+	t := v.Type()
+	if v.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return 0
+	}
+	return t.NumField()
 }
 
 // Appends additional results and errors to incoming envVars and allErrors and return them (for recursion).
-func structToEnvVars(envVars []KeyValue, allErrors []error, prefix string, s interface{}) ([]KeyValue, []error) {
-	v := reflect.ValueOf(s)
+func (e *Encoder) structToEnvVars(
+	envVars []KeyValue,
+	allErrors []error,
+	prefix string,
+	index []int, // Index path of v in the top level struct.
+	v reflect.Value,
+) ([]KeyValue, []error) {
 	// if we're passed a pointer to a struct instead of the struct, let that work too
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
 	if v.Kind() != reflect.Struct {
 		err := fmt.Errorf("unexpected kind %v, expected a struct", v.Kind())
-		allErrors = append(allErrors, err)
-		return envVars, allErrors
+		return e.add(envVars, allErrors, nil, err)
 	}
+	keys := e.keyMapper()
+	section := strings.TrimSuffix(prefix, keys.Sep)
 	t := v.Type()
-	for i := 0; i < t.NumField(); i++ {
+	outerGroup := e.inGroup
+	skipValuer := e.skipValuer
+	e.skipValuer = false
+	outerReadOnly := e.inReadOnly
+	indexes := newIndexPaths(index, t.NumField())
+	info := cachedStructInfo(t)
+	for i := 0; i < t.NumField() && !e.stopped; i++ {
 		fieldType := t.Field(i)
-		tag := fieldType.Tag.Get("env")
+		fieldIndex := indexes.field(i)
+		tag, opts := info.fields[i].tag, info.fields[i].opts
 		if tag == "-" {
+			e.skip(t, fieldType, "", SkipExcluded)
+			continue
+		}
+		if e.StrictTags {
+			if err := opts.check(fieldType.Name); err != nil {
+				envVars, allErrors = e.add(envVars, allErrors, nil, err)
+				continue
+			}
+		}
+		if !e.inputs && opts.Contains("readonly") {
+			e.skip(t, fieldType, "", SkipReadOnly)
+			continue
+		}
+		if e.inputs && opts.Contains("writeonly") {
+			e.skip(t, fieldType, "", SkipWriteOnly)
+			continue
+		}
+		e.inReadOnly = outerReadOnly || opts.Contains("readonly")
+		_, hasCodec := opts.Get("codec")
+		recurse := (fieldType.Anonymous && isEmbeddedStruct(fieldType.Type)) ||
+			(fieldType.Type.Kind() == reflect.Struct && !isTimeType(fieldType.Type) && !hasCodec)
+		inGroup, skip := e.groupFilter(fieldType, outerGroup, recurse)
+		if skip {
+			e.skip(t, fieldType, "", SkipGroup)
 			continue
 		}
-		if fieldType.Anonymous {
-			// Recurse
-			envVars, allErrors = structToEnvVars(envVars, allErrors, "", v.Field(i).Interface())
+		e.inGroup = inGroup
+		if fieldType.Anonymous && isEmbeddedStruct(fieldType.Type) {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Ptr && embedded.IsNil() {
+				if !e.OmitNilPointers {
+					envVars, allErrors = e.add(envVars, allErrors, nil, embedError(t, i, "is nil"))
+				}
+				continue
+			}
+			// Recurse. When v is an EnvValuer, its EnvValues is the one promoted from (or overriding) the
+			// embedded struct's, so only v's is used, like a method call on v would.
+			_, e.skipValuer = envValuer(v)
+			envVars, allErrors = e.structToEnvVars(envVars, allErrors, prefix, fieldIndex, embedded)
 			continue
 		}
-		if tag == "" {
-			tag = CamelCaseToUpperSnakeCase(fieldType.Name)
+		if fieldType.Anonymous && fieldType.PkgPath != "" {
+			e.skip(t, fieldType, "", SkipCantInterface)
+			envVars, allErrors = e.add(envVars, allErrors, nil, embedError(t, i, unexportedEmbed))
+			continue
 		}
+		tag = info.key(i, fieldType.Name, &e.Options)
 		fieldValue := v.Field(i)
 		var err error
 		res := KeyValue{Key: prefix + tag}
+		if opts.Contains("noprefix") {
+			if fieldValue.Kind() == reflect.Struct && !isTimeType(fieldValue.Type()) {
+				err = fmt.Errorf("noprefix is only supported on non struct fields (%s)", fieldType.Name)
+				envVars, allErrors = e.add(envVars, allErrors, nil, err)
+				continue
+			}
+			res = KeyValue{Key: tag, NoPrefix: true}
+		}
+		res.Secret = opts.Contains("secret")
+		res.Help = fieldType.Tag.Get("help")
+		res.Section = section
+		res.Type = fieldType.Type.String()
+		for _, alias := range opts.Values("alias") {
+			if !res.NoPrefix {
+				alias = prefix + alias
+			}
+			res.Aliases = append(res.Aliases, alias)
+		}
+		res.Index = fieldIndex
+		if (e.OmitEmpty || opts.Contains("omitempty")) && !e.keepEmpty && fieldValue.IsZero() {
+			e.skip(t, fieldType, res.Key, SkipEmpty)
+			continue
+		}
+		if !recurse && e.isReserved(res.Key, opts) {
+			err = &ReservedError{EnvName: res.Key, Field: fieldType.Name}
+			envVars, allErrors = e.add(envVars, allErrors, nil, err)
+			continue
+		}
+		if e.NonFinite != NonFiniteText && isNonFinite(fieldValue) {
+			if e.NonFinite == NonFiniteSkip {
+				e.skip(t, fieldType, res.Key, SkipNonFinite)
+			} else {
+				err = fmt.Errorf("%s: non finite value for %s", res.Key, fieldType.Name)
+				envVars, allErrors = e.add(envVars, allErrors, nil, err)
+			}
+			continue
+		}
 
-		if fieldValue.Type() == reflect.TypeOf(time.Time{}) { // other wise we hit the "struct" case below
-			timeField := fieldValue.Interface().(time.Time)
-			err = SerializeValue(&res, timeField.Format(time.RFC3339))
+		if codec, found := opts.Get("codec"); found {
+			if err = e.formatCodec(&res, codec, fieldType.Name, fieldValue); err == nil {
+				err = quoteAs(&res, opts)
+			}
 			if err != nil {
-				allErrors = append(allErrors, err)
+				envVars, allErrors = e.add(envVars, allErrors, nil, err)
+			} else {
+				envVars, allErrors = e.add(envVars, allErrors, &res, nil)
+			}
+			continue
+		}
+		if isTimeType(fieldValue.Type()) { // other wise we hit the "struct" case below
+			fieldValue = timeValue(fieldValue)
+			if !fieldValue.CanInterface() {
+				err = fmt.Errorf("can't interface %s", fieldType.Name)
+				e.skip(t, fieldType, res.Key, SkipCantInterface)
 			} else {
-				envVars = append(envVars, res)
+				err = e.serializeTime(&res, fieldValue.Interface().(time.Time), opts)
+			}
+			if err == nil {
+				err = quoteAs(&res, opts)
+			}
+			if err != nil {
+				envVars, allErrors = e.add(envVars, allErrors, nil, err)
+			} else {
+				envVars, allErrors = e.add(envVars, allErrors, &res, nil)
 			}
 			continue // Continue to the next field
 		}
@@ -250,36 +904,87 @@ func structToEnvVars(envVars []KeyValue, allErrors []error, prefix string, s int
 		switch fieldValue.Kind() { //nolint: exhaustive // we have default: for the other cases
 		case reflect.Ptr:
 			if fieldValue.IsNil() {
+				if e.OmitNilPointers {
+					continue
+				}
 				res.YamlQuotedVal = "null"
+			} else if elem := fieldValue.Elem(); isTimeType(elem.Type()) && timeValue(elem).CanInterface() {
+				err = e.serializeTime(&res, timeValue(elem).Interface().(time.Time), opts)
 			} else {
-				fieldValue = fieldValue.Elem()
-				err = SerializeValue(&res, fieldValue.Interface())
+				err = e.serializeField(&res, elem, opts)
 			}
 		case reflect.Map, reflect.Array, reflect.Chan, reflect.Slice:
-			// From that list of other types, only support []byte
-			if fieldValue.Type().Elem().Kind() == reflect.Uint8 {
-				err = SerializeValue(&res, fieldValue.Interface())
-			} else {
+			// From that list of other types, only support map[string]string (one entry per key unless inline),
+			// []string, number slices, []byte, byte arrays and arrays implementing encoding.TextMarshaler.
+			isMap := isStringMap(fieldValue.Type())
+			if isMap && !opts.Contains("inline") {
+				envVars, allErrors = e.mapToEnvVars(envVars, allErrors, res, keys.Sep, fieldType.Name, fieldValue, opts)
+				continue
+			}
+			switch {
+			case isMap || isStringSlice(fieldValue.Type()) || isNumberSlice(fieldValue.Type()):
+				err = e.serializeField(&res, fieldValue, opts)
+			case isTextArray(fieldValue.Type()) || fieldValue.Type().Elem().Kind() == reflect.Uint8:
+				err = serializeValue(&res, fieldValue)
+			default:
 				// log.LogVf("Skipping field %s of type %v, not supported", fieldType.Name, fieldType.Type)
+				e.skip(t, fieldType, res.Key, SkipUnsupported)
 				continue
 			}
 		case reflect.Struct:
 			// Recurse with prefix
-			envVars, allErrors = structToEnvVars(envVars, allErrors, tag+"_", fieldValue.Interface())
+			envVars, allErrors = e.structToEnvVars(envVars, allErrors, prefix+tag+keys.Sep, fieldIndex, fieldValue)
 			continue
 		default:
 			if !fieldValue.CanInterface() {
 				err = fmt.Errorf("can't interface %s", fieldType.Name)
+				e.skip(t, fieldType, res.Key, SkipCantInterface)
 			} else {
-				value := fieldValue.Interface()
-				err = SerializeValue(&res, value)
+				err = e.serializeField(&res, fieldValue, opts)
 			}
 		}
-		envVars = append(envVars, res)
+		if err == nil {
+			err = quoteAs(&res, opts)
+		}
+		envVars, allErrors = e.add(envVars, allErrors, &res, err)
+	}
+	e.inGroup = outerGroup
+	e.inReadOnly = outerReadOnly
+	if skipValuer || (len(e.Groups) != 0 && !outerGroup) {
+		return envVars, allErrors
+	}
+	return e.addEnvValues(envVars, allErrors, prefix, section, index, v)
+}
+
+// add appends res (when not nil) and err (when not nil) to the results, or passes them to the
+// iterator's yield function when streaming (see EnvVars).
+func (e *Encoder) add(envVars []KeyValue, allErrors []error, res *KeyValue, err error) ([]KeyValue, []error) {
+	if res != nil && e.inReadOnly {
+		withheld := *res
+		withheld.ShellQuotedVal, withheld.YamlQuotedVal, withheld.Value = "", "null", ""
+		res = &withheld
+	}
+	if e.yield == nil {
+		if res != nil {
+			envVars = append(envVars, *res)
+		}
 		if err != nil {
 			allErrors = append(allErrors, err)
+			e.stopped = e.tooManyErrors(allErrors)
 		}
+		return envVars, allErrors
+	}
+	if e.stopped {
+		return envVars, allErrors
 	}
+	var kv KeyValue
+	if res != nil {
+		kv = *res
+		if err == nil && e.NamePolicy != nil {
+			err = e.NamePolicy.Check(kv.Key)
+		}
+	}
+	e.stopped = !e.yield(kv, err)
 	return envVars, allErrors
 }
 
@@ -292,36 +997,407 @@ func setPointer(fieldValue reflect.Value) reflect.Value {
 	return fieldValue.Elem()
 }
 
-func checkEnv(envLookup EnvLookup, envName, fieldName string, fieldValue reflect.Value) (*string, error) {
-	val, found := envLookup(envName)
+// checkEnv returns the value for the first of names found (nil if none is), the name of the source
+// that had it and that variable name (the first of names when not found).
+func (d *Decoder) checkEnv(
+	names []string, fieldName string, fieldValue reflect.Value,
+) (*string, string, string, error) {
+	envName := names[0]
+	var val, source string
+	found := false
+	for _, name := range names {
+		if val, source, found = d.lookup(name); found {
+			envName = name
+			break
+		}
+	}
 	if !found {
 		// log.LogVf("%q not set for %s", envName, fieldName)
-		return nil, nil //nolint:nilnil
+		return nil, "", envName, nil
 	}
 	// log.Infof("Found %s=%q to set %s", envName, val, fieldName)
 	if !fieldValue.CanSet() {
 		err := fmt.Errorf("can't set %s (found %s=%q)", fieldName, envName, val)
-		return nil, err
+		return &val, source, envName, err
 	}
-	return &val, nil
+	return &val, source, envName, nil
+}
+
+// MissingError is the error for the fields tagged `env:",required"` whose variable is not set or empty.
+type MissingError struct {
+	EnvName string // Name of the variable, prefix included.
+	Field   string // Path of the field, e.g. DB.Password.
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("%s is required (for %s) but not set", e.EnvName, e.Field)
+}
+
+// fallbackNames returns prefix+tag followed by the names with the leading sep separated levels
+// of prefix removed, see Decoder.PrefixFallback.
+func fallbackNames(prefix, tag, sep string) []string {
+	names := []string{prefix + tag}
+	for sep != "" {
+		idx := strings.Index(prefix, sep)
+		if idx < 0 {
+			break
+		}
+		prefix = prefix[idx+len(sep):]
+		names = append(names, prefix+tag)
+	}
+	return names
 }
 
 type EnvLookup func(key string) (string, bool)
 
 // Reverse of StructToEnvVars, assumes the same encoding. Using the current os environment variables as source.
+// On Windows the environment variable names are matched case-insensitively, as os.LookupEnv does there;
+// use SetFrom with CaseInsensitiveLookup(os.Environ()) to get that behavior on other OSes.
+// Map fields are filled from all the matching variables, see Decoder.SetFrom.
 func SetFromEnv(prefix string, s interface{}) []error {
-	return SetFrom(os.LookupEnv, prefix, s)
+	d := NewDecoder(os.LookupEnv)
+	d.Keys = OSKeys
+	return d.SetFrom(prefix, s)
 }
 
 // Reverse of StructToEnvVars, assumes the same encoding. Using passed it lookup object that can lookup values by keys.
 func SetFrom(envLookup EnvLookup, prefix string, s interface{}) []error {
-	return setFromEnv(nil, envLookup, prefix, s)
+	return NewDecoder(envLookup).SetFrom(prefix, s)
+}
+
+// Options tune the conversions, the zero value gives the default behavior.
+type Options struct {
+	// KeyMapper derives the keys from the field names, defaults to UpperSnakeKeys when unset.
+	KeyMapper KeyMapper
+	// LenientBool makes bool fields also accept yes/no, on/off and enabled/disabled (case-insensitive)
+	// in addition to the strconv.ParseBool values.
+	LenientBool bool
+	// AutoPrefix derives the prefix from the struct type name (see TypePrefix): the Encoder then prefixes
+	// all the keys with it and the Decoder uses it when SetFrom is called with an empty prefix.
+	AutoPrefix bool
+	// TrimTypeSuffix makes AutoPrefix first remove the common Config, Settings, Options... suffix
+	// from the type name (FooConfig gives FOO_ instead of FOO_CONFIG_).
+	TrimTypeSuffix bool
+	// TimeLayouts are the layouts (see time.Parse) tried in order by the Decoder for time.Time fields,
+	// the first one is also the one used by the Encoder. Defaults to []string{time.RFC3339} when empty.
+	// See CommonTimeLayouts for a more lenient list.
+	TimeLayouts []string
+	// TimeUTC makes the Encoder convert the time.Time values to UTC (giving the Z suffix with RFC3339) instead
+	// of preserving their offset. The unix and unixmilli representations have no zone either way.
+	TimeUTC bool
+	// TimeLocation, when not nil, is the location the Decoder uses for the time values without zone
+	// information: for the TimeLayouts without zone (e.g. "2006-01-02", otherwise UTC) and, when none of
+	// the TimeLayouts parses a value, with them stripped of their zone (e.g. 2024-03-10T08:00:00 for RFC3339,
+	// otherwise an error).
+	TimeLocation *time.Location
+	// DurationFormat selects how time.Duration fields are represented, defaults to DurationSeconds.
+	DurationFormat DurationFormat
+	// DurationPrecision, when positive, makes the Encoder write the DurationSeconds values without exponent
+	// and with that many decimals, exactly (e.g. 0.000000001 instead of 1e-09 with 9, the maximum, for lossless
+	// round trips to the nanosecond). Per field, the `precision=N` tag option does the same.
+	DurationPrecision int
+	// FloatFormat selects how the Encoder writes float fields, defaults to FloatShortest. Either way the output
+	// doesn't depend on the locale: the decimal separator is always a dot, without grouping.
+	FloatFormat FloatFormat
+	// NonFinite is the policy for NaN and infinite float values, defaults to NonFiniteText.
+	NonFinite NonFinitePolicy
+	// NamePolicy, when set, makes the Encoder report the generated names it doesn't allow as errors.
+	NamePolicy *NamePolicy
+	// OmitNilPointers makes the Encoder skip nil pointer fields instead of emitting empty/null values,
+	// so unset, explicitly empty (pointer to "") and set values survive a round trip through SetFrom.
+	OmitNilPointers bool
+	// OmitEmpty makes the Encoder skip all the fields with their zero value, as if they all had the
+	// `omitempty` tag option.
+	OmitEmpty bool
+	// Codecs are the custom representations, by name, for the fields with the `codec=name` tag option.
+	// See RegisterCodec.
+	Codecs map[string]Codec
+	// ReservedNames are variables the Encoder refuses to emit and the Decoder to read (nor to use as
+	// fallback or alias), returning a ReservedError, unless the field is tagged `allowreserved`: to protect
+	// against accidentally clobbering critical variables. See DefaultReservedNames. The Encoder checks the
+	// keys it produces, so before any output prefix is applied.
+	ReservedNames []string
+	// MaxErrors, when positive, stops the conversions once that many errors were collected (1 for fail-fast),
+	// for large structs where cascading errors would obscure the first one. Callers of the EnvVars iterator
+	// already decide when to stop.
+	MaxErrors int
+	// StrictTags makes unknown env tag options (see TagOptions), typically typos, and misused ones errors
+	// instead of being ignored.
+	StrictTags bool
+}
+
+// tooManyErrors returns whether the MaxErrors limit is reached.
+func (o *Options) tooManyErrors(errs []error) bool {
+	return o.MaxErrors > 0 && len(errs) >= o.MaxErrors
+}
+
+// limitErrors returns the first MaxErrors errors.
+func (o *Options) limitErrors(errs []error) []error {
+	if o.tooManyErrors(errs) {
+		return errs[:o.MaxErrors]
+	}
+	return errs
+}
+
+// DurationFormat is the representation of time.Duration values.
+type DurationFormat int
+
+const (
+	// DurationSeconds is (floating point) seconds, e.g. 3600.1 - the default.
+	DurationSeconds DurationFormat = iota
+	// DurationMilliseconds is integer milliseconds, e.g. 3600100.
+	DurationMilliseconds
+	// DurationString is the Go time.Duration String() / time.ParseDuration format, e.g. 1h0m0.1s.
+	DurationString
+)
+
+// FloatFormat is the representation of float values.
+type FloatFormat int
+
+const (
+	// FloatShortest is the shortest representation reading back to the same value, using an exponent
+	// for large and small values (e.g. 1e-06 or 1.5e+21) - the default.
+	FloatShortest FloatFormat = iota
+	// FloatDecimal never uses an exponent (e.g. 0.000001), for shell arithmetic and YAML consumers
+	// that don't support it.
+	FloatDecimal
+)
+
+// checkRange returns err, unless it is an out of range one: nil with the ClampIntegers option and otherwise
+// a more explicit error including the valid range of the integer type t (still wrapping strconv.ErrRange).
+func (d *Decoder) checkRange(err error, envName, envVal string, t reflect.Type) error {
+	if !errors.Is(err, strconv.ErrRange) {
+		return err
+	}
+	if d.ClampIntegers {
+		return nil
+	}
+	bits := 64 - t.Bits()
+	lo, hi := "0", strconv.FormatUint(math.MaxUint64>>bits, 10)
+	if isSignedInt(t.Kind()) {
+		lo, hi = strconv.FormatInt(math.MinInt64>>bits, 10), strconv.FormatInt(math.MaxInt64>>bits, 10)
+	}
+	return fmt.Errorf("%s=%q is out of range for %v (%s to %s): %w", envName, envVal, t, lo, hi, strconv.ErrRange)
+}
+
+// clampInt returns v clamped to the range of the signed integer type t.
+func clampInt(v int64, t reflect.Type) int64 {
+	bits := 64 - t.Bits()
+	if v < math.MinInt64>>bits {
+		return math.MinInt64 >> bits
+	}
+	if v > math.MaxInt64>>bits {
+		return math.MaxInt64 >> bits
+	}
+	return v
+}
+
+func isSignedInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// NonFinitePolicy is the handling of NaN and infinite float values, in both directions.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteText writes them as NaN, +Inf and -Inf (which SetFrom reads back) - the default.
+	NonFiniteText NonFinitePolicy = iota
+	// NonFiniteError makes them errors for both the Encoder and the Decoder.
+	NonFiniteError
+	// NonFiniteSkip makes the Encoder skip the fields (see SkipNonFinite) and the Decoder ignore the
+	// variables, keeping the fields' existing (or default) values.
+	NonFiniteSkip
+)
+
+// isNonFinite returns whether v is a NaN or infinite float, or a pointer to one.
+func isNonFinite(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+		return false
+	}
+	f := v.Float()
+	return math.IsNaN(f) || math.IsInf(f, 0)
+}
+
+// CommonTimeLayouts is a list of frequently encountered timestamp formats, to use as TimeLayouts:
+// RFC3339 (with optional fractional seconds), RFC3339Nano, date only, RFC1123 with numeric zone and RFC1123.
+var CommonTimeLayouts = []string{time.RFC3339, time.RFC3339Nano, "2006-01-02", time.RFC1123Z, time.RFC1123}
+
+func (o *Options) timeLayouts() []string {
+	if len(o.TimeLayouts) == 0 {
+		return []string{time.RFC3339}
+	}
+	return o.TimeLayouts
+}
+
+// parseTimeField parses a time.Time field value, as a unix timestamp (returned in UTC) when the unix or
+// unixmilli tag options are set and otherwise using the TimeLayouts.
+func (o *Options) parseTimeField(str string, opts tagOptions) (time.Time, error) {
+	unix, unixMilli := opts.Contains("unix"), opts.Contains("unixmilli")
+	if !unix && !unixMilli {
+		return o.parseTime(str)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid unix timestamp %q: %w", str, err)
+	}
+	if unixMilli {
+		return time.UnixMilli(n).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// parseTime tries each of the TimeLayouts in order and returns the first successful parse, then with
+// the TimeLocation the zoneless versions of the layouts.
+func (o *Options) parseTime(str string) (time.Time, error) {
+	layouts := o.timeLayouts()
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		t, err = o.parseTimeLayout(layout, str)
+		if err == nil {
+			return t, nil
+		}
+	}
+	if o.TimeLocation != nil {
+		for _, layout := range layouts {
+			if zoneless := zonelessLayout(layout); zoneless != layout {
+				if t, zerr := time.ParseInLocation(zoneless, str, o.TimeLocation); zerr == nil {
+					return t, nil
+				}
+			}
+		}
+	}
+	if len(layouts) == 1 {
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time %q with any of the %d layouts %q", str, len(layouts), layouts)
+}
+
+// parseTimeLayout is time.Parse, in the TimeLocation when set.
+func (o *Options) parseTimeLayout(layout, str string) (time.Time, error) {
+	if o.TimeLocation == nil {
+		return time.Parse(layout, str)
+	}
+	return time.ParseInLocation(layout, str, o.TimeLocation)
+}
+
+// zoneLayoutElements are the time layout elements for the zone, longest first.
+var zoneLayoutElements = []string{"Z07:00", "-07:00", "Z0700", "-0700", "Z07", "-07", "MST"}
+
+// zonelessLayout returns layout without its zone element, e.g. "2006-01-02T15:04:05" for time.RFC3339.
+func zonelessLayout(layout string) string {
+	for _, zone := range zoneLayoutElements {
+		if idx := strings.Index(layout, zone); idx >= 0 {
+			return strings.TrimSpace(layout[:idx] + layout[idx+len(zone):])
+		}
+	}
+	return layout
+}
+
+func (o *Options) keyMapper() KeyMapper {
+	if o.KeyMapper.Field == nil {
+		return UpperSnakeKeys
+	}
+	return o.KeyMapper
+}
+
+func (o *Options) typePrefix(s interface{}) string {
+	return typePrefix(o.keyMapper(), s, o.TrimTypeSuffix)
+}
+
+// typeSuffixes are the type name suffixes removed by TypePrefix when trimming.
+var typeSuffixes = []string{"Configuration", "Config", "Conf", "Cfg", "Settings", "Options", "Opts", "Params"}
+
+// TypePrefix returns the prefix derived from the name of the struct type of s (or of what s points to):
+// the name converted to UPPER_SNAKE_CASE followed by _, e.g. FOO_CONFIG_ for a FooConfig. When trim is true
+// the common Config, Settings, Options,... suffixes are removed first (so FooConfig gives FOO_).
+// Returns "" for unnamed types.
+func TypePrefix(s interface{}, trim bool) string {
+	return typePrefix(UpperSnakeKeys, s, trim)
+}
+
+func typePrefix(keys KeyMapper, s interface{}, trim bool) string {
+	t := reflect.TypeOf(s)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return ""
+	}
+	name := t.Name()
+	if trim {
+		for _, suffix := range typeSuffixes {
+			if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+				name = strings.TrimSuffix(name, suffix)
+				break
+			}
+		}
+	}
+	return keys.Field(name) + keys.Sep
+}
+
+// Decoder sets struct fields from environment variables (reverse of StructToEnvVars), with
+// the behavior tuned by its Options. Like Encoder it can be used concurrently as long as it isn't
+// modified meanwhile, and doesn't have a Trace (whose entries are appended to).
+type Decoder struct {
+	Options
+	Lookup EnvLookup // Source of the values.
+	// Keys enumerates the available variable names, needed to fill map[string]string fields
+	// (which are left untouched when Keys is nil), see SetFrom.
+	Keys EnvKeys
+	// Sources, when not empty, are tried in order instead of Lookup; their names show up in the Trace.
+	Sources []NamedLookup
+	// Trace, when not nil, gets one entry appended per field resolved by SetFrom.
+	Trace *Trace
+	// Schema, when not nil, is checked for each value found and for the presence of its required names.
+	Schema *Schema
+	// CheckContractHash makes SetFrom return an ErrContractMismatch error when the ContractHashKey variable
+	// (see Encoder.EmitContractHash) is set to a different value than the ContractHash of the struct.
+	CheckContractHash bool
+	// PrefixFallback makes SetFrom also try, for the variables not found, the names with the leading
+	// levels of the prefix removed: APP_SERVICE_TIMEOUT then SERVICE_TIMEOUT then TIMEOUT, so platform
+	// wide variables can be shared by services while still allowing per app overrides.
+	// It doesn't apply to map fields nor noprefix ones.
+	PrefixFallback bool
+	// EmptyAsUnset makes the variables set to the empty string count as unset, leaving the fields' values
+	// (or their `default=`) as is, for deployment systems which can't express unset. With Sources, the
+	// next ones are tried.
+	EmptyAsUnset bool
+	// ClampIntegers makes SetFrom set the integer fields to the closest value of their type's range
+	// (e.g. 127 for 300 in an int8) instead of returning an out of range error.
+	ClampIntegers bool
 }
 
-func setFromEnv(allErrors []error, envLookup EnvLookup, prefix string, s interface{}) []error {
+// NewDecoder returns a Decoder using the given lookup and default options.
+func NewDecoder(envLookup EnvLookup) *Decoder {
+	return &Decoder{Lookup: envLookup}
+}
+
+// SetFrom sets the fields of s (pointer to a struct) from the values found through the Decoder's Lookup
+// for the (prefixed) environment variable names. Returns all the errors encountered.
+// With the AutoPrefix option, an empty prefix is replaced by the one derived from the type of s.
+// map[string]string fields get one entry per variable named PREFIX_FIELD_<key> amongst the Decoder's Keys,
+// with the key lowercased unless the field has the `keepcase` option.
+func (d *Decoder) SetFrom(prefix string, s interface{}) []error {
+	if prefix == "" && d.AutoPrefix {
+		prefix = d.typePrefix(s)
+	}
+	allErrors := d.setFromEnv(nil, prefix, "", reflect.ValueOf(s))
+	if d.Schema != nil {
+		allErrors = d.Schema.checkRequired(d, allErrors)
+	}
+	if d.CheckContractHash {
+		allErrors = d.checkContractHash(allErrors, prefix, s)
+	}
+	return d.limitErrors(allErrors)
+}
+
+func (d *Decoder) setFromEnv(allErrors []error, prefix, path string, v reflect.Value) []error {
 	// TODO: this is quite similar in structure to structToEnvVars() - can it be refactored with
 	// passing setter vs getter function and share the same iteration (yet a little bit of copy is the go way too)
-	v := reflect.ValueOf(s)
 	// if we're passed a pointer to a struct instead of the struct, let that work too
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
@@ -331,67 +1407,177 @@ func setFromEnv(allErrors []error, envLookup EnvLookup, prefix string, s interfa
 		allErrors = append(allErrors, err)
 		return allErrors
 	}
+	keys := d.keyMapper()
 	t := v.Type()
-	for i := 0; i < t.NumField(); i++ {
+	var templates []int // indexes of the template fields, expanded once all the fields are set.
+	info := cachedStructInfo(t)
+	for _, i := range info.order {
+		if d.tooManyErrors(allErrors) {
+			break
+		}
 		fieldType := t.Field(i)
-		tag := fieldType.Tag.Get("env")
+		tag, opts := info.fields[i].tag, info.fields[i].opts
 		if tag == "-" {
 			continue
 		}
-		if tag == "" {
-			tag = CamelCaseToUpperSnakeCase(fieldType.Name)
+		if d.StrictTags {
+			if err := opts.check(fieldType.Name); err != nil {
+				allErrors = append(allErrors, err)
+				continue
+			}
+		}
+		if opts.Contains("writeonly") {
+			continue
 		}
-		envName := prefix + tag
 		fieldValue := v.Field(i)
-
 		kind := fieldValue.Kind()
+		if fieldType.Anonymous && isEmbeddedStruct(fieldType.Type) {
+			// Embedded struct fields are at the same level as ours (like StructToEnvVars does).
+			if kind == reflect.Ptr {
+				if fieldValue.IsNil() {
+					if !fieldValue.CanSet() {
+						allErrors = append(allErrors, embedError(t, i, "is a nil pointer to an unexported type, can't allocate it"))
+						continue
+					}
+					fieldValue.Set(reflect.New(fieldType.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			allErrors = d.setFromEnv(allErrors, prefix, path, fieldValue)
+			continue
+		}
+		if fieldType.Anonymous && fieldType.PkgPath != "" {
+			allErrors = append(allErrors, embedError(t, i, unexportedEmbed))
+			continue
+		}
+		tag = info.key(i, fieldType.Name, &d.Options)
+		envName := prefix + tag
+		_, hasCodec := opts.Get("codec")
+		isStruct := kind == reflect.Struct && !isTimeType(fieldType.Type) && !hasCodec
+		if opts.Contains("noprefix") {
+			if isStruct {
+				allErrors = append(allErrors, fmt.Errorf("noprefix is only supported on non struct fields (%s)", fieldType.Name))
+				continue
+			}
+			envName = tag
+		}
+		if guard, found := opts.Get("when"); found {
+			active, err := guardActive(v, &d.Options, guard)
+			if err != nil {
+				allErrors = append(allErrors, fmt.Errorf("%s%s: %w", path, fieldType.Name, err))
+				continue
+			}
+			if !active {
+				continue
+			}
+		}
 
 		// Handle time.Time separately a bit below after we get the value
-		if kind == reflect.Struct && fieldType.Type != reflect.TypeOf(time.Time{}) {
+		if isStruct {
 			// Recurse with prefix
 			if fieldValue.CanAddr() { // Check if we can get the address
-				allErrors = setFromEnv(allErrors, envLookup, envName+"_", fieldValue.Addr().Interface())
+				allErrors = d.setFromEnv(allErrors, envName+keys.Sep, path+fieldType.Name+".", fieldValue)
 			} else {
 				err := fmt.Errorf("cannot take the address of %s to recurse", fieldType.Name)
 				allErrors = append(allErrors, err)
 			}
 			continue
 		}
-		val, err := checkEnv(envLookup, envName, fieldType.Name, fieldValue)
-		if err != nil {
-			allErrors = append(allErrors, err)
+		if kind == reflect.Map && isStringMap(fieldType.Type) && !hasCodec && !opts.Contains("inline") {
+			allErrors = d.setMap(allErrors, path+fieldType.Name, fieldValue, opts, envName+keys.Sep)
 			continue
 		}
-		if val == nil {
+		names := []string{envName}
+		if d.PrefixFallback && !opts.Contains("noprefix") {
+			names = fallbackNames(prefix, tag, keys.Sep)
+		}
+		for _, alias := range opts.Values("alias") {
+			if !opts.Contains("noprefix") {
+				alias = prefix + alias
+			}
+			names = append(names, alias)
+		}
+		if d.isReserved(envName, opts) {
+			allErrors = append(allErrors, &ReservedError{EnvName: envName, Field: path + fieldType.Name})
 			continue
 		}
-		envVal := *val
+		names = d.dropReserved(names, opts)
+		allErrors = d.setField(allErrors, path+fieldType.Name, fieldType, fieldValue, opts, names)
+		if opts.Contains("template") {
+			templates = append(templates, i)
+		}
+	}
+	allErrors = checkRequires(allErrors, path, v, &d.Options)
+	return d.expandTemplates(allErrors, path, v, templates)
+}
 
-		// Handle pointer fields separately
-		if kind == reflect.Ptr {
-			kind = fieldValue.Type().Elem().Kind()
-			fieldValue = setPointer(fieldValue)
+// setField sets a single (non struct) field from envName, recording the outcome in the Trace if any.
+func (d *Decoder) setField(allErrors []error, fieldPath string, fieldType reflect.StructField, fieldValue reflect.Value,
+	opts tagOptions, names []string,
+) []error {
+	val, source, envName, err := d.checkEnv(names, fieldType.Name, fieldValue)
+	if def, found := opts.Get("default"); found && val == nil && err == nil {
+		val, source = &def, "default"
+		if !fieldValue.CanSet() {
+			err = fmt.Errorf("can't set %s (default %q)", fieldType.Name, def)
 		}
-		if fieldType.Type == reflect.TypeOf(time.Time{}) {
-			var timeField time.Time
-			timeField, err = time.Parse(time.RFC3339, envVal)
-			if err == nil {
-				fieldValue.Set(reflect.ValueOf(timeField))
-			} else {
-				allErrors = append(allErrors, err)
-			}
-			continue
+	}
+	if d.Trace != nil {
+		numErrors := len(allErrors)
+		defer func() {
+			d.Trace.add(fieldPath, names, source, val, allErrors[numErrors:])
+		}()
+	}
+	if err != nil {
+		allErrors = append(allErrors, err)
+		return allErrors
+	}
+	if opts.Contains("required") && (val == nil || *val == "") {
+		allErrors = append(allErrors, &MissingError{EnvName: envName, Field: fieldPath})
+		return allErrors
+	}
+	if val == nil {
+		return allErrors
+	}
+	envVal := *val
+	if d.Schema != nil {
+		if err = d.Schema.Validate(envName, envVal); err != nil {
+			allErrors = append(allErrors, err)
+			return allErrors
+		}
+	}
+	if codec, found := opts.Get("codec"); found {
+		if err = d.setCodec(codec, fieldType.Name, fieldValue, envName, envVal); err != nil {
+			allErrors = append(allErrors, err)
+		}
+		return allErrors
+	}
+	kind := fieldValue.Kind()
+	// Handle pointer fields separately
+	if kind == reflect.Ptr {
+		kind = fieldValue.Type().Elem().Kind()
+		fieldValue = setPointer(fieldValue)
+	}
+	if isTimeType(fieldValue.Type()) {
+		var timeField time.Time
+		timeField, err = d.parseTimeField(envVal, opts)
+		if err == nil {
+			timeValue(fieldValue).Set(reflect.ValueOf(timeField))
+		} else {
+			allErrors = append(allErrors, err)
 		}
-		allErrors = setValue(allErrors, fieldType, fieldValue, kind, envName, envVal)
+		return allErrors
 	}
+	allErrors = d.setValue(allErrors, fieldType, fieldValue, kind, opts, envName, envVal)
 	return allErrors
 }
 
-func setValue(
+func (d *Decoder) setValue(
 	allErrors []error,
 	fieldType reflect.StructField,
 	fieldValue reflect.Value,
 	kind reflect.Kind,
+	opts tagOptions,
 	envName, envVal string,
 ) []error {
 	var err error
@@ -400,39 +1586,103 @@ func setValue(
 		fieldValue.SetString(envVal)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		// if it's a duration, parse it as a float seconds
-		if fieldType.Type == reflect.TypeOf(time.Duration(0)) {
-			var ev float64
-			ev, err = strconv.ParseFloat(envVal, 64)
+		if isDurationType(fieldValue.Type(), opts) {
+			var ev time.Duration
+			ev, err = d.parseDuration(envVal)
 			if err == nil {
-				fieldValue.SetInt(int64(ev * float64(1*time.Second)))
+				fieldValue.SetInt(int64(ev))
+			}
+		} else if opts.Contains("size") {
+			var ev int64
+			ev, err = ParseByteSize(envVal)
+			if err == nil && fieldValue.OverflowInt(ev) {
+				ev, err = clampInt(ev, fieldValue.Type()), strconv.ErrRange
+			}
+			if err = d.checkRange(err, envName, envVal, fieldValue.Type()); err == nil {
+				fieldValue.SetInt(ev)
 			}
 		} else {
 			var ev int64
 			ev, err = strconv.ParseInt(envVal, 10, fieldValue.Type().Bits())
-			if err == nil {
+			if err = d.checkRange(err, envName, envVal, fieldValue.Type()); err == nil {
 				fieldValue.SetInt(ev)
 			}
 		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var ev uint64
+		if opts.Contains("size") {
+			var sz int64
+			sz, err = ParseByteSize(envVal)
+			if err == nil && sz < 0 {
+				err = fmt.Errorf("negative size %s=%q for %v", envName, envVal, fieldValue.Type())
+			}
+			ev = uint64(sz)
+			if err == nil && fieldValue.OverflowUint(ev) {
+				ev, err = math.MaxUint64>>(64-fieldValue.Type().Bits()), strconv.ErrRange
+			}
+		} else {
+			ev, err = strconv.ParseUint(envVal, 10, fieldValue.Type().Bits())
+			if err != nil && strings.HasPrefix(envVal, "-") {
+				if _, e := strconv.ParseInt(envVal, 10, 64); e == nil || errors.Is(e, strconv.ErrRange) {
+					ev, err = 0, strconv.ErrRange // Negative value.
+				}
+			}
+		}
+		if err = d.checkRange(err, envName, envVal, fieldValue.Type()); err == nil {
+			fieldValue.SetUint(ev)
+		}
 	case reflect.Float32, reflect.Float64:
 		var ev float64
-		ev, err = strconv.ParseFloat(envVal, fieldValue.Type().Bits())
-		if err == nil {
+		if opts.Contains("percent") {
+			ev, err = ParsePercent(envVal)
+		} else {
+			ev, err = strconv.ParseFloat(envVal, fieldValue.Type().Bits())
+		}
+		switch {
+		case err != nil:
+		case d.NonFinite != NonFiniteText && (math.IsNaN(ev) || math.IsInf(ev, 0)):
+			if d.NonFinite == NonFiniteError {
+				err = fmt.Errorf("non finite value %s=%q for %s", envName, envVal, fieldType.Name)
+			}
+		default:
 			fieldValue.SetFloat(ev)
 		}
 	case reflect.Bool:
 		var ev bool
-		ev, err = strconv.ParseBool(envVal)
+		if d.LenientBool {
+			ev, err = ParseLenientBool(envVal)
+		} else {
+			ev, err = strconv.ParseBool(envVal)
+		}
 		if err == nil {
 			fieldValue.SetBool(ev)
 		}
 	case reflect.Slice:
-		if fieldValue.Type().Elem().Kind() != reflect.Uint8 {
-			err = fmt.Errorf("unsupported slice of %v to set from %s=%q", fieldValue.Type().Elem().Kind(), envName, envVal)
-		} else {
+		switch fieldValue.Type().Elem().Kind() { //nolint: exhaustive // we have default: for the other cases
+		case reflect.String:
+			splitStrings(fieldValue, envVal, separator(opts))
+		case reflect.Uint8:
 			var data []byte
 			data, err = base64.StdEncoding.DecodeString(envVal)
 			fieldValue.SetBytes(data)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			if !isNumberSlice(fieldValue.Type()) {
+				err = fmt.Errorf("unsupported slice of %v to set from %s=%q", fieldValue.Type().Elem(), envName, envVal)
+				break
+			}
+			err = splitNumbers(fieldValue, envName, envVal, separator(opts))
+		default:
+			err = fmt.Errorf("unsupported slice of %v to set from %s=%q", fieldValue.Type().Elem().Kind(), envName, envVal)
 		}
+	case reflect.Map:
+		if isStringMap(fieldValue.Type()) {
+			err = splitMap(fieldValue, envName, envVal, separator(opts))
+		} else {
+			err = fmt.Errorf("unsupported type %v to set from %s=%q", fieldValue.Type(), envName, envVal)
+		}
+	case reflect.Array:
+		err = setArray(fieldValue, envName, envVal)
 	default:
 		err = fmt.Errorf("unsupported type %v to set from %s=%q", kind, envName, envVal)
 	}
@@ -441,3 +1691,55 @@ func setValue(
 	}
 	return allErrors
 }
+
+// parseDuration parses durations according to the DurationFormat.
+func (o *Options) parseDuration(str string) (time.Duration, error) {
+	switch o.DurationFormat {
+	case DurationString:
+		return time.ParseDuration(str)
+	case DurationMilliseconds:
+		return parseDecimalDuration(str, "ms", time.Millisecond)
+	default:
+		return parseDecimalDuration(str, "s", time.Second)
+	}
+}
+
+// parseDecimalDuration parses a number of units. Plain decimal numbers (e.g. 3600.000000001) are parsed
+// exactly, to the nanosecond, through time.ParseDuration with the unit's suffix; others (e.g. 1e-09)
+// as floats.
+func parseDecimalDuration(str, suffix string, unit time.Duration) (time.Duration, error) {
+	if str != "" && strings.Trim(str, "+-.0123456789") == "" {
+		if d, err := time.ParseDuration(str + suffix); err == nil {
+			return d, nil
+		}
+	}
+	ev, err := strconv.ParseFloat(str, 64)
+	return time.Duration(ev * float64(unit)), err
+}
+
+// ParsePercent parses either a fraction (0.75) or a percentage (75%) into the fraction (0.75).
+func ParsePercent(str string) (float64, error) {
+	s := strings.TrimSpace(str)
+	div := 1.
+	if strings.HasSuffix(s, "%") {
+		s = strings.TrimSpace(s[:len(s)-1])
+		div = 100.
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage or fraction %q: %w", str, err)
+	}
+	return f / div, nil
+}
+
+// ParseLenientBool is like strconv.ParseBool but also accepts, case-insensitively,
+// yes/no, y/n, on/off and enabled/disabled as operators tend to set FEATURE=yes.
+func ParseLenientBool(str string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "1", "t", "true", "y", "yes", "on", "enable", "enabled":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off", "disable", "disabled":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q (expecting true/false, yes/no, on/off or enabled/disabled)", str)
+}
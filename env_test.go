@@ -1,7 +1,12 @@
 package struct2env
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -93,6 +98,14 @@ func TestCamelCaseToLowerKebabCase(t *testing.T) {
 			t.Errorf("for %q expected %q and got %q", test.in, test.out, got)
 		}
 	}
+	if got := CamelCaseToUpperKebabCase("HTTPSServer42"); got != "HTTPS-SERVER42" {
+		t.Errorf("unexpected screaming kebab %q", got)
+	}
+	for in, out := range map[string]string{"": "", "HTTPServer": "httpServer", "XRequestID": "xRequestId", "a": "a"} {
+		if got := CamelCaseToLowerCamelCase(in); got != out {
+			t.Errorf("for %q expected lower camel %q and got %q", in, out, got)
+		}
+	}
 }
 
 type Embedded struct {
@@ -289,3 +302,1180 @@ func TestSetFromEnv(t *testing.T) {
 		t.Errorf("Expected 1 error, got %v", errors)
 	}
 }
+
+func TestParseLenientBool(t *testing.T) {
+	for _, in := range []string{"1", "true", "TRUE", "T", "yes", "Yes", "y", "on", "ON", "enabled", " Enabled "} {
+		if v, err := ParseLenientBool(in); err != nil || !v {
+			t.Errorf("expected %q to parse as true, got %v %v", in, v, err)
+		}
+	}
+	for _, in := range []string{"0", "false", "F", "no", "NO", "n", "off", "Off", "disabled", "DISABLE"} {
+		if v, err := ParseLenientBool(in); err != nil || v {
+			t.Errorf("expected %q to parse as false, got %v %v", in, v, err)
+		}
+	}
+	for _, in := range []string{"", "maybe", "yess", "2"} {
+		if _, err := ParseLenientBool(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestDecoderLenientBool(t *testing.T) {
+	type Cfg struct {
+		Feature bool
+	}
+	lookup := func(key string) (string, bool) {
+		return "yes", key == "FEATURE"
+	}
+	cfg := Cfg{}
+	errors := SetFrom(lookup, "", &cfg)
+	if len(errors) != 1 {
+		t.Errorf("expected strict parse error for yes, got %v", errors)
+	}
+	d := NewDecoder(lookup)
+	d.LenientBool = true
+	errors = d.SetFrom("", &cfg)
+	if len(errors) != 0 || !cfg.Feature {
+		t.Errorf("expected lenient parse of yes, got %v %+v", errors, cfg)
+	}
+}
+
+type BenchConfig struct {
+	Name       string
+	Host       string
+	Port       int
+	Timeout    time.Duration
+	Ratio      float64
+	Enabled    bool
+	MaxConns   int64
+	Retries    uint8
+	Labels     string
+	Nested     Embedded
+	Binary     []byte
+	IntPointer *int
+}
+
+func newBenchConfig() *BenchConfig {
+	intV := 42
+	return &BenchConfig{
+		Name:       "bench",
+		Host:       "localhost",
+		Port:       8080,
+		Timeout:    1500 * time.Millisecond,
+		Ratio:      0.75,
+		Enabled:    true,
+		MaxConns:   12345,
+		Retries:    3,
+		Labels:     "it's a label",
+		Nested:     Embedded{InnerA: "a", InnerB: "b"},
+		Binary:     []byte{1, 2, 3},
+		IntPointer: &intV,
+	}
+}
+
+func BenchmarkStructToEnvVars(b *testing.B) {
+	cfg := newBenchConfig()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, errs := StructToEnvVars(cfg)
+		if len(errs) != 0 {
+			b.Fatalf("unexpected errors %v", errs)
+		}
+	}
+}
+
+func TestConvertCaseMatchesSplitByCase(t *testing.T) {
+	inputs := []string{
+		"", "a", "HTTPServer", "http2Server", "ABCd", "aaBbbCcc", "ÉtéÀParis", "xÆyZ", "a_b-C", "\xffBad",
+	}
+	for _, in := range inputs {
+		words := SplitByCase(in)
+		if got, expected := CamelCaseToUpperSnakeCase(in), strings.ToUpper(strings.Join(words, "_")); got != expected {
+			t.Errorf("for %q expected %q and got %q", in, expected, got)
+		}
+		if got, expected := CamelCaseToLowerKebabCase(in), strings.ToLower(strings.Join(words, "-")); got != expected {
+			t.Errorf("for %q expected %q and got %q", in, expected, got)
+		}
+	}
+}
+
+func TestSerializeValueFastPath(t *testing.T) {
+	type Level int
+	type Named string
+	values := []interface{}{
+		0, -42, int8(-8), uint16(65535), uint64(1 << 63), 3.5, float32(0.1), 1e21, true, false,
+		"it's", "plain", Named("named"), Level(3), time.Duration(1500) * time.Millisecond, time.Month(5),
+	}
+	for _, value := range values {
+		var expected, got KeyValue
+		errE := SerializeValue(&expected, value)
+		errG := serializeValue(&got, reflect.ValueOf(value))
+		if !reflect.DeepEqual(expected, got) || (errE == nil) != (errG == nil) {
+			t.Errorf("mismatch for %T %v: expected %+v got %+v (%v %v)", value, value, expected, got, errE, errG)
+		}
+	}
+}
+
+func BenchmarkCamelCaseToUpperSnakeCase(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CamelCaseToUpperSnakeCase("HTTPSServerMaxConnections")
+	}
+}
+
+type HTTPConf struct {
+	Port int
+}
+
+type ServerConf struct {
+	HTTP     HTTPConf
+	HostName string
+}
+
+type TopConf struct {
+	Server ServerConf
+	Embedded
+}
+
+func TestKeyMappers(t *testing.T) {
+	top := TopConf{Server: ServerConf{HTTP: HTTPConf{Port: 8080}, HostName: "localhost"}}
+	top.InnerA = "a"
+	tests := []struct {
+		keys     KeyMapper
+		expected []string
+	}{
+		{KeyMapper{}, []string{"SERVER_HTTP_PORT", "SERVER_HOST_NAME", "INNER_A", "INNER_B"}},
+		{DotKeys, []string{"server.http.port", "server.host.name", "inner.a", "inner.b"}},
+		{PathKeys, []string{"server/http/port", "server/host/name", "inner/a", "inner/b"}},
+		{VerbatimKeys, []string{"Server_HTTP_Port", "Server_HostName", "InnerA", "InnerB"}},
+		{LowerSnakeKeys, []string{"server_http_port", "server_host_name", "inner_a", "inner_b"}},
+		{UpperKebabKeys, []string{"SERVER-HTTP-PORT", "SERVER-HOST-NAME", "INNER-A", "INNER-B"}},
+		{LowerCamelKeys, []string{"server_http_port", "server_hostName", "innerA", "innerB"}},
+		{
+			KeyMapper{Field: strings.ToLower, Sep: "__"},
+			[]string{"server__http__port", "server__hostname", "innera", "innerb"},
+		},
+	}
+	for _, test := range tests {
+		e := NewEncoder()
+		e.KeyMapper = test.keys
+		kvl, errors := e.StructToEnvVars(top)
+		if len(errors) != 0 {
+			t.Errorf("unexpected errors %v", errors)
+		}
+		var got []string
+		lookupMap := map[string]string{}
+		for _, kv := range kvl {
+			got = append(got, kv.Key)
+			lookupMap["P"+test.keys.Sep+kv.Key] = kv.Value
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected keys %v got %v", test.expected, got)
+		}
+		// Round trip
+		d := NewDecoder(func(key string) (string, bool) {
+			v, found := lookupMap[key]
+			return v, found
+		})
+		d.KeyMapper = test.keys
+		res := TopConf{}
+		errors = d.SetFrom("P"+test.keys.Sep, &res)
+		if len(errors) != 0 || res != top {
+			t.Errorf("round trip mismatch %+v vs %+v: %v", res, top, errors)
+		}
+	}
+}
+
+func TestLowerDotAndPathCase(t *testing.T) {
+	if got := CamelCaseToLowerDotCase("HTTPServerPort"); got != "http.server.port" {
+		t.Errorf("unexpected dot case %q", got)
+	}
+	if got := CamelCaseToLowerPathCase("HTTPServerPort"); got != "http/server/port" {
+		t.Errorf("unexpected path case %q", got)
+	}
+}
+
+func TestCamelCaseToTrainCase(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"", ""},
+		{"a", "A"},
+		{"AB", "Ab"},
+		{"HTTPServer", "Http-Server"},
+		{"XRequestID", "X-Request-Id"},
+		{"contentType", "Content-Type"},
+		{"http2Server", "Http2-Server"},
+		{"étéParis", "Été-Paris"},
+	}
+	for _, test := range tests {
+		if got := CamelCaseToTrainCase(test.in); got != test.out {
+			t.Errorf("for %q expected %q and got %q", test.in, test.out, got)
+		}
+	}
+}
+
+func TestUpperSnakeCaseToCamelCase(t *testing.T) {
+	tests := []struct {
+		in    string
+		out   string
+		lower string
+	}{
+		{"", "", ""},
+		{"A", "A", "a"},
+		{"HTTP_SERVER", "HttpServer", "httpServer"},
+		{"HTTP2_SERVER", "Http2Server", "http2Server"},
+		{"A_SPECIAL__BLAH_", "ASpecialBlah", "aSpecialBlah"},
+		{"_LEADING", "Leading", "leading"},
+		{"ÉTÉ_PARIS", "ÉtéParis", "étéParis"},
+	}
+	for _, test := range tests {
+		if got := UpperSnakeCaseToCamelCase(test.in); got != test.out {
+			t.Errorf("for %q expected %q and got %q", test.in, test.out, got)
+		}
+		if got := UpperSnakeCaseToLowerCamelCase(test.in); got != test.lower {
+			t.Errorf("for %q expected lower %q and got %q", test.in, test.lower, got)
+		}
+	}
+	// Round trip for non acronym names
+	for _, name := range []string{"FooBar", "HostName", "MaxConns2"} {
+		if got := UpperSnakeCaseToCamelCase(CamelCaseToUpperSnakeCase(name)); got != name {
+			t.Errorf("round trip of %q gave %q", name, got)
+		}
+		if got := LowerSnakeCaseToCamelCase(CamelCaseToLowerSnakeCase(name)); got != name {
+			t.Errorf("snake round trip of %q gave %q", name, got)
+		}
+		if got := KebabCaseToCamelCase(CamelCaseToLowerKebabCase(name)); got != name {
+			t.Errorf("kebab round trip of %q gave %q", name, got)
+		}
+		if got := KebabCaseToCamelCase(CamelCaseToTrainCase(name)); got != name {
+			t.Errorf("train case round trip of %q gave %q", name, got)
+		}
+	}
+	if got := LowerSnakeCaseToCamelCase("http2_server__x"); got != "Http2ServerX" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := KebabCaseToCamelCase("-x-request-id"); got != "XRequestId" {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestKeyValueStringAndJSON(t *testing.T) {
+	type Cfg struct {
+		Name  string
+		Count int
+	}
+	kvl, errors := StructToEnvVars(Cfg{Name: "it's \"quoted\"\n", Count: 3})
+	if len(errors) != 0 {
+		t.Fatalf("unexpected errors %v", errors)
+	}
+	if str := kvl[1].String(); str != "COUNT='3'" {
+		t.Errorf("unexpected String() %q", str)
+	}
+	if str := fmt.Sprint(kvl[0]); str != `NAME='it'\''s "quoted"`+"\n'" {
+		t.Errorf("unexpected Sprint %q", str)
+	}
+	data, err := json.Marshal(kvl)
+	if err != nil {
+		t.Fatalf("unexpected marshal error %v", err)
+	}
+	expected := `[{"name":"NAME","value":"it's \"quoted\"\n"},{"name":"COUNT","value":"3"}]`
+	if string(data) != expected {
+		t.Errorf("expected %s got %s", expected, data)
+	}
+	var back []KeyValue
+	if err = json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("unexpected unmarshal error %v", err)
+	}
+	for i := range kvl {
+		kvl[i].Index, kvl[i].Type = nil, "" // The struct position and type aren't serialized.
+	}
+	if !reflect.DeepEqual(back, kvl) {
+		t.Errorf("round trip mismatch %+v vs %+v", back, kvl)
+	}
+	if err = json.Unmarshal([]byte(`{"name":"X","value":"a\u0000b"}`), &back[0]); err == nil {
+		t.Errorf("expected error for NUL in value")
+	}
+}
+
+func TestAutoPrefix(t *testing.T) {
+	type AppSettings struct {
+		Port int
+	}
+	type Config struct {
+		Port int
+	}
+	tests := []struct {
+		s        interface{}
+		trim     bool
+		expected string
+	}{
+		{FooConfig{}, false, "FOO_CONFIG_"},
+		{&FooConfig{}, true, "FOO_"},
+		{AppSettings{}, true, "APP_"},
+		{Config{}, true, "CONFIG_"},
+		{struct{ A int }{}, true, ""},
+		{nil, false, ""},
+	}
+	for _, test := range tests {
+		if got := TypePrefix(test.s, test.trim); got != test.expected {
+			t.Errorf("for %T trim %v expected %q got %q", test.s, test.trim, test.expected, got)
+		}
+	}
+	e := NewEncoder()
+	e.AutoPrefix = true
+	e.TrimTypeSuffix = true
+	kvl, errors := e.StructToEnvVars(AppSettings{Port: 80})
+	if len(errors) != 0 || len(kvl) != 1 || kvl[0].Key != "APP_PORT" {
+		t.Errorf("unexpected auto prefixed result %v %v", kvl, errors)
+	}
+	d := NewDecoder(func(key string) (string, bool) {
+		return "8080", key == "APP_PORT"
+	})
+	d.AutoPrefix = true
+	d.TrimTypeSuffix = true
+	res := AppSettings{}
+	errors = d.SetFrom("", &res)
+	if len(errors) != 0 || res.Port != 8080 {
+		t.Errorf("unexpected auto prefixed decoding %+v %v", res, errors)
+	}
+}
+
+func TestNoPrefix(t *testing.T) {
+	type Proxy struct {
+		HTTPProxy string `env:"HTTP_PROXY,noprefix"`
+		Timeout   int
+	}
+	type Cfg struct {
+		Name  string
+		Home  string `env:",noprefix"`
+		Proxy Proxy
+	}
+	cfg := Cfg{Name: "n", Home: "/home/x", Proxy: Proxy{HTTPProxy: "http://proxy", Timeout: 5}}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("APP_", kvl, false)
+	expected := `APP_NAME='n'
+HOME='/home/x'
+HTTP_PROXY='http://proxy'
+APP_PROXY_TIMEOUT='5'
+export APP_NAME HOME HTTP_PROXY APP_PROXY_TIMEOUT
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	str = ToYamlWithPrefix(0, "APP_", kvl[:2])
+	expected = "- name: APP_NAME\n  value: \"n\"\n- name: HOME\n  value: \"/home/x\"\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	envs := map[string]string{
+		"APP_NAME":          "n2",
+		"HOME":              "/root",
+		"APP_HOME":          "wrong",
+		"HTTP_PROXY":        "http://other",
+		"APP_PROXY_TIMEOUT": "7",
+	}
+	res := Cfg{}
+	errors = SetFrom(func(key string) (string, bool) {
+		v, found := envs[key]
+		return v, found
+	}, "APP_", &res)
+	expectedCfg := Cfg{Name: "n2", Home: "/root", Proxy: Proxy{HTTPProxy: "http://other", Timeout: 7}}
+	if len(errors) != 0 || res != expectedCfg {
+		t.Errorf("unexpected decoding %+v %v", res, errors)
+	}
+	type Bad struct {
+		P Proxy `env:",noprefix"`
+	}
+	if _, errors = StructToEnvVars(Bad{}); len(errors) != 1 {
+		t.Errorf("expected error for noprefix on struct, got %v", errors)
+	}
+	if errors = SetFrom(func(string) (string, bool) { return "", false }, "", &Bad{}); len(errors) != 1 {
+		t.Errorf("expected error for noprefix on struct, got %v", errors)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		in  string
+		out float64
+	}{
+		{"75%", 0.75},
+		{" 75 % ", 0.75},
+		{"0.75", 0.75},
+		{"100%", 1},
+		{"0.5%", 0.005},
+		{"1", 1},
+	}
+	for _, test := range tests {
+		if got, err := ParsePercent(test.in); err != nil || got != test.out {
+			t.Errorf("for %q expected %g got %g (%v)", test.in, test.out, got, err)
+		}
+	}
+	for _, in := range []string{"", "%", "abc%", "75%%"} {
+		if _, err := ParsePercent(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+	type Cfg struct {
+		SamplingRate float64  `env:",percent"`
+		CPULimit     *float32 `env:",percent"`
+		NotAFloat    int      `env:",percent"`
+	}
+	cfg := Cfg{}
+	errors := SetFrom(MapLookup(map[string]string{"SAMPLING_RATE": "12.5%", "CPU_LIMIT": "0.5"}), "", &cfg)
+	if len(errors) != 0 || cfg.SamplingRate != 0.125 || cfg.CPULimit == nil || *cfg.CPULimit != 0.5 {
+		t.Errorf("unexpected decoding %+v %v", cfg, errors)
+	}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 error for percent on int, got %v", errors)
+	}
+	if kvl[0].Value != "0.125" || kvl[1].Value != "0.5" {
+		t.Errorf("unexpected serialization %v", kvl)
+	}
+}
+
+func TestTimeLayouts(t *testing.T) {
+	type Cfg struct {
+		TS time.Time
+	}
+	expected := time.Date(2023, time.March, 4, 0, 0, 0, 0, time.UTC)
+	inputs := []string{"2023-03-04T00:00:00Z", "2023-03-04T00:00:00.000Z", "2023-03-04", "Sat, 04 Mar 2023 00:00:00 UTC"}
+	for _, in := range inputs {
+		cfg := Cfg{}
+		d := NewDecoder(MapLookup(map[string]string{"TS": in}))
+		errors := d.SetFrom("", &cfg)
+		if in != inputs[0] && in != inputs[1] {
+			if len(errors) != 1 {
+				t.Errorf("expected error for %q with default layout, got %v", in, errors)
+			}
+		} else if len(errors) != 0 || !cfg.TS.Equal(expected) {
+			t.Errorf("unexpected decoding of %q: %v %v", in, cfg.TS, errors)
+		}
+		d.TimeLayouts = CommonTimeLayouts
+		errors = d.SetFrom("", &cfg)
+		if len(errors) != 0 || !cfg.TS.Equal(expected) {
+			t.Errorf("unexpected decoding of %q: %v %v", in, cfg.TS, errors)
+		}
+	}
+	d := NewDecoder(MapLookup(map[string]string{"TS": "yesterday"}))
+	d.TimeLayouts = CommonTimeLayouts
+	errors := d.SetFrom("", &Cfg{})
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "any of the 5 layouts") {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	e := NewEncoder()
+	e.TimeLayouts = []string{"2006-01-02"}
+	kvl, _ := e.StructToEnvVars(Cfg{TS: expected})
+	if kvl[0].Value != "2023-03-04" {
+		t.Errorf("unexpected encoding with custom layout %v", kvl)
+	}
+}
+
+func TestTimeZones(t *testing.T) {
+	type Cfg struct {
+		TS time.Time
+	}
+	paris := time.FixedZone("CET", 3600)
+	ts := time.Date(2024, time.March, 10, 9, 0, 0, 0, paris)
+	kvl, _ := StructToEnvVars(Cfg{TS: ts})
+	if kvl[0].Value != "2024-03-10T09:00:00+01:00" {
+		t.Errorf("unexpected default encoding %v", kvl)
+	}
+	e := NewEncoder()
+	e.TimeUTC = true
+	kvl, _ = e.StructToEnvVars(Cfg{TS: ts})
+	if kvl[0].Value != "2024-03-10T08:00:00Z" {
+		t.Errorf("unexpected UTC encoding %v", kvl)
+	}
+	for _, test := range []struct {
+		in       string
+		layouts  []string
+		expected time.Time
+	}{
+		{"2024-03-10T09:00:00", nil, ts},
+		{"2024-03-10T08:00:00Z", nil, ts},
+		{"2024-03-10", []string{"2006-01-02"}, time.Date(2024, time.March, 10, 0, 0, 0, 0, paris)},
+		{"Sun, 10 Mar 2024 09:00:00", CommonTimeLayouts, ts},
+	} {
+		d := NewDecoder(MapLookup(map[string]string{"TS": test.in}))
+		d.TimeLayouts = test.layouts
+		var cfg Cfg
+		if errs := d.SetFrom("", &cfg); len(errs) != 1 && test.in == "2024-03-10T09:00:00" {
+			t.Errorf("expected an error without TimeLocation for %q, got %v", test.in, errs)
+		}
+		d.TimeLocation = paris
+		if errs := d.SetFrom("", &cfg); len(errs) != 0 || !cfg.TS.Equal(test.expected) {
+			t.Errorf("unexpected decoding of %q: %v %v", test.in, cfg.TS, errs)
+		}
+	}
+	if got := zonelessLayout(time.RFC3339); got != "2006-01-02T15:04:05" {
+		t.Errorf("unexpected zoneless layout %q", got)
+	}
+}
+
+func TestUnixTimestamps(t *testing.T) {
+	type Cfg struct {
+		Cutover time.Time `env:",unix"`
+		Expiry  time.Time `env:",unixmilli"`
+	}
+	in := Cfg{
+		Cutover: time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Expiry:  time.Date(2024, time.January, 2, 3, 4, 5, 678000000, time.UTC),
+	}
+	kvl, errors := StructToEnvVars(in)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	if expected := "CUTOVER='1704164645'\nEXPIRY='1704164645678'\n"; str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	out := Cfg{}
+	errors = SetFrom(MapLookup(ToJSONMap(kvl)), "", &out)
+	if len(errors) != 0 || out != in {
+		t.Errorf("round trip mismatch %+v vs %+v: %v", out, in, errors)
+	}
+	errors = SetFrom(MapLookup(map[string]string{"CUTOVER": "2024-01-02"}), "", &out)
+	if len(errors) != 1 {
+		t.Errorf("expected error for non numeric unix timestamp, got %v", errors)
+	}
+}
+
+func TestDurationFormat(t *testing.T) {
+	type Cfg struct {
+		Dur time.Duration
+		Ptr *time.Duration
+	}
+	dur := 2 * time.Second
+	in := Cfg{Dur: 1*time.Hour + 100*time.Millisecond, Ptr: &dur}
+	tests := []struct {
+		format   DurationFormat
+		expected string
+	}{
+		{DurationSeconds, "  - name: DUR\n    value: 3600.1\n  - name: PTR\n    value: 2\n"},
+		{DurationMilliseconds, "  - name: DUR\n    value: 3600100\n  - name: PTR\n    value: 2000\n"},
+		{DurationString, "  - name: DUR\n    value: \"1h0m0.1s\"\n  - name: PTR\n    value: \"2s\"\n"},
+	}
+	for _, test := range tests {
+		e := NewEncoder()
+		e.DurationFormat = test.format
+		kvl, errors := e.StructToEnvVars(in)
+		if len(errors) != 0 {
+			t.Errorf("unexpected errors %v", errors)
+		}
+		if str := ToYamlWithPrefix(2, "", kvl); str != test.expected {
+			t.Errorf("\n---expected:---\n%s\n---got:---\n%s", test.expected, str)
+		}
+		d := NewDecoder(MapLookup(ToJSONMap(kvl)))
+		d.DurationFormat = test.format
+		out := Cfg{}
+		errors = d.SetFrom("", &out)
+		if len(errors) != 0 || out.Dur != in.Dur || out.Ptr == nil || *out.Ptr != dur {
+			t.Errorf("round trip mismatch for %v: %v %v %v", test.format, out.Dur, out.Ptr, errors)
+		}
+	}
+	d := NewDecoder(MapLookup(map[string]string{"DUR": "3600.1"}))
+	d.DurationFormat = DurationString
+	if errors := d.SetFrom("", &Cfg{}); len(errors) != 1 {
+		t.Errorf("expected error parsing seconds as Go duration, got %v", errors)
+	}
+}
+
+func TestDurationPrecision(t *testing.T) {
+	type Cfg struct {
+		Long  time.Duration
+		Tiny  time.Duration
+		Neg   *time.Duration
+		Fixed time.Duration `env:",precision=1"`
+	}
+	neg := -1500 * time.Millisecond
+	in := Cfg{Long: 400*24*time.Hour + time.Nanosecond, Tiny: time.Nanosecond, Neg: &neg,
+		Fixed: 1250 * time.Millisecond}
+	kvl, errs := StructToEnvVars(in)
+	// The default loses the nanosecond of Long.
+	if len(errs) != 0 || kvl[0].Value != "3.456e+07" || kvl[1].Value != "1e-09" || kvl[3].Value != "1.3" {
+		t.Errorf("unexpected default %v %v", kvl, errs)
+	}
+	e := NewEncoder()
+	e.DurationPrecision = 9
+	kvl, errs = e.StructToEnvVars(in)
+	expected := []string{"34560000.000000001", "0.000000001", "-1.500000000", "1.3"}
+	for i, kv := range kvl {
+		if kv.Value != expected[i] {
+			t.Errorf("got %q expected %q for %s (%v)", kv.Value, expected[i], kv.Key, errs)
+		}
+	}
+	var out Cfg
+	errs = SetFrom(MapLookup(ToJSONMap(kvl)), "", &out)
+	if len(errs) != 0 || out.Long != in.Long || out.Tiny != in.Tiny || *out.Neg != neg ||
+		out.Fixed != 1300*time.Millisecond {
+		t.Errorf("round trip mismatch %+v %v", out, errs)
+	}
+	e.DurationPrecision = 2
+	kvl, _ = e.StructToEnvVars(Cfg{Long: 1005 * time.Millisecond, Tiny: -1005 * time.Millisecond, Neg: &neg})
+	if kvl[0].Value != "1.01" || kvl[1].Value != "-1.01" || kvl[2].Value != "-1.50" || kvl[3].Value != "0.0" {
+		t.Errorf("unexpected rounding %v", kvl)
+	}
+	type Bad struct {
+		D time.Duration `env:",precision=x"`
+	}
+	if _, errs = StructToEnvVars(Bad{}); len(errs) != 1 {
+		t.Errorf("expected an invalid precision error, got %v", errs)
+	}
+	for _, bad := range []string{"5m", "1.2.3", "+-1", ""} {
+		if _, err := parseDecimalDuration(bad, "s", time.Second); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestReadOnlyWriteOnly(t *testing.T) {
+	type Cfg struct {
+		Name     string
+		Password string `env:",readonly,secret" help:"not re-exported"`
+		Started  string `env:",writeonly"`
+	}
+	cfg := Cfg{Name: "app", Password: "hunter2", Started: "now"}
+	kvl, skipped, errs := StructToEnvVarsReport(cfg)
+	if len(errs) != 0 || len(kvl) != 2 || kvl[0].Key != "NAME" || kvl[1].Key != "STARTED" {
+		t.Errorf("unexpected %v %v", kvl, errs)
+	}
+	if len(skipped) != 1 || skipped[0].Field != "Cfg.Password" || skipped[0].Reason != SkipReadOnly {
+		t.Errorf("unexpected skipped %+v", skipped)
+	}
+	env := map[string]string{"NAME": "other", "PASSWORD": "secret", "STARTED": "ignored"}
+	var out Cfg
+	if errs = SetFrom(MapLookup(env), "", &out); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if out.Name != "other" || out.Password != "secret" || out.Started != "" {
+		t.Errorf("unexpected %+v", out)
+	}
+	desc, _ := DescribeEnvVars("APP_", cfg)
+	if len(desc) != 2 || desc[0].Name != "APP_NAME" || desc[1].Name != "APP_PASSWORD" || !desc[1].Secret ||
+		desc[1].Value != "" || desc[1].Set {
+		t.Errorf("unexpected description %+v", desc)
+	}
+}
+
+func TestFloatFormat(t *testing.T) {
+	type Cfg struct {
+		Small float64
+		Large float64
+		F32   float32
+		Pi    *float64 `env:",precision=2"`
+	}
+	// Go's formatting doesn't depend on the C locale, check it stays that way.
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	t.Setenv("LC_NUMERIC", "fr_FR.UTF-8")
+	pi := 3.14159
+	in := Cfg{Small: 0.000001, Large: 1234567890123456789012.0, F32: 0.1, Pi: &pi}
+	tests := []struct {
+		format   FloatFormat
+		expected string
+	}{
+		{FloatShortest, "SMALL='1e-06' LARGE='1.2345678901234568e+21' F32='0.1' PI='3.14'"},
+		{FloatDecimal, "SMALL='0.000001' LARGE='1234567890123456800000' F32='0.1' PI='3.14'"},
+	}
+	for _, test := range tests {
+		e := NewEncoder()
+		e.FloatFormat = test.format
+		kvl, errors := e.StructToEnvVars(in)
+		if len(errors) != 0 {
+			t.Errorf("unexpected errors %v", errors)
+		}
+		str := ToShellWithOptions(kvl, ShellOptions{SkipExport: true})
+		if str = strings.Join(strings.Fields(str), " "); str != test.expected {
+			t.Errorf("for %v got %q expected %q", test.format, str, test.expected)
+		}
+		out := Cfg{}
+		if errors = SetFrom(MapLookup(ToJSONMap(kvl)), "", &out); len(errors) != 0 || out.Small != in.Small ||
+			out.Large != in.Large || out.F32 != in.F32 || *out.Pi != 3.14 {
+			t.Errorf("round trip mismatch for %v: %+v %v", test.format, out, errors)
+		}
+	}
+	type Bad struct {
+		I int     `env:",precision=2"`
+		F float64 `env:",precision=x"`
+	}
+	if _, errors := StructToEnvVars(Bad{}); len(errors) != 2 {
+		t.Errorf("expected 2 errors, got %v", errors)
+	}
+}
+
+func TestNonFinite(t *testing.T) {
+	type Cfg struct {
+		NaN  float64
+		Inf  *float32
+		Fine float64
+	}
+	inf := float32(math.Inf(-1))
+	in := Cfg{NaN: math.NaN(), Inf: &inf, Fine: 1.5}
+	kvl, errors := StructToEnvVars(in)
+	if len(errors) != 0 || kvl[0].Value != "NaN" || kvl[1].Value != "-Inf" {
+		t.Errorf("unexpected %v %v", kvl, errors)
+	}
+	env := ToJSONMap(kvl)
+	out := Cfg{}
+	errors = SetFrom(MapLookup(env), "", &out)
+	if len(errors) != 0 || !math.IsNaN(out.NaN) || !math.IsInf(float64(*out.Inf), -1) {
+		t.Errorf("round trip mismatch %+v %v", out, errors)
+	}
+	e := NewEncoder()
+	e.NonFinite = NonFiniteError
+	if kvl, errors = e.StructToEnvVars(in); len(errors) != 2 || len(kvl) != 1 {
+		t.Errorf("expected 2 errors, got %v %v", kvl, errors)
+	}
+	e.NonFinite = NonFiniteSkip
+	kvl, skipped, errors := e.StructToEnvVarsReport(in)
+	if len(errors) != 0 || len(kvl) != 1 || len(skipped) != 2 || skipped[1].Reason != SkipNonFinite {
+		t.Errorf("unexpected %v %v %v", kvl, skipped, errors)
+	}
+	d := NewDecoder(MapLookup(env))
+	d.NonFinite = NonFiniteError
+	if errors = d.SetFrom("", &Cfg{}); len(errors) != 2 {
+		t.Errorf("expected 2 errors, got %v", errors)
+	}
+	d.NonFinite = NonFiniteSkip
+	out = Cfg{NaN: 1}
+	if errors = d.SetFrom("", &out); len(errors) != 0 || out.NaN != 1 || out.Fine != 1.5 {
+		t.Errorf("unexpected %+v %v", out, errors)
+	}
+}
+
+func TestIntegerRange(t *testing.T) {
+	type Cfg struct {
+		I8   int8
+		U16  uint16
+		U    uint
+		Size int16 `env:",size"`
+	}
+	env := map[string]string{"I8": "300", "U16": "70000", "U": "-5", "SIZE": "1MiB"}
+	errs := SetFrom(MapLookup(env), "", &Cfg{})
+	expected := []string{
+		`I8="300" is out of range for int8 (-128 to 127): value out of range`,
+		`U16="70000" is out of range for uint16 (0 to 65535): value out of range`,
+		`U="-5" is out of range for uint (0 to 18446744073709551615): value out of range`,
+		`SIZE="1MiB" is out of range for int16 (-32768 to 32767): value out of range`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] || !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("got %q expected %q", err, expected[i])
+		}
+	}
+	env["I8"] = "-300"
+	d := NewDecoder(MapLookup(env))
+	d.ClampIntegers = true
+	cfg := Cfg{}
+	errs = d.SetFrom("", &cfg)
+	if len(errs) != 0 || cfg.I8 != -128 || cfg.U16 != 65535 || cfg.U != 0 || cfg.Size != 32767 {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+	env["I8"] = "abc"
+	if errs = d.SetFrom("", &cfg); len(errs) != 1 || errors.Is(errs[0], strconv.ErrRange) {
+		t.Errorf("expected a syntax error, got %v", errs)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	type Sub struct {
+		A int
+		B int
+	}
+	type Cfg struct {
+		X   int
+		Sub Sub
+		Y   int
+		W   int `env:",percent"`
+		Z   int `env:",percent"`
+	}
+	env := map[string]string{"X": "x", "SUB_A": "a", "SUB_B": "b", "Y": "y"}
+	if errs := SetFrom(MapLookup(env), "", &Cfg{}); len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %v", errs)
+	}
+	d := NewDecoder(MapLookup(env))
+	d.MaxErrors = 1
+	cfg := Cfg{}
+	if errs := d.SetFrom("", &cfg); len(errs) != 1 || !strings.Contains(errs[0].Error(), `"x"`) {
+		t.Errorf("expected only the first error, got %v", errs)
+	}
+	d.MaxErrors = 2
+	if errs := d.SetFromMulti("", &Cfg{}, &Cfg{}); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+	e := NewEncoder()
+	if _, errs := e.StructToEnvVars(Cfg{}); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+	e.MaxErrors = 1
+	kvl, errs := e.StructToEnvVars(Cfg{})
+	if len(errs) != 1 || len(kvl) != 5 {
+		t.Errorf("expected to stop at the first error, got %v %v", kvl, errs)
+	}
+	if _, errs = e.StructsToEnvVars("", Cfg{}, Cfg{}); len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}
+
+type Timeout time.Duration
+
+type Timestamp struct {
+	time.Time
+}
+
+func TestTimeWrappers(t *testing.T) {
+	type Cfg struct {
+		Timeout Timeout `env:",duration"`
+		Count   int64
+		When    Timestamp
+		Since   Timestamp `env:",unix"`
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	in := Cfg{Timeout: Timeout(1500 * time.Millisecond), Count: 3, When: Timestamp{ts}, Since: Timestamp{ts}}
+	kvl, errors := StructToEnvVars(in)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := "TIMEOUT=1.5\nCOUNT='3'\nWHEN='2024-01-02T03:04:05Z'\nSINCE='1704164645'\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	out := Cfg{}
+	errors = SetFrom(MapLookup(ToJSONMap(kvl)), "", &out)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if out.Timeout != in.Timeout || out.Count != 3 || !out.When.Equal(ts) || !out.Since.Equal(ts) {
+		t.Errorf("round trip mismatch %+v", out)
+	}
+	type Bad struct {
+		N int32 `env:",duration"`
+	}
+	if _, errors = StructToEnvVars(Bad{}); len(errors) != 1 {
+		t.Errorf("expected error for duration option on int32, got %v", errors)
+	}
+}
+
+func TestOmitNilPointers(t *testing.T) {
+	type Cfg struct {
+		Unset *string
+		Empty *string
+		Value *string
+		Zero  *int
+	}
+	empty, value, zero := "", "v", 0
+	in := Cfg{Empty: &empty, Value: &value, Zero: &zero}
+	e := NewEncoder()
+	e.OmitNilPointers = true
+	kvl, errors := e.StructToEnvVars(in)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if str := ToShellWithPrefix("", kvl, true); str != "EMPTY=''\nVALUE='v'\nZERO='0'\n" {
+		t.Errorf("unexpected output %q", str)
+	}
+	out := Cfg{}
+	if errors = SetFrom(MapLookup(ToJSONMap(kvl)), "", &out); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if out.Unset != nil || out.Empty == nil || *out.Empty != "" || *out.Value != "v" || *out.Zero != 0 {
+		t.Errorf("tri-state not preserved: %+v", out)
+	}
+	// Default is to emit them.
+	kvl, _ = StructToEnvVars(in)
+	if len(kvl) != 4 {
+		t.Errorf("expected nil pointer entry by default, got %v", kvl)
+	}
+}
+
+func TestTimePointers(t *testing.T) {
+	type Cfg struct {
+		TS     *time.Time
+		Unix   *time.Time `env:",unix"`
+		Stamp  *Timestamp
+		Dur    *time.Duration
+		Unset  *time.Time
+		hidden *time.Time
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dur := 1500 * time.Millisecond
+	in := Cfg{TS: &ts, Unix: &ts, Stamp: &Timestamp{ts}, Dur: &dur, hidden: &ts}
+	kvl, errors := StructToEnvVars(in)
+	if len(errors) != 1 { // can't interface hidden
+		t.Errorf("expected 1 error, got %v", errors)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := "TS='2024-01-02T03:04:05Z'\nUNIX='1704164645'\nSTAMP='2024-01-02T03:04:05Z'\nDUR=1.5\nUNSET=\nHIDDEN=\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	out := Cfg{}
+	errors = SetFrom(MapLookup(ToJSONMap(kvl[:4])), "", &out)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if out.TS == nil || !out.TS.Equal(ts) || !out.Unix.Equal(ts) || !out.Stamp.Equal(ts) || *out.Dur != dur {
+		t.Errorf("round trip mismatch %+v", out)
+	}
+	if out.Unset != nil {
+		t.Errorf("unset pointer should stay nil")
+	}
+}
+
+type Label string
+
+type EmbedsScalars struct {
+	time.Duration
+	Label
+	Timestamp `env:"STARTED"`
+	Name      string
+}
+
+func TestEmbeddedNonStruct(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cfg := EmbedsScalars{Duration: 1500 * time.Millisecond, Label: "blue", Timestamp: Timestamp{ts}, Name: "x"}
+	kvl, errors := StructToEnvVars(&cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("E_", kvl, true)
+	expected := "E_DURATION=1.5\nE_LABEL='blue'\nE_STARTED='2024-01-02T03:04:05Z'\nE_NAME='x'\n"
+	if str != expected {
+		t.Errorf("unexpected %q", str)
+	}
+	env := map[string]string{}
+	for _, kv := range kvl {
+		env[kv.Key] = kv.Value
+	}
+	res := EmbedsScalars{}
+	if errors = SetFrom(MapLookup(env), "", &res); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if res.Duration != cfg.Duration || res.Label != cfg.Label || !res.Time.Equal(ts) || res.Name != "x" {
+		t.Errorf("round trip mismatch %+v vs %+v", res, cfg)
+	}
+}
+
+type embeddedInner struct {
+	A string
+}
+
+type EmbeddedPtr struct {
+	B string
+}
+
+type level int
+
+type EmbedsUnexported struct {
+	embeddedInner
+	*EmbeddedPtr
+	level
+	Name string
+}
+
+func TestEmbedErrors(t *testing.T) {
+	cfg := EmbedsUnexported{embeddedInner: embeddedInner{A: "a"}, Name: "n"}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 2 ||
+		errors[0].Error() != "embedded *struct2env.EmbeddedPtr (field #1 of struct2env.EmbedsUnexported) is nil" ||
+		!strings.HasPrefix(errors[1].Error(), "embedded struct2env.level (field #2 of struct2env.EmbedsUnexported) is of an") {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if str := ToShellWithPrefix("", kvl, true); str != "A='a'\nNAME='n'\n" {
+		t.Errorf("unexpected %q", str)
+	}
+	res := EmbedsUnexported{}
+	env := map[string]string{"A": "x", "B": "y", "LEVEL": "3"}
+	errors = SetFrom(MapLookup(env), "", &res)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 error, got %v", errors)
+	}
+	if res.A != "x" || res.EmbeddedPtr == nil || res.B != "y" {
+		t.Errorf("unexpected %+v", res)
+	}
+	e := NewEncoder()
+	e.OmitNilPointers = true
+	if _, errors = e.StructToEnvVars(struct{ *EmbeddedPtr }{}); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+}
+
+func TestDefaultOption(t *testing.T) {
+	type Cfg struct {
+		Port    int           `env:"PORT,default=8080"`
+		Host    string        `env:",default=localhost"`
+		Timeout time.Duration `env:",default=1.5"`
+		Ratio   *float64      `env:",default=0.5"`
+		Bad     int           `env:",default=x"`
+	}
+	cfg := Cfg{}
+	d := NewDecoder(MapLookup(map[string]string{"HOST": "example.com", "BAD": "3"}))
+	d.Trace = &Trace{}
+	if errors := d.SetFrom("", &cfg); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if cfg.Port != 8080 || cfg.Host != "example.com" || cfg.Timeout != 1500*time.Millisecond || *cfg.Ratio != 0.5 {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if e := d.Trace.Entries[0]; e.Source != "default" || e.Value != "8080" {
+		t.Errorf("unexpected trace entry %+v", e)
+	}
+	if errors := SetFrom(MapLookup(nil), "", &cfg); len(errors) != 1 {
+		t.Errorf("expected 1 error for the invalid default, got %v", errors)
+	}
+}
+
+func TestRequiredOption(t *testing.T) {
+	type DB struct {
+		Password string `env:",required"`
+	}
+	type Cfg struct {
+		APIKey string `env:"API_KEY,required"`
+		Port   *int   `env:",required"`
+		Host   string `env:",required,default=localhost"`
+		DB     DB
+	}
+	cfg := Cfg{}
+	errs := SetFrom(MapLookup(map[string]string{"APP_API_KEY": "", "APP_PORT": "80"}), "APP_", &cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	var missing *MissingError
+	if !errors.As(errs[0], &missing) || missing.EnvName != "APP_API_KEY" || missing.Field != "APIKey" {
+		t.Errorf("unexpected %#v", errs[0])
+	}
+	if errs[1].Error() != "APP_DB_PASSWORD is required (for DB.Password) but not set" {
+		t.Errorf("unexpected %v", errs[1])
+	}
+	if *cfg.Port != 80 || cfg.Host != "localhost" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+}
+
+func TestEmptyAsUnset(t *testing.T) {
+	type Cfg struct {
+		Name  string
+		Port  int `env:",default=8080"`
+		Debug bool
+		Key   string `env:",required"`
+	}
+	env := map[string]string{"NAME": "", "PORT": "", "DEBUG": "", "KEY": ""}
+	cfg := Cfg{Name: "dflt"}
+	d := NewDecoder(MapLookup(env))
+	if errs := d.SetFrom("", &cfg); len(errs) != 3 { // PORT and DEBUG can't be parsed, KEY is required.
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+	cfg = Cfg{Name: "dflt"}
+	d.EmptyAsUnset = true
+	errs := d.SetFrom("", &cfg)
+	if len(errs) != 1 || cfg.Name != "dflt" || cfg.Port != 8080 {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+	d.Sources = []NamedLookup{{"env", MapLookup(env)}, {"file", MapLookup(map[string]string{"NAME": "file", "KEY": "k"})}}
+	if errs := d.SetFrom("", &cfg); len(errs) != 0 || cfg.Name != "file" || cfg.Key != "k" {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+}
+
+func TestPrefixFallback(t *testing.T) {
+	type Service struct {
+		Timeout int
+		Name    string
+		Region  string `env:"REGION,noprefix"`
+	}
+	type Cfg struct {
+		Service Service
+		Debug   bool
+	}
+	env := map[string]string{
+		"APP_SERVICE_NAME": "app", "SERVICE_NAME": "shared", "SERVICE_TIMEOUT": "10", "DEBUG": "true", "APP_REGION": "x",
+	}
+	cfg := Cfg{}
+	d := NewDecoder(MapLookup(env))
+	d.PrefixFallback = true
+	d.Trace = &Trace{}
+	if errs := d.SetFrom("APP_", &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Service.Timeout != 10 || cfg.Service.Name != "app" || !cfg.Debug || cfg.Service.Region != "" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if names := d.Trace.Entries[0].Names; len(names) != 3 || names[2] != "TIMEOUT" {
+		t.Errorf("unexpected names %v", names)
+	}
+	d.PrefixFallback = false
+	cfg = Cfg{}
+	if errs := d.SetFrom("APP_", &cfg); len(errs) != 0 || cfg.Service.Timeout != 0 || cfg.Debug {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+}
+
+// newWideConfig returns a pointer to a struct with n fields cycling through the common config
+// field types, all set, to benchmark typical 10-50 field structs.
+func newWideConfig(n int) interface{} {
+	types := []reflect.Type{
+		reflect.TypeOf(""), reflect.TypeOf(0), reflect.TypeOf(false), reflect.TypeOf(time.Duration(0)), reflect.TypeOf(0.0),
+	}
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		fields[i] = reflect.StructField{Name: fmt.Sprintf("ConfigField%d", i), Type: types[i%len(types)]}
+	}
+	v := reflect.New(reflect.StructOf(fields))
+	for i := 0; i < n; i++ {
+		f := v.Elem().Field(i)
+		switch f.Kind() { //nolint: exhaustive // only the types above
+		case reflect.String:
+			f.SetString(fmt.Sprintf("value %d", i))
+		case reflect.Int, reflect.Int64:
+			f.SetInt(int64(i * 1000))
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Float64:
+			f.SetFloat(float64(i) / 3)
+		}
+	}
+	return v.Interface()
+}
+
+func benchmarkWide(b *testing.B, n int) {
+	cfg := newWideConfig(n)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		kvl, errs := StructToEnvVars(cfg)
+		if len(errs) != 0 || len(kvl) != n {
+			b.Fatalf("unexpected %v %v", kvl, errs)
+		}
+	}
+}
+
+func BenchmarkStructToEnvVars10(b *testing.B) { benchmarkWide(b, 10) }
+func BenchmarkStructToEnvVars50(b *testing.B) { benchmarkWide(b, 50) }
+
+func BenchmarkToShell(b *testing.B) {
+	kvl, _ := StructToEnvVars(newWideConfig(50))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ToShell(kvl)
+	}
+}
+
+func BenchmarkKeyValueString(b *testing.B) {
+	kvl, _ := StructToEnvVars(newBenchConfig())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, kv := range kvl {
+			_ = kv.String()
+		}
+	}
+}
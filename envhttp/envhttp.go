@@ -0,0 +1,113 @@
+// Package envhttp provides an http.Handler serving the environment variables contract of a service
+// (names, descriptions, secret flags) along with the current values, secrets masked, as JSON or
+// as an HTML table; to mount under an admin mux so fleets can introspect configuration uniformly.
+package envhttp
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"fortio.org/struct2env"
+)
+
+// Handler serves the description of the variables of the configuration returned by Config,
+// see struct2env.DescribeEnvVars. JSON by default, HTML when the format=html query parameter
+// is set or the request Accept header prefers text/html (e.g. from a browser).
+type Handler struct {
+	// Prefix is prepended to the variable names.
+	Prefix string
+	// Config returns the current configuration (struct or pointer to struct), called for each
+	// request so reloads are reflected. It must be safe to call concurrently.
+	Config func() interface{}
+	// Encoder is used to serialize the configuration, for its Options. Defaults to struct2env.NewEncoder().
+	Encoder *struct2env.Encoder
+}
+
+// NewHandler returns a Handler for the configuration returned by cfg.
+func NewHandler(prefix string, cfg func() interface{}) *Handler {
+	return &Handler{Prefix: prefix, Config: cfg}
+}
+
+// Variable is the JSON representation of a variable.
+type Variable struct {
+	Name    string  `json:"name"`
+	Value   *string `json:"value"` // null for nil pointers, struct2env.SecretMask for non empty secrets.
+	Secret  bool    `json:"secret,omitempty"`
+	Help    string  `json:"help,omitempty"`
+	Section string  `json:"section,omitempty"`
+}
+
+// Response is the JSON document served.
+type Response struct {
+	Variables []Variable `json:"variables"`
+	Errors    []string   `json:"errors,omitempty"`
+}
+
+// Describe returns the current Response.
+func (h *Handler) Describe() Response {
+	e := h.Encoder
+	if e == nil {
+		e = struct2env.NewEncoder()
+	}
+	vars, errs := e.DescribeEnvVars(h.Prefix, h.Config())
+	res := Response{Variables: make([]Variable, 0, len(vars))}
+	for _, v := range vars {
+		jv := Variable{Name: v.Name, Secret: v.Secret, Help: v.Help, Section: v.Section}
+		if v.Set {
+			value := v.Value
+			jv.Value = &value
+		}
+		res.Variables = append(res.Variables, jv)
+	}
+	for _, err := range errs {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	return res
+}
+
+var page = template.Must(template.New("envhttp").Parse(`<!DOCTYPE html>
+<html><head><title>Configuration</title></head><body>
+<table border="1">
+<tr><th>Variable</th><th>Value</th><th>Description</th></tr>
+{{range .Variables}}<tr><td><code>{{.Name}}</code></td>
+<td>{{if .Value}}<code>{{.Value}}</code>{{end}}</td><td>{{.Help}}</td></tr>
+{{end}}</table>
+{{if .Errors}}<h2>Errors</h2><ul>{{range .Errors}}<li>{{.}}</li>{{end}}</ul>{{end}}
+</body></html>
+`))
+
+// wantsHTML returns whether the request asks for HTML.
+func wantsHTML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "html"
+	}
+	accept := r.Header.Get("Accept")
+	html := strings.Index(accept, "text/html")
+	if html < 0 {
+		return false
+	}
+	js := strings.Index(accept, "application/json")
+	return js < 0 || html < js
+}
+
+// ServeHTTP serves the description, see Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	res := h.Describe()
+	w.Header().Set("Cache-Control", "no-store")
+	if wantsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = page.Execute(w, res)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(res)
+}
@@ -0,0 +1,82 @@
+package envhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Config struct {
+	Name     string `help:"Service <name>"`
+	Password string `env:",secret"`
+	Port     *int
+}
+
+func TestHandler(t *testing.T) {
+	cfg := &Config{Name: "app", Password: "hunter2"}
+	h := NewHandler("APP_", func() interface{} { return cfg })
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected %d %v", rec.Code, rec.Header())
+	}
+	var res Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(res.Variables) != 3 || *res.Variables[1].Value != "********" || !res.Variables[1].Secret ||
+		res.Variables[2].Value != nil || res.Variables[0].Help != "Service <name>" {
+		t.Errorf("unexpected %+v", res)
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("secret leaked: %s", rec.Body.String())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(body, "<code>APP_NAME</code>") || !strings.Contains(body, "Service &lt;name&gt;") {
+		t.Errorf("unexpected html %s", body)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?format=json", nil))
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected %v", rec.Header())
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/config", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected %d", rec.Code)
+	}
+}
+
+type ReadOnlyConfig struct {
+	Name  string
+	Token string `env:",readonly"`
+	DB    struct {
+		URL string
+	} `env:",readonly"`
+}
+
+func TestHandlerReadOnly(t *testing.T) {
+	cfg := &ReadOnlyConfig{Name: "app", Token: "tok123"}
+	cfg.DB.URL = "postgres://user:pw@db"
+	h := NewHandler("", func() interface{} { return cfg })
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	var res Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(res.Variables) != 3 || res.Variables[1].Name != "TOKEN" || res.Variables[1].Value != nil ||
+		res.Variables[2].Name != "DB_URL" || res.Variables[2].Value != nil {
+		t.Errorf("unexpected %+v", res)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "tok123") || strings.Contains(body, "pw@db") {
+		t.Errorf("readonly value leaked: %s", body)
+	}
+}
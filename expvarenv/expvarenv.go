@@ -0,0 +1,60 @@
+// Package expvarenv publishes the effective configuration, as environment variables with the secrets
+// masked, through expvar: it then shows up in the /debug/vars JSON of the service for operators to inspect.
+// It's a separate package because importing expvar registers the /debug/vars handler.
+package expvarenv
+
+import (
+	"expvar"
+	"sync"
+
+	"fortio.org/struct2env"
+)
+
+// Var is a published configuration, see Publish.
+type Var struct {
+	enc    *struct2env.Encoder
+	prefix string
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// Publish serializes cfg (struct or pointer to struct) like struct2env.DescribeEnvVars does, so with the
+// `secret` fields masked, and publishes the variables (with prefix prepended) as an expvar named name. The
+// published values are a snapshot: call Update when the configuration changes, e.g. from the reload or
+// watch callback. Like expvar.Publish it panics if name is already in use.
+func Publish(name, prefix string, cfg interface{}) (*Var, []error) {
+	return PublishWith(struct2env.NewEncoder(), name, prefix, cfg)
+}
+
+// PublishWith is Publish using the Encoder e, for its Options.
+func PublishWith(e *struct2env.Encoder, name, prefix string, cfg interface{}) (*Var, []error) {
+	v := &Var{enc: e, prefix: prefix}
+	errs := v.Update(cfg)
+	expvar.Publish(name, expvar.Func(func() interface{} { return v.Value() }))
+	return v, errs
+}
+
+// Update refreshes the published values from cfg. Safe to call concurrently with the expvar being read.
+func (v *Var) Update(cfg interface{}) []error {
+	vars, errs := v.enc.DescribeEnvVars(v.prefix, cfg)
+	values := make(map[string]interface{}, len(vars))
+	for _, ev := range vars {
+		if ev.Set {
+			values[ev.Name] = ev.Value
+		} else {
+			values[ev.Name] = nil
+		}
+	}
+	v.mu.Lock()
+	v.values = values
+	v.mu.Unlock()
+	return errs
+}
+
+// Value returns the currently published values: the variables names to their (masked for secrets)
+// values, nil for nil pointers.
+func (v *Var) Value() map[string]interface{} {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.values
+}
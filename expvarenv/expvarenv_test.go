@@ -0,0 +1,38 @@
+package expvarenv
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+type Config struct {
+	Name     string
+	Password string `env:",secret"`
+	Port     *int
+}
+
+func TestPublish(t *testing.T) {
+	cfg := Config{Name: "app", Password: "hunter2"}
+	v, errs := Publish("test-config", "APP_", &cfg)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(expvar.Get("test-config").String()), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got["APP_NAME"] != "app" || got["APP_PASSWORD"] != "********" || got["APP_PORT"] != nil || len(got) != 3 {
+		t.Errorf("unexpected %v", got)
+	}
+	port := 8080
+	cfg.Port = &port
+	cfg.Password = ""
+	v.Update(cfg)
+	if err := json.Unmarshal([]byte(expvar.Get("test-config").String()), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got["APP_PORT"] != "8080" || got["APP_PASSWORD"] != "" {
+		t.Errorf("unexpected %v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package struct2env
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// The file based loaders below read from an fs.FS, so they work as well on embedded (go:embed) and in
+// memory (testing/fstest) filesystems as on disk. A nil fsys is the OS filesystem, like os.DirFS but also
+// accepting absolute and relative (../) paths.
+
+// osFS is the fs.FS of the OS filesystem, used for nil ones.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func orOS(fsys fs.FS) fs.FS {
+	if fsys == nil {
+		return osFS{}
+	}
+	return fsys
+}
+
+// ReadDotEnvFile parses the .env file name of fsys (see ParseDotEnv), errors include the file name.
+func ReadDotEnvFile(fsys fs.FS, name string) (map[string]string, error) {
+	f, err := orOS(fsys).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ParseDotEnv(f)
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.File = name
+	} else if err != nil {
+		err = fmt.Errorf("%s: %w", name, err)
+	}
+	return m, err
+}
+
+// SetFromDotEnvFile is SetFromDotEnvReader for the file name of fsys (nil for the OS filesystem).
+func SetFromDotEnvFile(fsys fs.FS, name, prefix string, s interface{}) []error {
+	m, err := ReadDotEnvFile(fsys, name)
+	if err != nil {
+		return []error{err}
+	}
+	return setFromMap(m, prefix, s)
+}
+
+// ReadEnvDir reads the variables of the directory dir of fsys holding one file per variable, named after
+// it, with the value as content: daemontools envdir, kubernetes ConfigMap and Secret volumes or docker
+// secrets. One trailing newline is removed from the values. Sub directories and hidden files (e.g. the
+// ..data links of kubernetes volumes) are ignored.
+func ReadEnvDir(fsys fs.FS, dir string) (map[string]string, error) {
+	fsys = orOS(fsys)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || entry.IsDir() {
+			continue
+		}
+		file := path.Join(dir, name)
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			if info, statErr := fs.Stat(fsys, file); statErr == nil && info.IsDir() {
+				continue // Symbolic link to a directory.
+			}
+			return nil, err
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		res[name] = strings.TrimSuffix(value, "\r")
+	}
+	return res, nil
+}
+
+// SetFromEnvDir sets the fields of s (pointer to a struct) from the variables of the directory dir of fsys
+// (nil for the OS filesystem), see ReadEnvDir, with the same conversions as SetFromEnv.
+func SetFromEnvDir(fsys fs.FS, dir, prefix string, s interface{}) []error {
+	m, err := ReadEnvDir(fsys, dir)
+	if err != nil {
+		return []error{err}
+	}
+	return setFromMap(m, prefix, s)
+}
+
+// setFromMap is SetFrom with the variables of m (map fields included).
+func setFromMap(m map[string]string, prefix string, s interface{}) []error {
+	d := NewDecoder(MapLookup(m))
+	d.Keys = MapKeys(m)
+	return d.SetFrom(prefix, s)
+}
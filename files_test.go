@@ -0,0 +1,75 @@
+package struct2env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+type filesConfig struct {
+	Name   string
+	Port   int
+	Labels map[string]string
+}
+
+func TestDotEnvFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/app.env": {Data: []byte("APP_NAME='my app'\nexport APP_PORT=80\nAPP_LABELS_TEAM=infra\n")},
+		"bad.env":      {Data: []byte("APP_NAME='oops\n")},
+	}
+	cfg := filesConfig{}
+	if errs := SetFromDotEnvFile(fsys, "conf/app.env", "APP_", &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Name != "my app" || cfg.Port != 80 || cfg.Labels["team"] != "infra" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	_, err := ReadDotEnvFile(fsys, "bad.env")
+	if err == nil || err.Error() != `bad.env:1:10: unterminated quoted value for APP_NAME (in "APP_NAME='oops")` {
+		t.Errorf("expected error with the file name, got %v", err)
+	}
+	if errs := SetFromDotEnvFile(fsys, "missing.env", "APP_", &cfg); len(errs) != 1 {
+		t.Errorf("expected error for missing file, got %v", errs)
+	}
+	// nil is the OS filesystem, absolute paths included.
+	name := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(name, []byte("APP_PORT=8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if errs := SetFromDotEnvFile(nil, name, "APP_", &cfg); len(errs) != 0 || cfg.Port != 8080 {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+}
+
+func TestEnvDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"secrets/APP_NAME":        {Data: []byte("from dir\n")},
+		"secrets/APP_PORT":        {Data: []byte("443")},
+		"secrets/APP_LABELS_ENV":  {Data: []byte("prod\r\n")},
+		"secrets/..data/APP_NAME": {Data: []byte("ignored")},
+		"secrets/.hidden":         {Data: []byte("ignored")},
+		"secrets/sub/APP_PORT":    {Data: []byte("ignored")},
+	}
+	m, err := ReadEnvDir(fsys, "secrets")
+	if err != nil || len(m) != 3 {
+		t.Errorf("unexpected %v %v", m, err)
+	}
+	cfg := filesConfig{}
+	if errs := SetFromEnvDir(fsys, "secrets", "APP_", &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Name != "from dir" || cfg.Port != 443 || cfg.Labels["env"] != "prod" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if errs := SetFromEnvDir(fsys, "nope", "APP_", &cfg); len(errs) != 1 {
+		t.Errorf("expected error for missing dir, got %v", errs)
+	}
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "APP_PORT"), []byte("9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if errs := SetFromEnvDir(nil, dir, "APP_", &cfg); len(errs) != 0 || cfg.Port != 9000 {
+		t.Errorf("unexpected %+v %v", cfg, errs)
+	}
+}
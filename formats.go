@@ -0,0 +1,595 @@
+package struct2env
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShellMode selects how ToShellWithOptions declares each variable.
+type ShellMode int
+
+const (
+	// ShellAssign emits bourne shell `KEY='value'` lines (followed by the export line).
+	ShellAssign ShellMode = iota
+	// ShellDeclare emits bash `declare -x KEY='value'` lines, exporting each variable atomically
+	// (so there is no export line).
+	ShellDeclare
+	// ShellReadonly emits `readonly KEY='value'` lines protecting the variables from later modification
+	// (hardening init scripts), followed by the export line unless SkipExport is set.
+	ShellReadonly
+)
+
+// keyword returns what goes before the KEY='value' assignments.
+func (m ShellMode) keyword() string {
+	switch m {
+	case ShellDeclare:
+		return "declare -x "
+	case ShellReadonly:
+		return "readonly "
+	default:
+		return ""
+	}
+}
+
+// ShellOptions controls the output of ToShellWithOptions so generated scripts can fit existing conventions.
+type ShellOptions struct {
+	Prefix     string    // Applied to the keys (except the NoPrefix ones).
+	CRLF       bool      // Use \r\n line endings instead of \n.
+	Mode       ShellMode // How the variables are declared.
+	Shebang    bool      // Start with a `#!/bin/sh` line (`#!/bin/bash` for ShellDeclare).
+	Header     bool      // Add a `# Generated by struct2env from <Source> on <Time>` comment.
+	Source     string    // Name of the source struct for the header, e.g. fmt.Sprintf("%T", cfg). Omitted when empty.
+	Time       time.Time // Timestamp for the header, time.Now() when zero.
+	SkipExport bool      // Omit the trailing `export VAR1 VAR2...` line.
+	Sections   bool      // Separate the nested structs' variables, see KeyValue.Section, with `# --- Section ---` lines.
+}
+
+// ToShellWithOptions converts the key value pairs to bourne shell syntax (`KEY='value'` lines
+// followed by an `export KEY1 KEY2...` line by default) according to the options.
+func ToShellWithOptions(kvl []KeyValue, opts ShellOptions) string {
+	eol := "\n"
+	if opts.CRLF {
+		eol = "\r\n"
+	}
+	var sb strings.Builder
+	if opts.Shebang {
+		if opts.Mode == ShellDeclare {
+			sb.WriteString("#!/bin/bash")
+		} else {
+			sb.WriteString("#!/bin/sh")
+		}
+		sb.WriteString(eol)
+	}
+	if opts.Header {
+		sb.WriteString("# Generated by struct2env")
+		if opts.Source != "" {
+			sb.WriteString(" from ")
+			sb.WriteString(opts.Source)
+		}
+		ts := opts.Time
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		sb.WriteString(" on ")
+		sb.WriteString(ts.Format(time.RFC3339))
+		sb.WriteString(eol)
+	}
+	size := 0
+	for _, kv := range kvl {
+		size += 2*(len(opts.Prefix)+len(kv.Key)) + len(kv.ShellQuotedVal) + len(opts.Mode.keyword()) + 4
+	}
+	sb.Grow(size + 16)
+	section := ""
+	for _, kv := range kvl {
+		if opts.Sections {
+			writeSection(&sb, &section, kv.Section, eol)
+		}
+		sb.WriteString(opts.Mode.keyword())
+		writePrefixedKey(&sb, opts.Prefix, kv)
+		sb.WriteRune('=')
+		sb.WriteString(kv.ShellQuotedVal)
+		sb.WriteString(eol)
+	}
+	if !opts.SkipExport && opts.Mode != ShellDeclare {
+		if section != "" {
+			sb.WriteString(eol)
+		}
+		sb.WriteString("export ")
+		for i, kv := range kvl {
+			if i > 0 {
+				sb.WriteRune(' ')
+			}
+			writePrefixedKey(&sb, opts.Prefix, kv)
+		}
+		sb.WriteString(eol)
+	}
+	return sb.String()
+}
+
+// PowerShellQuote returns input as a PowerShell single quoted (verbatim) string.
+// Inside such strings only quotes need escaping, by doubling them; note that PowerShell
+// also treats the typographic single quotes (‘ ’ ‚ ‛) as quotes so those get doubled too.
+func PowerShellQuote(input string) string {
+	var sb strings.Builder
+	sb.Grow(len(input) + 2)
+	sb.WriteRune('\'')
+	for _, r := range input {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			sb.WriteRune(r)
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteRune('\'')
+	return sb.String()
+}
+
+// ToPowerShellWithPrefix emits one `[Environment]::SetEnvironmentVariable('KEY','value','Scope')`
+// line per entry, which persists the variables across sessions on Windows (unlike `$env:KEY=`).
+// The scope is one of "User", "Machine" (requires elevation) or "Process"; it defaults to "User" when empty.
+// Nil pointers are emitted as $null, which removes the variable.
+func ToPowerShellWithPrefix(prefix string, kvl []KeyValue, scope string) string {
+	if scope == "" {
+		scope = "User"
+	}
+	quotedScope := PowerShellQuote(scope)
+	var sb strings.Builder
+	for _, kv := range kvl {
+		sb.WriteString("[Environment]::SetEnvironmentVariable(")
+		sb.WriteString(PowerShellQuote(kv.prefixedKey(prefix)))
+		sb.WriteString(", ")
+		if kv.YamlQuotedVal == "null" {
+			sb.WriteString("$null")
+		} else {
+			sb.WriteString(PowerShellQuote(kv.Value))
+		}
+		sb.WriteString(", ")
+		sb.WriteString(quotedScope)
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
+
+// ToDotEnvWithPrefix emits `KEY=value` lines as read by docker compose `env_file`/`--env-file`, systemd
+// EnvironmentFile and the dotenv libraries. Values are single quoted (literal, no interpolation) unless they
+// contain single quotes or newlines in which case they are double quoted with \n, \r, \" and \\ escapes
+// and $ doubled (so docker compose doesn't interpolate it).
+// Nil pointers give empty values.
+func ToDotEnvWithPrefix(prefix string, kvl []KeyValue) string {
+	return toDotEnv(prefix, kvl, false)
+}
+
+// toDotEnv is ToDotEnvWithPrefix optionally with section comments (see writeSection).
+func toDotEnv(prefix string, kvl []KeyValue, sections bool) string {
+	var sb strings.Builder
+	section := ""
+	for _, kv := range kvl {
+		if sections {
+			writeSection(&sb, &section, kv.Section, "\n")
+		}
+		sb.WriteString(kv.prefixedKey(prefix))
+		sb.WriteRune('=')
+		if kv.YamlQuotedVal != "null" {
+			sb.WriteString(DotEnvQuote(kv.Value))
+		}
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+// writeSection starts a new section when section differs from the *last one: with a blank line (unless
+// at the very beginning) and, unless going back to the top level, a `# --- section ---` comment line.
+func writeSection(sb *strings.Builder, last *string, section, eol string) {
+	if section == *last {
+		return
+	}
+	*last = section
+	if sb.Len() > 0 {
+		sb.WriteString(eol)
+	}
+	if section != "" {
+		sb.WriteString("# --- ")
+		sb.WriteString(section)
+		sb.WriteString(" ---")
+		sb.WriteString(eol)
+	}
+}
+
+// ToGitLabCIWithPrefix emits the `variables:` block of a .gitlab-ci.yml file with one `KEY: "value"` entry
+// per variable. All values are strings (as GitLab requires, so numbers and booleans are quoted too) with `$`
+// doubled to avoid GitLab's variable expansion. Nil pointers give empty values.
+func ToGitLabCIWithPrefix(prefix string, kvl []KeyValue) string {
+	var sb strings.Builder
+	sb.WriteString("variables:\n")
+	for _, kv := range kvl {
+		sb.WriteString("  ")
+		sb.WriteString(kv.prefixedKey(prefix))
+		sb.WriteString(": ")
+		sb.WriteString(GitLabQuote(kv.Value))
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+// GitLabQuote returns the value as a YAML double quoted string with `$` escaped as `$$`, see ToGitLabCIWithPrefix.
+func GitLabQuote(input string) string {
+	return YamlQuote(strings.ReplaceAll(input, "$", "$$"))
+}
+
+// ToGitHubEnvWithPrefix emits the variables in the format of the GitHub Actions GITHUB_ENV (and GITHUB_OUTPUT)
+// files: `KEY=value` lines, values taken literally, and for multi-line values the `KEY<<EOF` form with the value
+// on the following lines up to the delimiter (EOF, or EOF_2, EOF_3... when the value has such a line). Nil
+// pointers are left out.
+func ToGitHubEnvWithPrefix(prefix string, kvl []KeyValue) string {
+	var sb strings.Builder
+	for _, kv := range kvl {
+		if kv.YamlQuotedVal == "null" {
+			continue
+		}
+		writePrefixedKey(&sb, prefix, kv)
+		if strings.ContainsAny(kv.Value, "\r\n") {
+			delim := heredocDelimiter(kv.Value)
+			sb.WriteString("<<")
+			sb.WriteString(delim)
+			sb.WriteRune('\n')
+			sb.WriteString(kv.Value)
+			sb.WriteRune('\n')
+			sb.WriteString(delim)
+		} else {
+			sb.WriteRune('=')
+			sb.WriteString(kv.Value)
+		}
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+// heredocDelimiter returns EOF, or the first of EOF_2, EOF_3... which isn't a line of value.
+func heredocDelimiter(value string) string {
+	lines := strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == '\r' })
+	delim := "EOF"
+	for n := 2; ; n++ {
+		found := false
+		for _, line := range lines {
+			if line == delim {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return delim
+		}
+		delim = "EOF_" + strconv.Itoa(n)
+	}
+}
+
+// ToAnsibleVarsWithPrefix emits an Ansible vars YAML file (e.g. for group_vars/ or vars_files) with one
+// `key: value` line per variable. The keys are lower_snake_case (see AnsibleVarName) and the values keep
+// their YAML typing: booleans and durations are native, other values (numbers included) are quoted strings
+// so Ansible passes them through unchanged. Nil pointers are null.
+func ToAnsibleVarsWithPrefix(prefix string, kvl []KeyValue) string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	for _, kv := range kvl {
+		sb.WriteString(AnsibleVarName(kv.prefixedKey(prefix)))
+		sb.WriteString(": ")
+		sb.WriteString(kv.YamlQuotedVal)
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+// AnsibleVarName returns the key lowercased with the characters not allowed in Ansible variable
+// names replaced by _ (APP_HTTP_PORT and app.http.port both give app_http_port).
+func AnsibleVarName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// ToStarlarkDictWithPrefix emits a Starlark (Bazel) dict literal `{"KEY": "value", ...}`, e.g. for the
+// `env` attribute of test rules, formatted like buildifier does. Nil pointers give empty values.
+func ToStarlarkDictWithPrefix(prefix string, kvl []KeyValue) string {
+	if len(kvl) == 0 {
+		return "{}\n"
+	}
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, kv := range kvl {
+		sb.WriteString("    ")
+		sb.WriteString(StarlarkQuote(kv.prefixedKey(prefix)))
+		sb.WriteString(": ")
+		sb.WriteString(StarlarkQuote(kv.Value))
+		sb.WriteString(",\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// StarlarkQuote returns input as a Starlark double quoted string literal.
+func StarlarkQuote(input string) string {
+	return backslashQuote(input, false)
+}
+
+// ToRubyHashWithPrefix emits a Ruby hash literal assignment `NAME = { "KEY" => "value", ... }`, e.g. for
+// Vagrantfiles or Capistrano configuration. name defaults to ENV_VARS when empty. Nil pointers give empty values.
+func ToRubyHashWithPrefix(prefix string, kvl []KeyValue, name string) string {
+	if name == "" {
+		name = "ENV_VARS"
+	}
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(kvl) == 0 {
+		sb.WriteString(" = {}\n")
+		return sb.String()
+	}
+	sb.WriteString(" = {\n")
+	for _, kv := range kvl {
+		sb.WriteString("  ")
+		sb.WriteString(RubyQuote(kv.prefixedKey(prefix)))
+		sb.WriteString(" => ")
+		sb.WriteString(RubyQuote(kv.Value))
+		sb.WriteString(",\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// RubyQuote returns input as a Ruby double quoted string literal, with # escaped to prevent interpolation.
+func RubyQuote(input string) string {
+	return backslashQuote(input, true)
+}
+
+// ToPythonWithPrefix emits a Python dict literal `{"KEY": "value", ...}` or, when environUpdate is true, an
+// `os.environ.update({...})` statement (needing `import os`). Nil pointers give empty values.
+func ToPythonWithPrefix(prefix string, kvl []KeyValue, environUpdate bool) string {
+	var sb strings.Builder
+	if environUpdate {
+		sb.WriteString("os.environ.update(")
+	}
+	if len(kvl) == 0 {
+		sb.WriteString("{}")
+	} else {
+		sb.WriteString("{\n")
+		for _, kv := range kvl {
+			sb.WriteString("    ")
+			sb.WriteString(PythonQuote(kv.prefixedKey(prefix)))
+			sb.WriteString(": ")
+			sb.WriteString(PythonQuote(kv.Value))
+			sb.WriteString(",\n")
+		}
+		sb.WriteString("}")
+	}
+	if environUpdate {
+		sb.WriteString(")")
+	}
+	sb.WriteRune('\n')
+	return sb.String()
+}
+
+// PythonQuote returns input as a Python double quoted string literal.
+func PythonQuote(input string) string {
+	return backslashQuote(input, false)
+}
+
+// backslashQuote returns input double quoted with the backslash escapes common to Starlark, Python
+// and Ruby: \\ \" \n \r \t and \xHH for the other ASCII control characters. escapeHash also escapes #
+// (as \#) which starts interpolations in Ruby.
+func backslashQuote(input string, escapeHash bool) string {
+	var sb strings.Builder
+	sb.Grow(len(input) + 2)
+	sb.WriteRune('"')
+	for _, r := range input {
+		switch {
+		case r == '\\' || r == '"' || (r == '#' && escapeHash):
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString("\\n")
+		case r == '\r':
+			sb.WriteString("\\r")
+		case r == '\t':
+			sb.WriteString("\\t")
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&sb, "\\x%02x", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune('"')
+	return sb.String()
+}
+
+// DotEnvQuote returns the value quoted for a dotenv file, see ToDotEnvWithPrefix.
+func DotEnvQuote(input string) string {
+	if !strings.ContainsAny(input, "'\n\r") {
+		return "'" + input + "'"
+	}
+	var sb strings.Builder
+	sb.Grow(len(input) + 2)
+	sb.WriteRune('"')
+	for _, r := range input {
+		switch r {
+		case '\n':
+			sb.WriteString("\\n")
+		case '\r':
+			sb.WriteString("\\r")
+		case '"', '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case '$':
+			sb.WriteString("$$")
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune('"')
+	return sb.String()
+}
+
+// ToJSON returns the variables as an indented JSON object `{"KEY": "value"}` (in the order of kvl) or, when
+// array is true, as an array of the `{"name":"KEY","value":"value"}` objects KeyValue's MarshalJSON produces.
+// Values are the raw (unquoted) strings, nil pointers give empty ones (as with ToJSONMap).
+func ToJSON(kvl []KeyValue, array bool) string {
+	return ToJSONWithPrefix("", kvl, array)
+}
+
+// ToJSONWithPrefix is ToJSON with the prefix applied to the keys (except the NoPrefix ones).
+func ToJSONWithPrefix(prefix string, kvl []KeyValue, array bool) string {
+	open, end := "{", "}"
+	if array {
+		open, end = "[", "]"
+	}
+	if len(kvl) == 0 {
+		return open + end + "\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(open)
+	for i, kv := range kvl {
+		if i > 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteString("\n  ")
+		if array {
+			sb.WriteString(`{"name":`)
+			writeJSONString(&sb, kv.prefixedKey(prefix))
+			sb.WriteString(`,"value":`)
+			writeJSONString(&sb, kv.Value)
+			sb.WriteRune('}')
+		} else {
+			writeJSONString(&sb, kv.prefixedKey(prefix))
+			sb.WriteString(": ")
+			writeJSONString(&sb, kv.Value)
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString(end)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// writeJSONString writes the JSON string for s, without the HTML escaping of json.Marshal.
+func writeJSONString(sb *strings.Builder, s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // can't fail for a string (invalid UTF-8 is replaced).
+	sb.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// ToJSONMap returns the raw (unquoted) values keyed by their keys, ready to be
+// serialized as a JSON object or as the `data:` of a kubernetes ConfigMap
+// using any JSON/YAML library. Nil pointers map to empty strings.
+func ToJSONMap(kvl []KeyValue) map[string]string {
+	res := make(map[string]string, len(kvl))
+	for _, kv := range kvl {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
+
+// FromJSONMap is the inverse of ToJSONMap: it returns the KeyValue list, sorted by key,
+// with the values quoted as strings. Values that can't be represented (containing NUL) are
+// reported as errors and are present with empty quoted values, like StructToEnvVars does.
+func FromJSONMap(m map[string]string) ([]KeyValue, []error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var allErrors []error
+	kvl := make([]KeyValue, 0, len(keys))
+	for _, k := range keys {
+		kv := KeyValue{Key: k}
+		if err := setString(&kv, m[k]); err != nil {
+			allErrors = append(allErrors, err)
+		}
+		kvl = append(kvl, kv)
+	}
+	return kvl, allErrors
+}
+
+// ToDockerArgs returns the `-e KEY=value` arguments setting the variables in a container, to append
+// to a docker (or podman) run command's arguments for exec.Command: they are not quoted, see
+// ToDockerCommandLine for printing. Nil pointers are left out (as `-e KEY` would copy the host's value).
+func ToDockerArgs(kvl []KeyValue) []string {
+	return ToDockerArgsWithPrefix("", kvl)
+}
+
+// ToDockerArgsWithPrefix is ToDockerArgs with the prefix applied to the keys (except the NoPrefix ones).
+func ToDockerArgsWithPrefix(prefix string, kvl []KeyValue) []string {
+	res := make([]string, 0, 2*len(kvl))
+	for _, kv := range kvl {
+		if kv.YamlQuotedVal == "null" {
+			continue
+		}
+		res = append(res, "-e", kv.prefixedKey(prefix)+"="+kv.Value)
+	}
+	return res
+}
+
+// ToDockerCommandLine returns the same arguments as ToDockerArgsWithPrefix as a single line of shell quoted
+// `-e KEY='value'` arguments, to print as part of a docker run command line.
+func ToDockerCommandLine(prefix string, kvl []KeyValue) string {
+	var sb strings.Builder
+	for _, kv := range kvl {
+		if kv.YamlQuotedVal == "null" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteRune(' ')
+		}
+		sb.WriteString("-e ")
+		writePrefixedKey(&sb, prefix, kv)
+		sb.WriteRune('=')
+		sb.WriteString(kv.ShellQuotedVal)
+	}
+	return sb.String()
+}
+
+// ToComposeEnvironmentWithPrefix emits the `environment:` block of a docker compose service, indented by
+// indent spaces, in the map form (`KEY: "value"`) or, when list is true, the list form (`- "KEY=value"`).
+// Values are always quoted so compose doesn't turn them into booleans or numbers, with `$` doubled to avoid its
+// variable interpolation (see GitLabQuote). Nil pointers are left out (a key without value would pass the host's
+// value through).
+func ToComposeEnvironmentWithPrefix(indent int, prefix string, kvl []KeyValue, list bool) string {
+	kvl = manifestEntries(kvl)
+	var sb strings.Builder
+	pad := strings.Repeat(" ", indent)
+	sb.WriteString(pad)
+	if len(kvl) == 0 {
+		if list {
+			sb.WriteString("environment: []\n")
+		} else {
+			sb.WriteString("environment: {}\n")
+		}
+		return sb.String()
+	}
+	sb.WriteString("environment:\n")
+	for _, kv := range kvl {
+		sb.WriteString(pad)
+		if list {
+			sb.WriteString("  - ")
+			sb.WriteString(GitLabQuote(kv.prefixedKey(prefix) + "=" + kv.Value))
+		} else {
+			sb.WriteString("  ")
+			writePrefixedKey(&sb, prefix, kv)
+			sb.WriteString(": ")
+			sb.WriteString(GitLabQuote(kv.Value))
+		}
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
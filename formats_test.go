@@ -0,0 +1,354 @@
+package struct2env
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPowerShellQuote(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"", "''"},
+		{"abc", "'abc'"},
+		{"it's", "'it''s'"},
+		{"$env:X `n", "'$env:X `n'"},
+		{"‘curly’", "'‘‘curly’’'"},
+	}
+	for _, test := range tests {
+		if got := PowerShellQuote(test.in); got != test.out {
+			t.Errorf("for %q expected %s and got %s", test.in, test.out, got)
+		}
+	}
+}
+
+func TestDotEnvQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a $HOME", `'a $HOME'`},
+		{"it's $HOME", `"it's $$HOME"`},
+		{"a\n$$b\"", `"a\n$$$$b\""`},
+	}
+	for _, tst := range tests {
+		q := DotEnvQuote(tst.input)
+		if q != tst.expected {
+			t.Errorf("for %q got %s expected %s", tst.input, q, tst.expected)
+		}
+		if u, err := DotEnvUnquote(q); err != nil || u != tst.input {
+			t.Errorf("round trip of %q gave %q, %v", tst.input, u, err)
+		}
+	}
+}
+
+func TestToPowerShell(t *testing.T) {
+	type Cfg struct {
+		Name    string
+		Verbose bool
+		Count   *int
+	}
+	envVars, errors := StructToEnvVars(Cfg{Name: "it's $HOME", Verbose: true})
+	if len(errors) != 0 {
+		t.Errorf("expected no error, got %v", errors)
+	}
+	str := ToPowerShellWithPrefix("PS_", envVars, "")
+	expected := `[Environment]::SetEnvironmentVariable('PS_NAME', 'it''s $HOME', 'User')
+[Environment]::SetEnvironmentVariable('PS_VERBOSE', 'true', 'User')
+[Environment]::SetEnvironmentVariable('PS_COUNT', $null, 'User')
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	str = ToPowerShellWithPrefix("", envVars[:1], "Machine")
+	expected = "[Environment]::SetEnvironmentVariable('NAME', 'it''s $HOME', 'Machine')\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
+
+func TestJSONMap(t *testing.T) {
+	type Cfg struct {
+		Name  string
+		Count int
+		Ptr   *int
+	}
+	kvl, errors := StructToEnvVars(Cfg{Name: "it's", Count: 3})
+	if len(errors) != 0 {
+		t.Fatalf("unexpected errors %v", errors)
+	}
+	m := ToJSONMap(kvl)
+	expected := map[string]string{"NAME": "it's", "COUNT": "3", "PTR": ""}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v got %v", expected, m)
+	}
+	back, errors := FromJSONMap(m)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if str := ToShell(back); str != "COUNT='3'\nNAME='it'\\''s'\nPTR=''\nexport COUNT NAME PTR\n" {
+		t.Errorf("unexpected shell output %q", str)
+	}
+	_, errors = FromJSONMap(map[string]string{"A": "x\x00y", "B": "ok"})
+	if len(errors) != 1 {
+		t.Errorf("expected 1 error for NUL, got %v", errors)
+	}
+}
+
+func TestToShellWithOptions(t *testing.T) {
+	kvl := []KeyValue{{Key: "A", ShellQuotedVal: "'x'"}, {Key: "B", ShellQuotedVal: "'y'", NoPrefix: true}}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	str := ToShellWithOptions(kvl, ShellOptions{
+		Prefix: "P_", CRLF: true, Shebang: true, Header: true, Source: "main.Config", Time: ts,
+	})
+	expected := "#!/bin/sh\r\n# Generated by struct2env from main.Config on 2024-01-02T03:04:05Z\r\n" +
+		"P_A='x'\r\nB='y'\r\nexport P_A B\r\n"
+	if str != expected {
+		t.Errorf("unexpected %q", str)
+	}
+	str = ToShellWithOptions(kvl, ShellOptions{SkipExport: true, Header: true, Time: ts})
+	if str != "# Generated by struct2env on 2024-01-02T03:04:05Z\nA='x'\nB='y'\n" {
+		t.Errorf("unexpected %q", str)
+	}
+	if str := ToShellWithOptions(kvl, ShellOptions{}); str != ToShell(kvl) {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestShellModes(t *testing.T) {
+	kvl := []KeyValue{{Key: "A", ShellQuotedVal: "'x'"}, {Key: "B", ShellQuotedVal: "'y'"}}
+	str := ToShellWithOptions(kvl, ShellOptions{Mode: ShellDeclare, Shebang: true})
+	if str != "#!/bin/bash\ndeclare -x A='x'\ndeclare -x B='y'\n" {
+		t.Errorf("unexpected %q", str)
+	}
+	str = ToShellWithOptions(kvl, ShellOptions{Mode: ShellReadonly, Prefix: "P_"})
+	if str != "readonly P_A='x'\nreadonly P_B='y'\nexport P_A P_B\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestGitLabCI(t *testing.T) {
+	type Cfg struct {
+		Path  string
+		Count int
+		Debug bool
+		Ptr   *int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Path: "$HOME/\"bin\"", Count: 3})
+	str := ToGitLabCIWithPrefix("CI_", kvl)
+	expected := `variables:
+  CI_PATH: "$$HOME/\"bin\""
+  CI_COUNT: "3"
+  CI_DEBUG: "false"
+  CI_PTR: ""
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
+
+func TestGitHubEnv(t *testing.T) {
+	type Cfg struct {
+		Path   string
+		Cert   string
+		Tricky string
+		Ptr    *int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Path: "$HOME/'bin'", Cert: "line1\nline2", Tricky: "a\nEOF\nEOF_2\n"})
+	str := ToGitHubEnvWithPrefix("CI_", kvl)
+	expected := `CI_PATH=$HOME/'bin'
+CI_CERT<<EOF
+line1
+line2
+EOF
+CI_TRICKY<<EOF_3
+a
+EOF
+EOF_2
+
+EOF_3
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
+
+func TestAnsibleVars(t *testing.T) {
+	type Cfg struct {
+		Name  string
+		Count int
+		Debug bool
+		Ptr   *int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "yes", Count: 3})
+	str := ToAnsibleVarsWithPrefix("APP_", kvl)
+	expected := `---
+app_name: "yes"
+app_count: "3"
+app_debug: false
+app_ptr: null
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	if name := AnsibleVarName("app.http-Port"); name != "app_http_port" {
+		t.Errorf("unexpected %q", name)
+	}
+}
+
+func TestStarlarkDict(t *testing.T) {
+	type Cfg struct {
+		Name string
+		Ptr  *int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "a \"q\" \\ \n\x01 é"})
+	str := ToStarlarkDictWithPrefix("T_", kvl)
+	expected := `{
+    "T_NAME": "a \"q\" \\ \n\x01 é",
+    "T_PTR": "",
+}
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	if str := ToStarlarkDictWithPrefix("", nil); str != "{}\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestRubyHash(t *testing.T) {
+	type Cfg struct {
+		Name string
+		Port int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: `#{system("id")} "x" \`, Port: 22})
+	str := ToRubyHashWithPrefix("VM_", kvl, "")
+	expected := `ENV_VARS = {
+  "VM_NAME" => "\#{system(\"id\")} \"x\" \\",
+  "VM_PORT" => "22",
+}
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	if str := ToRubyHashWithPrefix("", nil, "VARS"); str != "VARS = {}\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestPython(t *testing.T) {
+	type Cfg struct {
+		Name string
+		Port int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "it's \"x\"\t#", Port: 22})
+	str := ToPythonWithPrefix("PY_", kvl, true)
+	expected := `os.environ.update({
+    "PY_NAME": "it's \"x\"\t#",
+    "PY_PORT": "22",
+})
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	if str := ToPythonWithPrefix("", nil, false); str != "{}\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestDockerArgs(t *testing.T) {
+	type Cfg struct {
+		Name   string
+		Debug  bool
+		Ptr    *int
+		Global string `env:"GLOBAL,noprefix"`
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "it's $HOME", Debug: true, Global: "g"})
+	args := ToDockerArgsWithPrefix("APP_", kvl)
+	expected := []string{"-e", "APP_NAME=it's $HOME", "-e", "APP_DEBUG=true", "-e", "GLOBAL=g"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("got %q expected %q", args, expected)
+	}
+	if args = ToDockerArgs(kvl); args[1] != "NAME=it's $HOME" {
+		t.Errorf("unexpected %q", args)
+	}
+	str := ToDockerCommandLine("APP_", kvl)
+	if str != `-e APP_NAME='it'\''s $HOME' -e APP_DEBUG=true -e GLOBAL='g'` {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestComposeEnvironment(t *testing.T) {
+	type Cfg struct {
+		Name   string
+		Debug  bool
+		Ptr    *int
+		Global string `env:"GLOBAL,noprefix"`
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "it's $HOME", Debug: true, Global: "g"})
+	str := ToComposeEnvironmentWithPrefix(4, "APP_", kvl, false)
+	expected := `    environment:
+      APP_NAME: "it's $$HOME"
+      APP_DEBUG: "true"
+      GLOBAL: "g"
+`
+	if str != expected {
+		t.Errorf("got\n%s\nexpected\n%s", str, expected)
+	}
+	str = ToComposeEnvironmentWithPrefix(0, "APP_", kvl, true)
+	expected = `environment:
+  - "APP_NAME=it's $$HOME"
+  - "APP_DEBUG=true"
+  - "GLOBAL=g"
+`
+	if str != expected {
+		t.Errorf("got\n%s\nexpected\n%s", str, expected)
+	}
+	if str = ToComposeEnvironmentWithPrefix(0, "", nil, true); str != "environment: []\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	type Cfg struct {
+		Name   string
+		Count  int
+		Ptr    *int
+		Global string `env:"GLOBAL,noprefix"`
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "<a & \"b\">\n", Count: 3})
+	str := ToJSONWithPrefix("APP_", kvl, false)
+	expected := `{
+  "APP_NAME": "<a & \"b\">\n",
+  "APP_COUNT": "3",
+  "APP_PTR": "",
+  "GLOBAL": ""
+}
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(str), &m); err != nil || m["APP_NAME"] != kvl[0].Value {
+		t.Errorf("invalid JSON %v %v", m, err)
+	}
+	str = ToJSON(kvl[:2], true)
+	expected = `[
+  {"name":"NAME","value":"<a & \"b\">\n"},
+  {"name":"COUNT","value":"3"}
+]
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	var back []KeyValue
+	if err := json.Unmarshal([]byte(str), &back); err != nil || back[0].Value != kvl[0].Value || back[1].Key != "COUNT" {
+		t.Errorf("invalid JSON %v %v", back, err)
+	}
+	if str = ToJSON(nil, true); str != "[]\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}
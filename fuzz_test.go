@@ -0,0 +1,103 @@
+//go:build go1.18
+// +build go1.18
+
+package struct2env
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// The Quote functions iterate over runes, so invalid UTF-8 sequences only round trip as U+FFFD.
+
+var fuzzSeeds = []string{
+	"", "abc", "it's", `a "b" \c`, "a\nb\r\n", "$HOME `id`", "\x01\x7f\t", "é‘’‚‛'", "\\", "''",
+}
+
+func addSeeds(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+}
+
+func FuzzShellQuote(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, s string) {
+		q, err := ShellQuote(s)
+		if err != nil {
+			if !strings.ContainsRune(s, 0) {
+				t.Errorf("unexpected error for %q: %v", s, err)
+			}
+			return
+		}
+		if u, err := ShellUnquote(q); err != nil || u != s {
+			t.Errorf("round trip of %q gave %q, %q, %v", s, q, u, err)
+		}
+	})
+}
+
+func FuzzShellUnquote(f *testing.F) {
+	addSeeds(f)
+	f.Add(`'a'\''b'"c\$"d\ e`)
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := ShellUnquote(s)
+		if err != nil || strings.ContainsRune(u, 0) {
+			return
+		}
+		q, _ := ShellQuote(u)
+		if u2, err := ShellUnquote(q); err != nil || u2 != u {
+			t.Errorf("re-quoting %q (from %q) gave %q, %q, %v", u, s, q, u2, err)
+		}
+	})
+}
+
+func FuzzDotEnvQuote(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, s string) {
+		q := DotEnvQuote(s)
+		u, err := DotEnvUnquote(q)
+		if err != nil || (u != s && utf8.ValidString(s)) {
+			t.Errorf("round trip of %q gave %q, %q, %v", s, q, u, err)
+		}
+	})
+}
+
+func FuzzYamlQuote(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, s string) {
+		q := YamlQuote(s)
+		u, err := YamlUnquote(q)
+		if err != nil || (u != s && utf8.ValidString(s)) {
+			t.Errorf("round trip of %q gave %q, %q, %v", s, q, u, err)
+		}
+	})
+}
+
+func FuzzPowerShellQuote(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, s string) {
+		q := PowerShellQuote(s)
+		u, err := PowerShellUnquote(q)
+		if err != nil || (u != s && utf8.ValidString(s)) {
+			t.Errorf("round trip of %q gave %q, %q, %v", s, q, u, err)
+		}
+	})
+}
+
+func FuzzANSICQuote(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, s string) {
+		q, err := ANSICQuote(s)
+		if err != nil {
+			if !strings.ContainsRune(s, 0) {
+				t.Errorf("unexpected error for %q: %v", s, err)
+			}
+			return
+		}
+		u, err := ANSICUnquote(q)
+		if err != nil || (u != s && utf8.ValidString(s)) {
+			t.Errorf("round trip of %q gave %q, %q, %v", s, q, u, err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package struct2env
+
+import (
+	"reflect"
+	"strings"
+)
+
+// StructToEnvVarsGroups is StructToEnvVars only keeping the fields in at least one of groups, set by
+// the `groups:"public,debug"` tag, so different subsets of the same struct can go to different
+// destinations (child process environment, debug dump, manifest...). See Encoder.Groups.
+func StructToEnvVarsGroups(s interface{}, groups ...string) ([]KeyValue, []error) {
+	e := NewEncoder()
+	e.Groups = groups
+	return e.StructToEnvVars(s)
+}
+
+// inGroups returns whether the `groups` tag of field has one of the Encoder's Groups.
+func (e *Encoder) inGroups(field reflect.StructField) bool {
+	tag, found := field.Tag.Lookup("groups")
+	if !found {
+		return false
+	}
+	for _, g := range strings.Split(tag, ",") {
+		for _, want := range e.Groups {
+			if strings.TrimSpace(g) == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupFilter returns whether field is in the Encoder's Groups (or within a struct field that is, outer)
+// and whether it should be skipped: when not in the groups, unless it's a struct (recurse) without a
+// `groups` tag whose own fields can still be.
+func (e *Encoder) groupFilter(field reflect.StructField, outer, recurse bool) (bool, bool) {
+	if len(e.Groups) == 0 {
+		return false, false
+	}
+	in := outer || e.inGroups(field)
+	_, tagged := field.Tag.Lookup("groups")
+	return in, !in && (tagged || !recurse)
+}
@@ -0,0 +1,53 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+type groupsDB struct {
+	Host     string `groups:"public"`
+	Password string
+}
+
+type groupsConfig struct {
+	Name   string `groups:"public, debug"`
+	Debug  bool   `groups:"debug"`
+	DB     groupsDB
+	Tuning struct {
+		A int
+		B int `groups:"public"`
+	} `groups:"debug"`
+	Internal string
+}
+
+func (groupsConfig) EnvValues() map[string]string {
+	return map[string]string{"EXTRA": "x"}
+}
+
+func TestGroups(t *testing.T) {
+	cfg := groupsConfig{Name: "n"}
+	tests := []struct {
+		groups []string
+		shell  string
+	}{
+		{nil, "NAME='n'\nDEBUG=false\nDB_HOST=''\nDB_PASSWORD=''\nTUNING_A='0'\nTUNING_B='0'\nINTERNAL=''\nEXTRA='x'\n"},
+		{[]string{"public"}, "NAME='n'\nDB_HOST=''\n"},
+		{[]string{"debug"}, "NAME='n'\nDEBUG=false\nTUNING_A='0'\nTUNING_B='0'\n"},
+		{[]string{"other"}, ""},
+	}
+	for _, tst := range tests {
+		kvl, errs := StructToEnvVarsGroups(cfg, tst.groups...)
+		if len(errs) != 0 {
+			t.Errorf("unexpected errors %v", errs)
+		}
+		if str := ToShellWithPrefix("", kvl, true); str != tst.shell {
+			t.Errorf("for %v got %q expected %q", tst.groups, str, tst.shell)
+		}
+	}
+	e := NewEncoder()
+	e.Groups = []string{"public"}
+	_, skipped, _ := e.StructToEnvVarsReport(&cfg)
+	if len(skipped) != 4 || skipped[0].Field != "groupsConfig.Debug" || skipped[0].Reason != SkipGroup {
+		t.Errorf("unexpected skipped %+v", skipped)
+	}
+}
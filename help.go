@@ -0,0 +1,110 @@
+package struct2env
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// SecretMask replaces the (non empty) values of secret fields in HelpText.
+const SecretMask = "********"
+
+// HelpText returns a table of all the environment variables s (struct or pointer to struct) supports,
+// with their current value, e.g. after SetFromEnv() so the effective one, and their description from
+// the `help:"..."` tags. Values of fields tagged `env:",secret"` are masked. Meant for binaries to
+// implement something like `myapp env-help`.
+func HelpText(prefix string, s interface{}) string {
+	return NewEncoder().HelpText(prefix, s)
+}
+
+// HelpText is HelpText using the Encoder's Options.
+func (e *Encoder) HelpText(prefix string, s interface{}) string {
+	vars, errs := e.DescribeEnvVars(prefix, s)
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE\tDESCRIPTION")
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Name, helpValue(v), v.Help)
+	}
+	w.Flush()
+	// Remove the padding of the value column for variables without description.
+	table := strings.Split(sb.String(), "\n")
+	sb.Reset()
+	for _, line := range table[:len(table)-1] {
+		sb.WriteString(strings.TrimRight(line, " "))
+		sb.WriteString("\n")
+	}
+	for _, err := range errs {
+		sb.WriteString("error: ")
+		sb.WriteString(err.Error())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func helpValue(v EnvVarInfo) string {
+	switch {
+	case !v.Set:
+		return "(unset)"
+	case v.Secret && v.Value != "":
+		return SecretMask
+	default:
+		return fmt.Sprintf("%q", v.Value)
+	}
+}
+
+// EnvVarInfo describes one of the environment variables supported by a struct, see DescribeEnvVars.
+type EnvVarInfo struct {
+	Name    string // Variable name, prefix included.
+	Value   string // Current value, SecretMask for non empty secrets.
+	Set     bool   // Whether there is a value (false for nil pointers).
+	Secret  bool   // From the `secret` tag option.
+	Help    string // From the `help` tag.
+	Section string // See KeyValue.Section.
+	Type    string // Go type of the field, see KeyValue.Type.
+	// Aliases are the previous names of the variable, prefix included, see KeyValue.Aliases.
+	Aliases []string
+}
+
+// DescribeEnvVars returns the description of all the environment variables s (struct or pointer to struct)
+// supports, with their current value; the structured version of HelpText, for documentation or completion
+// generators. These are the variables SetFrom reads: fields tagged `readonly` are included (but without
+// their value, reported as unset) and `writeonly` ones aren't.
+func DescribeEnvVars(prefix string, s interface{}) ([]EnvVarInfo, []error) {
+	return NewEncoder().DescribeEnvVars(prefix, s)
+}
+
+// DescribeEnvVars is DescribeEnvVars using the Encoder's Options.
+func (e *Encoder) DescribeEnvVars(prefix string, s interface{}) ([]EnvVarInfo, []error) {
+	describer := *e
+	describer.inputs = true
+	kvl, errs := describer.StructToEnvVars(s)
+	return describe(prefix, kvl), errs
+}
+
+// describe converts (already serialized) kvl to EnvVarInfo, applying the output prefix.
+func describe(prefix string, kvl []KeyValue) []EnvVarInfo {
+	res := make([]EnvVarInfo, 0, len(kvl))
+	for _, kv := range kvl {
+		v := EnvVarInfo{
+			Name:    kv.prefixedKey(prefix),
+			Value:   kv.Value,
+			Set:     kv.YamlQuotedVal != "null",
+			Secret:  kv.Secret,
+			Help:    kv.Help,
+			Section: kv.Section,
+			Type:    kv.Type,
+		}
+		for _, alias := range kv.Aliases {
+			if !kv.NoPrefix {
+				alias = prefix + alias
+			}
+			v.Aliases = append(v.Aliases, alias)
+		}
+		if v.Secret && v.Value != "" {
+			v.Value = SecretMask
+		}
+		res = append(res, v)
+	}
+	return res
+}
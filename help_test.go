@@ -0,0 +1,33 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestHelpText(t *testing.T) {
+	type DB struct {
+		Password string `env:",secret" help:"Database password."`
+		Empty    string `env:",secret" help:"Not set yet."`
+	}
+	type Cfg struct {
+		Port    int    `help:"Port to listen on."`
+		Proxy   string `env:"HTTP_PROXY,noprefix" help:"Outgoing proxy."`
+		Timeout *int
+		DB      DB
+	}
+	str := HelpText("APP_", Cfg{Port: 8080, Proxy: "http://p:3128", DB: DB{Password: "hunter2"}})
+	expected := `NAME             VALUE            DESCRIPTION
+APP_PORT         "8080"           Port to listen on.
+HTTP_PROXY       "http://p:3128"  Outgoing proxy.
+APP_TIMEOUT      (unset)
+APP_DB_PASSWORD  ********         Database password.
+APP_DB_EMPTY     ""               Not set yet.
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	kvl, _ := StructToEnvVars(Cfg{})
+	if kvl[0].Help != "Port to listen on." || kvl[0].Secret || !kvl[3].Secret {
+		t.Errorf("unexpected help/secret in %+v", kvl)
+	}
+}
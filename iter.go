@@ -0,0 +1,36 @@
+//go:build go1.23
+
+package struct2env
+
+import (
+	"iter"
+	"reflect"
+)
+
+// EnvVars is the iterator version of StructToEnvVars: the entries (and their error if any) are produced
+// as the struct is walked, so callers can filter or stream them without building the whole list and
+// stop early, e.g. on the first error. Errors not tied to an entry come with an empty KeyValue.
+//
+//	for kv, err := range struct2env.EnvVars(cfg) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(kv)
+//	}
+func EnvVars(s interface{}) iter.Seq2[KeyValue, error] {
+	return NewEncoder().EnvVars(s)
+}
+
+// EnvVars is the Encoder version of EnvVars, the NamePolicy if any is checked for each entry.
+func (e *Encoder) EnvVars(s interface{}) iter.Seq2[KeyValue, error] {
+	return func(yield func(KeyValue, error) bool) {
+		streaming := *e
+		streaming.yield = yield
+		streaming.stopped = false
+		prefix := ""
+		if streaming.AutoPrefix {
+			prefix = streaming.typePrefix(s)
+		}
+		streaming.structToEnvVars(nil, nil, prefix, nil, reflect.ValueOf(s))
+	}
+}
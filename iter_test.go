@@ -0,0 +1,77 @@
+//go:build go1.23
+
+package struct2env
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvVarsIterator(t *testing.T) {
+	type Inner struct {
+		A string
+		B string
+	}
+	type Cfg struct {
+		Name   string
+		Bad    string
+		Inner  Inner
+		Labels map[string]string
+	}
+	cfg := Cfg{Name: "n", Bad: "nul\x00", Inner: Inner{"a", "b"}, Labels: map[string]string{"x": "1", "y": "2"}}
+	expected, expectedErrors := StructToEnvVars(cfg)
+	var kvl []KeyValue
+	var errs []error
+	for kv, err := range EnvVars(&cfg) {
+		kvl = append(kvl, kv)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !reflect.DeepEqual(kvl, expected) || !reflect.DeepEqual(errs, expectedErrors) {
+		t.Errorf("iterator mismatch:\n%v %v\n%v %v", kvl, errs, expected, expectedErrors)
+	}
+	// Stop at the first error.
+	var keys []string
+	for kv, err := range EnvVars(cfg) {
+		if err != nil {
+			break
+		}
+		keys = append(keys, kv.Key)
+	}
+	if strings.Join(keys, ",") != "NAME" {
+		t.Errorf("expected to stop after NAME, got %v", keys)
+	}
+	// Early stop within nested struct and map.
+	for _, n := range []int{3, 5} {
+		i := 0
+		for range EnvVars(cfg) {
+			i++
+			if i == n {
+				break
+			}
+		}
+		if i != n {
+			t.Errorf("expected %d iterations got %d", n, i)
+		}
+	}
+	e := NewEncoder()
+	e.NamePolicy = &UpperPortableNames
+	e.KeyMapper = DotKeys
+	count := 0
+	for kv, err := range e.EnvVars(Cfg{}) {
+		if err == nil {
+			t.Errorf("expected policy error for %s", kv.Key)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Errorf("expected 4 entries, got %d", count)
+	}
+	for kv, err := range EnvVars(42) {
+		if err == nil || kv.Key != "" {
+			t.Errorf("expected error for non struct, got %v %v", kv, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package struct2env
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// DefaultResourceName is the name of the kubernetes ConfigMap/Secret when none is given.
+const DefaultResourceName = "config"
+
+// writeManifestHeader writes the start of a kubernetes manifest of the given kind and name (DefaultResourceName
+// when empty) up to its data, `data: {}` when there is no entry.
+func writeManifestHeader(sb *strings.Builder, kind, name, extra string, empty bool) {
+	if name == "" {
+		name = DefaultResourceName
+	}
+	sb.WriteString("apiVersion: v1\nkind: ")
+	sb.WriteString(kind)
+	sb.WriteString("\nmetadata:\n  name: ")
+	sb.WriteString(YamlQuote(name))
+	sb.WriteString("\n")
+	sb.WriteString(extra)
+	if empty {
+		sb.WriteString("data: {}\n")
+	} else {
+		sb.WriteString("data:\n")
+	}
+}
+
+// manifestEntries returns the entries of kvl with a value, nil pointers being left out (unset).
+func manifestEntries(kvl []KeyValue) []KeyValue {
+	res := make([]KeyValue, 0, len(kvl))
+	for _, kv := range kvl {
+		if kv.YamlQuotedVal != "null" {
+			res = append(res, kv)
+		}
+	}
+	return res
+}
+
+// ToConfigMapWithPrefix emits a kubernetes ConfigMap manifest named name (DefaultResourceName when empty) with
+// the variables as its data, values quoted as strings. Nil pointers are left out. Use OmitSecrets to keep the
+// secrets for a Secret (see ToSecretWithPrefix).
+func ToConfigMapWithPrefix(prefix string, kvl []KeyValue, name string) string {
+	kvl = manifestEntries(kvl)
+	var sb strings.Builder
+	writeManifestHeader(&sb, "ConfigMap", name, "", len(kvl) == 0)
+	for _, kv := range kvl {
+		sb.WriteString("  ")
+		sb.WriteString(kv.prefixedKey(prefix))
+		sb.WriteString(": ")
+		sb.WriteString(YamlQuote(kv.Value))
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+// ToSecretWithPrefix emits a kubernetes (Opaque) Secret manifest named name (DefaultResourceName when empty) with
+// the base64 encoded values in its data. Nil pointers are left out. Use OnlySecrets to restrict it to the
+// fields tagged `env:",secret"` and have one struct drive both the ConfigMap and the Secret.
+func ToSecretWithPrefix(prefix string, kvl []KeyValue, name string) string {
+	kvl = manifestEntries(kvl)
+	var sb strings.Builder
+	writeManifestHeader(&sb, "Secret", name, "type: Opaque\n", len(kvl) == 0)
+	for _, kv := range kvl {
+		sb.WriteString("  ")
+		sb.WriteString(kv.prefixedKey(prefix))
+		sb.WriteString(": ")
+		sb.WriteString(base64.StdEncoding.EncodeToString([]byte(kv.Value)))
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package struct2env
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubernetesManifests(t *testing.T) {
+	type Cfg struct {
+		Port     int
+		Name     string
+		Password string  `env:",secret"`
+		Token    *string `env:",secret"`
+	}
+	kvl, errs := StructToEnvVars(Cfg{Port: 8080, Name: "it's", Password: "s3cr3t"})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	expected := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: "app"
+data:
+  APP_PORT: "8080"
+  APP_NAME: "it's"
+`
+	if str := ToConfigMapWithPrefix("APP_", OmitSecrets(kvl), "app"); str != expected {
+		t.Errorf("got %q expected %q", str, expected)
+	}
+	expected = `apiVersion: v1
+kind: Secret
+metadata:
+  name: "config"
+type: Opaque
+data:
+  APP_PASSWORD: czNjcjN0
+`
+	if str := ToSecretWithPrefix("APP_", OnlySecrets(kvl), ""); str != expected {
+		t.Errorf("got %q expected %q", str, expected)
+	}
+	var sb strings.Builder
+	if err := Render(&sb, "secret", kvl, FormatOptions{Name: "s", Secrets: SecretsOnly}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !strings.HasSuffix(sb.String(), "data:\n  PASSWORD: czNjcjN0\n") {
+		t.Errorf("unexpected %q", sb.String())
+	}
+	if str := ToConfigMapWithPrefix("", nil, "empty"); !strings.HasSuffix(str, "\ndata: {}\n") {
+		t.Errorf("unexpected %q", str)
+	}
+}
@@ -0,0 +1,198 @@
+package struct2env
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// splitEnviron splits a "KEY=value" entry as found in os.Environ() or exec.Cmd.Env.
+// The search for = starts after the first character as Windows has hidden
+// per drive variables like "=C:=C:\dir".
+func splitEnviron(entry string) (string, string, bool) {
+	if entry == "" {
+		return "", "", false
+	}
+	idx := strings.IndexByte(entry[1:], '=')
+	if idx < 0 {
+		return "", "", false
+	}
+	return entry[:idx+1], entry[idx+2:], true
+}
+
+// MapLookup returns an EnvLookup finding the keys in the given map.
+func MapLookup(m map[string]string) EnvLookup {
+	return func(key string) (string, bool) {
+		v, found := m[key]
+		return v, found
+	}
+}
+
+// EnvironLookup returns an EnvLookup over "KEY=value" entries as found in os.Environ() or exec.Cmd.Env.
+// Like for exec.Cmd, when a key is present more than once, the last value wins.
+// Malformed entries (without =) are ignored.
+func EnvironLookup(environ []string) EnvLookup {
+	m := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if k, v, ok := splitEnviron(entry); ok {
+			m[k] = v
+		}
+	}
+	return MapLookup(m)
+}
+
+// CaseInsensitiveLookup returns an EnvLookup over the given "KEY=value" entries (e.g. os.Environ())
+// matching keys regardless of case, like Windows does: Path finds PATH. An exact match is preferred
+// and otherwise the first entry (in environ order) matching case-insensitively is used.
+func CaseInsensitiveLookup(environ []string) EnvLookup {
+	exact := make(map[string]string, len(environ))
+	folded := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		k, v, ok := splitEnviron(entry)
+		if !ok {
+			continue
+		}
+		exact[k] = v
+		uk := strings.ToUpper(k)
+		if _, found := folded[uk]; !found {
+			folded[uk] = v
+		}
+	}
+	return func(key string) (string, bool) {
+		if v, found := exact[key]; found {
+			return v, true
+		}
+		v, found := folded[strings.ToUpper(key)]
+		return v, found
+	}
+}
+
+// EnvKeys enumerates the available variable names, to complement an EnvLookup (see Decoder.Keys).
+type EnvKeys func() []string
+
+// OSKeys returns the names of the current os environment variables.
+func OSKeys() []string {
+	return EnvironKeys(os.Environ())()
+}
+
+// MapKeys returns an EnvKeys listing the (sorted) keys of m, to use with MapLookup(m).
+func MapKeys(m map[string]string) EnvKeys {
+	return func() []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+}
+
+// EnvironKeys returns an EnvKeys listing the names of the "KEY=value" entries, to use with EnvironLookup(environ).
+func EnvironKeys(environ []string) EnvKeys {
+	return func() []string {
+		keys := make([]string, 0, len(environ))
+		for _, entry := range environ {
+			if k, _, ok := splitEnviron(entry); ok {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+}
+
+// ChainLookups returns an EnvLookup trying each of the lookups in order and returning the first
+// value found. E.g. ChainLookups(os.LookupEnv, fileLookup, defaultsLookup) for env over file over defaults.
+func ChainLookups(lookups ...EnvLookup) EnvLookup {
+	return func(key string) (string, bool) {
+		for _, l := range lookups {
+			if v, found := l(key); found {
+				return v, true
+			}
+		}
+		return "", false
+	}
+}
+
+// PrefixedLookup returns an EnvLookup prepending prefix to the keys before delegating to l.
+// E.g. PrefixedLookup("SIDECAR_", os.LookupEnv) lets a struct written for APP_ prefixed
+// variables read them from the SIDECAR_APP_ namespace.
+func PrefixedLookup(prefix string, l EnvLookup) EnvLookup {
+	return func(key string) (string, bool) {
+		return l(prefix + key)
+	}
+}
+
+// TrimPrefixLookup returns an EnvLookup stripping prefix from the keys before delegating to l;
+// keys not starting with prefix are not found.
+func TrimPrefixLookup(prefix string, l EnvLookup) EnvLookup {
+	return func(key string) (string, bool) {
+		if !strings.HasPrefix(key, prefix) {
+			return "", false
+		}
+		return l(key[len(prefix):])
+	}
+}
+
+// CachingLookup memoizes the results (found or not) of an expensive EnvLookup, like a remote
+// parameter store, so repeated SetFrom calls or polls don't hammer the source.
+// Use its Lookup method as the EnvLookup. It is safe for concurrent use.
+type CachingLookup struct {
+	lookup EnvLookup
+	ttl    time.Duration
+	now    func() time.Time
+	mu     sync.Mutex
+	cache  map[string]cachedValue
+	// generation is incremented by Invalidate, for the lookups started before it not to store their
+	// (possibly stale) result.
+	generation uint64
+}
+
+type cachedValue struct {
+	value   string
+	found   bool
+	expires time.Time
+}
+
+// NewCachingLookup returns a CachingLookup delegating to l for keys not in cache or whose
+// entry is older than ttl. A ttl of 0 means entries never expire (until Invalidate is called).
+func NewCachingLookup(l EnvLookup, ttl time.Duration) *CachingLookup {
+	return &CachingLookup{lookup: l, ttl: ttl, now: time.Now, cache: make(map[string]cachedValue)}
+}
+
+// Lookup is the (caching) EnvLookup.
+func (c *CachingLookup) Lookup(key string) (string, bool) {
+	c.mu.Lock()
+	entry, found := c.cache[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if found && (c.ttl == 0 || c.now().Before(entry.expires)) {
+		return entry.value, entry.found
+	}
+	// Not holding the lock while calling the (slow) source; concurrent misses may both call it.
+	entry.value, entry.found = c.lookup(key)
+	if c.ttl != 0 {
+		entry.expires = c.now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	if c.generation == generation {
+		c.cache[key] = entry
+	}
+	c.mu.Unlock()
+	return entry.value, entry.found
+}
+
+// Invalidate removes the given keys from the cache, or all of them when called without arguments.
+func (c *CachingLookup) Invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if len(keys) == 0 {
+		c.cache = make(map[string]cachedValue)
+		return
+	}
+	for _, k := range keys {
+		delete(c.cache, k)
+	}
+}
@@ -0,0 +1,184 @@
+package struct2env
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCaseInsensitiveLookup(t *testing.T) {
+	lookup := CaseInsensitiveLookup([]string{
+		"=C:=C:\\dir",
+		"Path=c:\\windows",
+		"PATH=/usr/bin",
+		"foo=bar=baz",
+		"invalid",
+		"EMPTY=",
+	})
+	tests := []struct {
+		key   string
+		val   string
+		found bool
+	}{
+		{"PATH", "/usr/bin", true},
+		{"Path", "c:\\windows", true},
+		{"path", "c:\\windows", true},
+		{"FOO", "bar=baz", true},
+		{"=C:", "C:\\dir", true},
+		{"empty", "", true},
+		{"invalid", "", false},
+		{"NOPE", "", false},
+	}
+	for _, test := range tests {
+		val, found := lookup(test.key)
+		if val != test.val || found != test.found {
+			t.Errorf("for %q expected %q, %v and got %q, %v", test.key, test.val, test.found, val, found)
+		}
+	}
+	type Cfg struct {
+		Path string
+	}
+	cfg := Cfg{}
+	errors := SetFrom(CaseInsensitiveLookup([]string{"app_path=/x"}), "APP_", &cfg)
+	if len(errors) != 0 || cfg.Path != "/x" {
+		t.Errorf("expected case insensitive match, got %+v %v", cfg, errors)
+	}
+}
+
+func TestChainLookups(t *testing.T) {
+	env := CaseInsensitiveLookup([]string{"A=env", "EMPTY="})
+	file := CaseInsensitiveLookup([]string{"A=file", "B=file"})
+	defaults := CaseInsensitiveLookup([]string{"A=default", "B=default", "C=default", "EMPTY=default"})
+	lookup := ChainLookups(env, file, defaults)
+	for _, test := range []struct{ key, val string }{{"A", "env"}, {"B", "file"}, {"C", "default"}, {"EMPTY", ""}} {
+		if v, found := lookup(test.key); !found || v != test.val {
+			t.Errorf("for %q expected %q got %q %v", test.key, test.val, v, found)
+		}
+	}
+	if v, found := lookup("D"); found {
+		t.Errorf("unexpected found %q", v)
+	}
+	if _, found := ChainLookups()("A"); found {
+		t.Errorf("empty chain should find nothing")
+	}
+}
+
+func TestPrefixLookups(t *testing.T) {
+	env := CaseInsensitiveLookup([]string{"SIDECAR_APP_PORT=8080", "APP_PORT=80", "PORT=1"})
+	if v, _ := PrefixedLookup("SIDECAR_", env)("APP_PORT"); v != "8080" {
+		t.Errorf("unexpected prefixed lookup %q", v)
+	}
+	trimmed := TrimPrefixLookup("SIDECAR_", env)
+	if v, _ := trimmed("SIDECAR_APP_PORT"); v != "80" {
+		t.Errorf("unexpected trimmed lookup %q", v)
+	}
+	if v, found := trimmed("APP_PORT"); found {
+		t.Errorf("unexpected trimmed lookup found %q", v)
+	}
+	type Cfg struct {
+		Port int
+	}
+	cfg := Cfg{}
+	errors := SetFrom(PrefixedLookup("SIDECAR_", env), "APP_", &cfg)
+	if len(errors) != 0 || cfg.Port != 8080 {
+		t.Errorf("unexpected decoding %+v %v", cfg, errors)
+	}
+}
+
+func TestMapAndEnvironLookup(t *testing.T) {
+	m := MapLookup(map[string]string{"A": "1", "EMPTY": ""})
+	if v, found := m("A"); !found || v != "1" {
+		t.Errorf("unexpected map lookup %q %v", v, found)
+	}
+	if v, found := m("EMPTY"); !found || v != "" {
+		t.Errorf("unexpected map lookup %q %v", v, found)
+	}
+	if _, found := m("B"); found {
+		t.Errorf("unexpected found B")
+	}
+	e := EnvironLookup([]string{"A=1", "B=x=y", "A=2", "bad", "=C:=C:\\"})
+	for _, test := range []struct{ key, val string }{{"A", "2"}, {"B", "x=y"}, {"=C:", "C:\\"}} {
+		if v, found := e(test.key); !found || v != test.val {
+			t.Errorf("for %q expected %q got %q %v", test.key, test.val, v, found)
+		}
+	}
+	if _, found := e("bad"); found {
+		t.Errorf("unexpected found bad")
+	}
+}
+
+func TestCachingLookup(t *testing.T) {
+	calls := 0
+	values := map[string]string{"A": "1"}
+	source := func(key string) (string, bool) {
+		calls++
+		v, found := values[key]
+		return v, found
+	}
+	now := time.Unix(1000, 0)
+	c := NewCachingLookup(source, time.Minute)
+	c.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		if v, found := c.Lookup("A"); !found || v != "1" {
+			t.Errorf("unexpected lookup %q %v", v, found)
+		}
+		if _, found := c.Lookup("B"); found {
+			t.Errorf("unexpected found B")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls to the source, got %d", calls)
+	}
+	values["A"] = "2"
+	now = now.Add(59 * time.Second)
+	if v, _ := c.Lookup("A"); v != "1" {
+		t.Errorf("expected cached value, got %q", v)
+	}
+	now = now.Add(time.Second)
+	if v, _ := c.Lookup("A"); v != "2" || calls != 3 {
+		t.Errorf("expected expired and refreshed value, got %q (%d calls)", v, calls)
+	}
+	values["B"] = "b"
+	c.Invalidate("B")
+	if v, _ := c.Lookup("B"); v != "b" || calls != 4 {
+		t.Errorf("expected invalidated B, got %q (%d calls)", v, calls)
+	}
+	forever := NewCachingLookup(source, 0)
+	forever.Lookup("A")
+	values["A"] = "3"
+	if v, _ := forever.Lookup("A"); v != "2" {
+		t.Errorf("expected never expiring value, got %q", v)
+	}
+	forever.Invalidate()
+	if v, _ := forever.Lookup("A"); v != "3" {
+		t.Errorf("expected invalidated value, got %q", v)
+	}
+}
+
+func TestCachingLookupInvalidateInFlight(t *testing.T) {
+	started, release := make(chan bool), make(chan bool)
+	value := "old"
+	source := func(string) (string, bool) {
+		if value == "old" {
+			started <- true
+			<-release
+			return "old", true
+		}
+		return value, true
+	}
+	c := NewCachingLookup(source, 0)
+	done := make(chan string)
+	go func() {
+		v, _ := c.Lookup("A")
+		done <- v
+	}()
+	<-started // The miss is in flight: the source changes and the cache is invalidated meanwhile.
+	value = "new"
+	c.Invalidate()
+	close(release)
+	if v := <-done; v != "old" {
+		t.Errorf("unexpected in flight value %q", v)
+	}
+	if v, _ := c.Lookup("A"); v != "new" {
+		t.Errorf("stale value stored after Invalidate: %q", v)
+	}
+}
@@ -0,0 +1,125 @@
+package struct2env
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// isStringMap reports whether t is a map[string]string (or a named type with that underlying type).
+func isStringMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+}
+
+// mapToEnvVars appends one entry per key of the map v (the field named fieldName), sorted by key,
+// named base.Key+sep+KEY.
+func (e *Encoder) mapToEnvVars(envVars []KeyValue, allErrors []error, base KeyValue, sep string,
+	fieldName string, v reflect.Value, opts tagOptions,
+) ([]KeyValue, []error) {
+	if !v.CanInterface() {
+		return e.add(envVars, allErrors, nil, fmt.Errorf("can't interface %s", base.Key))
+	}
+	mapKeys := v.MapKeys()
+	sort.Slice(mapKeys, func(i, j int) bool { return mapKeys[i].String() < mapKeys[j].String() })
+	keepCase := opts.Contains("keepcase")
+	for _, k := range mapKeys {
+		if e.stopped {
+			break
+		}
+		key := k.String()
+		if !keepCase {
+			key = strings.ToUpper(key)
+		}
+		res := base
+		res.Key = base.Key + sep + key
+		if e.isReserved(res.Key, opts) {
+			envVars, allErrors = e.add(envVars, allErrors, nil, &ReservedError{EnvName: res.Key, Field: fieldName})
+			continue
+		}
+		err := setString(&res, v.MapIndex(k).String())
+		envVars, allErrors = e.add(envVars, allErrors, &res, err)
+	}
+	return envVars, allErrors
+}
+
+// joinMap sets result to the sorted key=value entries of the map v joined by sep, for the `inline` option.
+func joinMap(result *KeyValue, v reflect.Value, sep string) error {
+	mapKeys := v.MapKeys()
+	sort.Slice(mapKeys, func(i, j int) bool { return mapKeys[i].String() < mapKeys[j].String() })
+	parts := make([]string, 0, len(mapKeys))
+	for _, k := range mapKeys {
+		key, val := k.String(), v.MapIndex(k).String()
+		if strings.Contains(key, "=") || strings.Contains(key, sep) || strings.Contains(val, sep) {
+			return fmt.Errorf("entry %q=%q of %s contains = in the key or the separator %q", key, val, result.Key, sep)
+		}
+		parts = append(parts, key+"="+val)
+	}
+	return setString(result, strings.Join(parts, sep))
+}
+
+// splitMap sets the map fieldValue to the sep separated key=value entries of envVal.
+func splitMap(fieldValue reflect.Value, envName, envVal, sep string) error {
+	t := fieldValue.Type()
+	res := reflect.MakeMap(t)
+	if envVal != "" {
+		for _, entry := range strings.Split(envVal, sep) {
+			idx := strings.IndexByte(entry, '=')
+			if idx < 0 {
+				return fmt.Errorf("can't set %s: entry %q isn't key=value (found %s=%q)", envName, entry, envName, envVal)
+			}
+			res.SetMapIndex(reflect.ValueOf(entry[:idx]).Convert(t.Key()), reflect.ValueOf(entry[idx+1:]).Convert(t.Elem()))
+		}
+	}
+	fieldValue.Set(res)
+	return nil
+}
+
+// setMap adds to the map fieldValue an entry for each of the Decoder's Keys starting with namePrefix,
+// keyed by the rest of the name (lowercased unless the `keepcase` option is set).
+func (d *Decoder) setMap(allErrors []error, fieldPath string, fieldValue reflect.Value, opts tagOptions,
+	namePrefix string,
+) []error {
+	if d.Keys == nil {
+		return allErrors
+	}
+	names := d.Keys()
+	sort.Strings(names)
+	keepCase := opts.Contains("keepcase")
+	t := fieldValue.Type()
+	for _, name := range names {
+		if len(name) <= len(namePrefix) || !strings.HasPrefix(name, namePrefix) {
+			continue
+		}
+		val, source, found := d.lookup(name)
+		if !found {
+			continue
+		}
+		key := name[len(namePrefix):]
+		if !keepCase {
+			key = strings.ToLower(key)
+		}
+		if d.isReserved(name, opts) {
+			allErrors = append(allErrors, &ReservedError{EnvName: name, Field: fieldPath})
+			continue
+		}
+		var err error
+		if !fieldValue.CanSet() {
+			err = fmt.Errorf("can't set %s (found %s=%q)", fieldPath, name, val)
+			allErrors = append(allErrors, err)
+		} else {
+			if fieldValue.IsNil() {
+				fieldValue.Set(reflect.MakeMap(t))
+			}
+			fieldValue.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), reflect.ValueOf(val).Convert(t.Elem()))
+		}
+		if d.Trace != nil {
+			var errs []error
+			if err != nil {
+				errs = []error{err}
+			}
+			d.Trace.add(fieldPath+"["+key+"]", []string{name}, source, &val, errs)
+		}
+	}
+	return allErrors
+}
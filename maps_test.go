@@ -0,0 +1,83 @@
+package struct2env
+
+import (
+	"reflect"
+	"testing"
+)
+
+type Labels map[string]string
+
+type MapConfig struct {
+	Name   string
+	Labels Labels
+	Raw    map[string]string `env:"RAW,keepcase"`
+}
+
+func TestMapEncode(t *testing.T) {
+	cfg := MapConfig{
+		Name:   "n",
+		Labels: Labels{"team": "infra", "tier": "it's 1"},
+		Raw:    map[string]string{"Mixed": "x"},
+	}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("M_", kvl, false)
+	expected := `M_NAME='n'
+M_LABELS_TEAM='infra'
+M_LABELS_TIER='it'\''s 1'
+M_RAW_Mixed='x'
+export M_NAME M_LABELS_TEAM M_LABELS_TIER M_RAW_Mixed
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
+
+func TestMapDecode(t *testing.T) {
+	env := map[string]string{
+		"M_NAME":        "n",
+		"M_LABELS_TEAM": "infra",
+		"M_LABELS_TIER": "1",
+		"M_LABELS_":     "ignored",
+		"M_LABELSX":     "ignored too",
+		"M_RAW_Mixed":   "x",
+		"OTHER":         "y",
+	}
+	d := NewDecoder(MapLookup(env))
+	cfg := MapConfig{}
+	errors := d.SetFrom("M_", &cfg)
+	if len(errors) != 0 || cfg.Labels != nil {
+		t.Errorf("expected maps untouched without Keys, got %v %+v", errors, cfg)
+	}
+	d.Keys = MapKeys(env)
+	d.Trace = &Trace{}
+	errors = d.SetFrom("M_", &cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	expected := MapConfig{
+		Name:   "n",
+		Labels: Labels{"team": "infra", "tier": "1"},
+		Raw:    map[string]string{"Mixed": "x"},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %+v got %+v", expected, cfg)
+	}
+	if e := d.Trace.Entries[1]; e.Field != "Labels[team]" || e.Names[0] != "M_LABELS_TEAM" {
+		t.Errorf("unexpected trace entry %+v", e)
+	}
+	// Round trip using the environ helpers.
+	kvl, _ := StructToEnvVars(expected)
+	environ := make([]string, 0, len(kvl))
+	for _, kv := range kvl {
+		environ = append(environ, kv.Key+"="+kv.Value)
+	}
+	d = NewDecoder(EnvironLookup(environ))
+	d.Keys = EnvironKeys(environ)
+	back := MapConfig{}
+	if errors = d.SetFrom("", &back); len(errors) != 0 || !reflect.DeepEqual(back, expected) {
+		t.Errorf("round trip mismatch %v %+v", errors, back)
+	}
+}
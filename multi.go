@@ -0,0 +1,76 @@
+package struct2env
+
+import (
+	"fmt"
+)
+
+// StructsToEnvVars converts several structs (for instance the option structs of the different
+// libraries a service is composed of) in one call, returning the combined list with the prefix
+// already applied to the keys (except for the noprefix ones). Keys collisions across the structs
+// are reported as errors and only the first occurrence is kept.
+func StructsToEnvVars(prefix string, structs ...interface{}) ([]KeyValue, []error) {
+	return NewEncoder().StructsToEnvVars(prefix, structs...)
+}
+
+// StructsToEnvVars is the multiple structs version of Encoder.StructToEnvVars, see the
+// StructsToEnvVars function.
+func (e *Encoder) StructsToEnvVars(prefix string, structs ...interface{}) ([]KeyValue, []error) {
+	var res []KeyValue
+	var allErrors []error
+	owners := make(map[string]int)
+	for i := 0; i < len(structs) && !e.tooManyErrors(allErrors); i++ {
+		s := structs[i]
+		kvl, errs := e.toEnvVars(s)
+		allErrors = append(allErrors, errs...)
+		for _, kv := range kvl {
+			kv.Key = kv.prefixedKey(prefix)
+			if err := checkCollision(owners, kv.Key, i, structs); err != nil {
+				allErrors = append(allErrors, err)
+				continue
+			}
+			res = append(res, kv)
+		}
+	}
+	return res, e.limitErrors(e.checkNames(allErrors, "", res))
+}
+
+// SetFromMulti is the reverse of StructsToEnvVars: sets the fields of all the passed structs pointers
+// from the lookup. Keys used by more than one struct are reported as errors (but still set in each).
+func SetFromMulti(envLookup EnvLookup, prefix string, structs ...interface{}) []error {
+	return NewDecoder(envLookup).SetFromMulti(prefix, structs...)
+}
+
+// SetFromMulti is the multiple structs version of Decoder.SetFrom, see the SetFromMulti function.
+func (d *Decoder) SetFromMulti(prefix string, structs ...interface{}) []error {
+	var allErrors []error
+	// Use an Encoder with the same options to find out the keys each struct uses (including the readonly fields).
+	keysFinder := Encoder{Options: d.Options, inputs: true, keepEmpty: true}
+	keysFinder.OmitEmpty = false // the structs are usually zero values.
+	keysFinder.OmitNilPointers = false
+	owners := make(map[string]int)
+	for i, s := range structs {
+		kvl, _ := keysFinder.StructToEnvVars(s) // errors will be reported by SetFrom below if relevant.
+		for _, kv := range kvl {
+			if err := checkCollision(owners, kv.prefixedKey(prefix), i, structs); err != nil {
+				allErrors = append(allErrors, err)
+			}
+		}
+	}
+	for _, s := range structs {
+		if d.tooManyErrors(allErrors) {
+			break
+		}
+		allErrors = append(allErrors, d.SetFrom(prefix, s)...)
+	}
+	return d.limitErrors(allErrors)
+}
+
+// checkCollision records that key is used by structs[idx] and returns an error if it was
+// already used by a previous struct.
+func checkCollision(owners map[string]int, key string, idx int, structs []interface{}) error {
+	if prev, found := owners[key]; found {
+		return fmt.Errorf("key %s of #%d (%T) collides with the one of #%d (%T)", key, idx, structs[idx], prev, structs[prev])
+	}
+	owners[key] = idx
+	return nil
+}
@@ -0,0 +1,91 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+type LibAOptions struct {
+	Timeout int
+	Name    string
+}
+
+type LibBOptions struct {
+	Retries int
+	Name    string
+}
+
+func TestStructsToEnvVars(t *testing.T) {
+	a := LibAOptions{Timeout: 3, Name: "a"}
+	b := LibBOptions{Retries: 2, Name: "b"}
+	kvl, errors := StructsToEnvVars("APP_", a, &b)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 collision error, got %v", errors)
+	} else if expected := "key APP_NAME of #1 (*struct2env.LibBOptions) collides with the one of #0 " +
+		"(struct2env.LibAOptions)"; errors[0].Error() != expected {
+		t.Errorf("unexpected error message %q", errors[0].Error())
+	}
+	str := ToShell(kvl)
+	expected := "APP_TIMEOUT='3'\nAPP_NAME='a'\nAPP_RETRIES='2'\nexport APP_TIMEOUT APP_NAME APP_RETRIES\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	envs := map[string]string{"APP_TIMEOUT": "5", "APP_RETRIES": "7", "APP_NAME": "x"}
+	lookup := func(key string) (string, bool) {
+		v, found := envs[key]
+		return v, found
+	}
+	a, b = LibAOptions{}, LibBOptions{}
+	errors = SetFromMulti(lookup, "APP_", &a, &b)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 collision error, got %v", errors)
+	}
+	if a.Timeout != 5 || b.Retries != 7 || a.Name != "x" || b.Name != "x" {
+		t.Errorf("unexpected decoding %+v %+v", a, b)
+	}
+	type Other struct {
+		Other bool
+	}
+	if errors = SetFromMulti(lookup, "APP_", &a, &Other{}); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+}
+
+func TestSetFromMultiReadOnly(t *testing.T) {
+	type Client struct {
+		Token string `env:",readonly"`
+	}
+	type Server struct {
+		Token string
+	}
+	lookup := MapLookup(map[string]string{"TOKEN": "t"})
+	c, srv := Client{}, Server{}
+	if errors := SetFromMulti(lookup, "", &c, &srv); len(errors) != 1 {
+		t.Errorf("expected 1 collision error, got %v", errors)
+	}
+	if c.Token != "t" || srv.Token != "t" {
+		t.Errorf("unexpected decoding %+v %+v", c, srv)
+	}
+}
+
+func TestSetFromMultiOmitted(t *testing.T) {
+	type A struct {
+		Name string `env:",omitempty"`
+	}
+	type B struct {
+		Name  string
+		Proxy *string
+	}
+	type C struct {
+		Proxy *string
+	}
+	lookup := MapLookup(map[string]string{"NAME": "n"})
+	if errors := SetFromMulti(lookup, "", &A{}, &B{}); len(errors) != 1 {
+		t.Errorf("expected 1 collision error, got %v", errors)
+	}
+	d := NewDecoder(lookup)
+	d.OmitEmpty = true
+	d.OmitNilPointers = true
+	if errors := d.SetFromMulti("", &B{}, &C{}); len(errors) != 1 {
+		t.Errorf("expected 1 collision error, got %v", errors)
+	}
+}
@@ -0,0 +1,40 @@
+package struct2env
+
+import (
+	"strings"
+)
+
+// Errors aggregates the errors returned by the conversions into a single error.
+type Errors []error
+
+// Error returns all the errors messages separated by "; ".
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors, for errors.Is and errors.As (go 1.20+).
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// MustStructToEnvVars is StructToEnvVars panicking with an Errors if there is any error.
+// For init time configuration or tests.
+func MustStructToEnvVars(s interface{}) []KeyValue {
+	kvl, errs := StructToEnvVars(s)
+	if len(errs) != 0 {
+		panic(Errors(errs))
+	}
+	return kvl
+}
+
+// MustSetFromEnv is SetFromEnv panicking with an Errors if there is any error.
+// For init time configuration or tests.
+func MustSetFromEnv(prefix string, s interface{}) {
+	if errs := SetFromEnv(prefix, s); len(errs) != 0 {
+		panic(Errors(errs))
+	}
+}
@@ -0,0 +1,51 @@
+package struct2env
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func expectPanic(t *testing.T, f func()) (errs Errors) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		var ok bool
+		if errs, ok = r.(Errors); !ok {
+			t.Errorf("expected an Errors panic, got %v", r)
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestMust(t *testing.T) {
+	type Cfg struct {
+		Port int
+		Name string
+	}
+	kvl := MustStructToEnvVars(Cfg{Port: 1})
+	if len(kvl) != 2 {
+		t.Errorf("unexpected %v", kvl)
+	}
+	errs := expectPanic(t, func() { MustStructToEnvVars(Cfg{Name: "a\x00"}) })
+	if len(errs) != 1 {
+		t.Errorf("unexpected %v", errs)
+	}
+	t.Setenv("MUST_PORT", "8080")
+	cfg := Cfg{}
+	MustSetFromEnv("MUST_", &cfg)
+	if cfg.Port != 8080 {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	t.Setenv("MUST_PORT", "x")
+	t.Setenv("MUST_NAME", "ok")
+	errs = expectPanic(t, func() { MustSetFromEnv("MUST_", &cfg) })
+	var numErr *strconv.NumError
+	if len(errs) != 1 || !errors.As(errs[0], &numErr) {
+		t.Errorf("expected a NumError, got %v", errs)
+	}
+	if e := (Errors{errors.New("a"), errors.New("b")}); e.Error() != "a; b" {
+		t.Errorf("unexpected message %q", e.Error())
+	}
+}
@@ -0,0 +1,73 @@
+package struct2env
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// NamePolicy restricts the environment variable names, as some platforms (busybox sh, some CI systems...)
+// reject long or exotic names. Set it in the Options to get violations reported when generating the names
+// instead of at runtime.
+type NamePolicy struct {
+	// MaxLen is the maximum length (in bytes) of a name, 0 for no limit.
+	MaxLen int
+	// ValidFirst reports whether r is allowed as first character of a name, nil allows any.
+	ValidFirst func(r rune) bool
+	// Valid reports whether r is allowed in the rest of the name, nil allows any.
+	Valid func(r rune) bool
+}
+
+// PortableNames is the POSIX portable names policy: letters, digits and underscores, not starting with a digit.
+var PortableNames = NamePolicy{ValidFirst: isPortableFirstRune, Valid: isPortableRune}
+
+// UpperPortableNames is like PortableNames but without lowercase letters.
+var UpperPortableNames = NamePolicy{
+	ValidFirst: func(r rune) bool { return r == '_' || (r >= 'A' && r <= 'Z') },
+	Valid:      func(r rune) bool { return r == '_' || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') },
+}
+
+func isPortableFirstRune(r rune) bool {
+	return r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+}
+
+func isPortableRune(r rune) bool {
+	return isPortableFirstRune(r) || (r >= '0' && r <= '9')
+}
+
+// Check returns an error describing why name isn't allowed by the policy, or nil if it is.
+func (p NamePolicy) Check(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty env name")
+	}
+	if p.MaxLen > 0 && len(name) > p.MaxLen {
+		return fmt.Errorf("env name %s is %d characters long, more than the maximum %d", name, len(name), p.MaxLen)
+	}
+	for i, r := range name {
+		valid := p.Valid
+		if i == 0 {
+			valid = p.ValidFirst
+		}
+		if r == utf8.RuneError || (valid != nil && !valid(r)) {
+			return fmt.Errorf("env name %q has invalid character %q at position %d", name, r, i)
+		}
+	}
+	return nil
+}
+
+// CheckNames returns the errors of the NamePolicy (if any is set) for the keys of kvl
+// with the output prefix applied (see ToShellWithPrefix).
+func (e *Encoder) CheckNames(prefix string, kvl []KeyValue) []error {
+	return e.checkNames(nil, prefix, kvl)
+}
+
+func (e *Encoder) checkNames(allErrors []error, prefix string, kvl []KeyValue) []error {
+	if e.NamePolicy == nil {
+		return allErrors
+	}
+	for _, kv := range kvl {
+		if err := e.NamePolicy.Check(kv.prefixedKey(prefix)); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+	return allErrors
+}
@@ -0,0 +1,69 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestNamePolicyCheck(t *testing.T) {
+	limited := PortableNames
+	limited.MaxLen = 8
+	tests := []struct {
+		policy NamePolicy
+		name   string
+		valid  bool
+	}{
+		{PortableNames, "FOO_BAR1", true},
+		{PortableNames, "_foo", true},
+		{PortableNames, "1FOO", false},
+		{PortableNames, "FOO-BAR", false},
+		{PortableNames, "FOO.BAR", false},
+		{PortableNames, "", false},
+		{PortableNames, "ÉTÉ", false},
+		{UpperPortableNames, "FOO_1", true},
+		{UpperPortableNames, "Foo", false},
+		{limited, "ABCDEFGH", true},
+		{limited, "ABCDEFGHI", false},
+		{NamePolicy{}, "any.thing-goes", true},
+		{NamePolicy{}, "bad\xffutf8", false},
+	}
+	for _, test := range tests {
+		err := test.policy.Check(test.name)
+		if (err == nil) != test.valid {
+			t.Errorf("for %q expected valid %v, got %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestNamePolicyEncoder(t *testing.T) {
+	type Inner struct {
+		VeryLongFieldName string
+	}
+	type Cfg struct {
+		Short string
+		Bad   string `env:"BAD-NAME"`
+		Inner Inner
+	}
+	e := NewEncoder()
+	kvl, errors := e.StructToEnvVars(Cfg{})
+	if len(errors) != 0 || len(kvl) != 3 {
+		t.Fatalf("unexpected %v %v", kvl, errors)
+	}
+	policy := UpperPortableNames
+	policy.MaxLen = 16
+	e.NamePolicy = &policy
+	kvl, errors = e.StructToEnvVars(Cfg{})
+	if len(kvl) != 3 {
+		t.Errorf("names violating the policy should still be returned, got %v", kvl)
+	}
+	if len(errors) != 2 {
+		t.Errorf("expected 2 errors, got %v", errors)
+	}
+	errors = e.CheckNames("A_LONGER_PREFIX_", kvl)
+	if len(errors) != 3 {
+		t.Errorf("expected 3 errors with the prefix, got %v", errors)
+	}
+	_, errors = e.StructsToEnvVars("P_", Cfg{})
+	if len(errors) != 2 {
+		t.Errorf("expected 2 errors for multi, got %v", errors)
+	}
+}
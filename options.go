@@ -0,0 +1,145 @@
+package struct2env
+
+import (
+	"time"
+)
+
+// Option changes one of the Options, for StructToEnvVarsWithOptions and SetFromWithOptions.
+// New features add Options fields and matching Option functions instead of new variants of
+// the top level functions.
+type Option func(o *Options)
+
+// Apply applies the opts, in order, to o.
+func (o *Options) Apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// StructToEnvVarsWithOptions is StructToEnvVars with the given options, e.g.
+//
+//	kvl, errs := StructToEnvVarsWithOptions(cfg, WithKeyMapper(DotKeys), WithOmitEmpty())
+func StructToEnvVarsWithOptions(s interface{}, opts ...Option) ([]KeyValue, []error) {
+	e := NewEncoder()
+	e.Apply(opts...)
+	return e.StructToEnvVars(s)
+}
+
+// SetFromWithOptions is SetFrom with the given options, which must match the ones used to
+// produce the variables (e.g. the same WithKeyMapper).
+func SetFromWithOptions(envLookup EnvLookup, prefix string, s interface{}, opts ...Option) []error {
+	d := NewDecoder(envLookup)
+	d.Apply(opts...)
+	return d.SetFrom(prefix, s)
+}
+
+// WithOptions replaces all the options by o, to start from a shared base before the next Options.
+func WithOptions(base Options) Option {
+	return func(o *Options) {
+		*o = base
+	}
+}
+
+// WithKeyMapper sets the KeyMapper (naming strategy and separator), e.g. DotKeys.
+func WithKeyMapper(m KeyMapper) Option {
+	return func(o *Options) {
+		o.KeyMapper = m
+	}
+}
+
+// WithNaming changes how the field names are converted into key segments (e.g. CamelCaseToLowerSnakeCase),
+// keeping the current separator.
+func WithNaming(field func(fieldName string) string) Option {
+	return func(o *Options) {
+		m := o.keyMapper()
+		m.Field = field
+		o.KeyMapper = m
+	}
+}
+
+// WithSeparator changes the separator of the segments of nested structs' keys, keeping the current naming.
+func WithSeparator(sep string) Option {
+	return func(o *Options) {
+		m := o.keyMapper()
+		m.Sep = sep
+		o.KeyMapper = m
+	}
+}
+
+// WithOmitEmpty sets OmitEmpty: the Encoder skips all the fields with their zero value.
+func WithOmitEmpty() Option {
+	return func(o *Options) {
+		o.OmitEmpty = true
+	}
+}
+
+// WithStrictTags sets StrictTags: unknown and misused env tag options are errors.
+func WithStrictTags() Option {
+	return func(o *Options) {
+		o.StrictTags = true
+	}
+}
+
+// WithMaxErrors sets MaxErrors, 1 for fail-fast.
+func WithMaxErrors(n int) Option {
+	return func(o *Options) {
+		o.MaxErrors = n
+	}
+}
+
+// WithLenientBool sets LenientBool: bool fields also accept yes/no, on/off and enabled/disabled.
+func WithLenientBool() Option {
+	return func(o *Options) {
+		o.LenientBool = true
+	}
+}
+
+// WithAutoPrefix sets AutoPrefix and TrimTypeSuffix: the prefix is derived from the struct type name.
+func WithAutoPrefix(trimTypeSuffix bool) Option {
+	return func(o *Options) {
+		o.AutoPrefix = true
+		o.TrimTypeSuffix = trimTypeSuffix
+	}
+}
+
+// WithTimeLayouts sets the TimeLayouts, the first one being used by the Encoder.
+func WithTimeLayouts(layouts ...string) Option {
+	return func(o *Options) {
+		o.TimeLayouts = layouts
+	}
+}
+
+// WithTimeUTC sets TimeUTC: the Encoder converts the time.Time values to UTC.
+func WithTimeUTC() Option {
+	return func(o *Options) {
+		o.TimeUTC = true
+	}
+}
+
+// WithTimeLocation sets the TimeLocation the Decoder uses for the time values without zone.
+func WithTimeLocation(loc *time.Location) Option {
+	return func(o *Options) {
+		o.TimeLocation = loc
+	}
+}
+
+// WithDurationFormat sets the DurationFormat.
+func WithDurationFormat(f DurationFormat) Option {
+	return func(o *Options) {
+		o.DurationFormat = f
+	}
+}
+
+// WithDurationPrecision sets the DurationPrecision, 9 for lossless nanoseconds.
+func WithDurationPrecision(decimals int) Option {
+	return func(o *Options) {
+		o.DurationPrecision = decimals
+	}
+}
+
+// WithReservedNames sets the ReservedNames, e.g. DefaultReservedNames.
+func WithReservedNames(names ...string) Option {
+	return func(o *Options) {
+		o.ReservedNames = names
+	}
+}
@@ -0,0 +1,61 @@
+package struct2env
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithOptions(t *testing.T) {
+	type Server struct {
+		HTTPPort int
+		Timeout  time.Duration
+	}
+	type Cfg struct {
+		Name   string
+		Debug  bool
+		Server Server
+	}
+	cfg := Cfg{Name: "app", Server: Server{HTTPPort: 8080, Timeout: time.Second}}
+	opts := []Option{WithNaming(CamelCaseToLowerSnakeCase), WithSeparator("."), WithOmitEmpty(),
+		WithDurationFormat(DurationString)}
+	kvl, errs := StructToEnvVarsWithOptions(cfg, opts...)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := "name='app'\nserver.http_port='8080'\nserver.timeout='1s'\n"
+	if str != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", str, expected)
+	}
+	env := map[string]string{"name": "other", "debug": "yes", "server.timeout": "1m"}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	var got Cfg
+	errs = SetFromWithOptions(lookup, "", &got, append(opts, WithLenientBool())...)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if got.Name != "other" || !got.Debug || got.Server.Timeout != time.Minute {
+		t.Errorf("unexpected %+v", got)
+	}
+	// Without WithLenientBool "yes" isn't a bool.
+	if errs = SetFromWithOptions(lookup, "", &got, opts...); len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}
+
+func TestOptionsApply(t *testing.T) {
+	var o Options
+	o.Apply(WithSeparator("/"), WithStrictTags(), WithMaxErrors(1), WithAutoPrefix(true),
+		WithReservedNames("PATH"), WithTimeLayouts(time.RFC1123))
+	if o.KeyMapper.Sep != "/" || o.KeyMapper.Field == nil || !o.StrictTags || o.MaxErrors != 1 ||
+		!o.AutoPrefix || !o.TrimTypeSuffix || len(o.ReservedNames) != 1 || o.TimeLayouts[0] != time.RFC1123 {
+		t.Errorf("unexpected %+v", o)
+	}
+	o.Apply(WithOptions(Options{LenientBool: true}), WithKeyMapper(DotKeys))
+	if !o.LenientBool || o.StrictTags || o.KeyMapper.Sep != "." {
+		t.Errorf("unexpected after WithOptions %+v", o)
+	}
+}
@@ -0,0 +1,66 @@
+package struct2env
+
+import (
+	"sort"
+)
+
+// appendIndex returns a new index path: index followed by i (index is never modified so it
+// can be shared by the siblings).
+func appendIndex(index []int, i int) []int {
+	res := make([]int, len(index)+1)
+	copy(res, index)
+	res[len(index)] = i
+	return res
+}
+
+// indexPaths hands out the index paths of the fields of a struct, sharing a single allocation.
+type indexPaths struct {
+	parent []int // Index path of the struct.
+	block  []int
+}
+
+func newIndexPaths(parent []int, numFields int) indexPaths {
+	return indexPaths{parent: parent, block: make([]int, (len(parent)+1)*numFields)}
+}
+
+// field returns the index path of the field i.
+func (p indexPaths) field(i int) []int {
+	n := len(p.parent) + 1
+	res := p.block[i*n : (i+1)*n : (i+1)*n]
+	copy(res, p.parent)
+	res[n-1] = i
+	return res
+}
+
+// CompareKeyValues orders a and b by their struct position (see KeyValue.Index), then by key:
+// returns -1 if a comes first, 1 if b does and 0 if they have the same position and key.
+// Entries from different structs (e.g. several StructToEnvVars results merged) are interleaved
+// by position so sort those lists separately or use StructsToEnvVars instead.
+func CompareKeyValues(a, b KeyValue) int {
+	for i := 0; i < len(a.Index) && i < len(b.Index); i++ {
+		switch {
+		case a.Index[i] < b.Index[i]:
+			return -1
+		case a.Index[i] > b.Index[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(a.Index) < len(b.Index):
+		return -1
+	case len(a.Index) > len(b.Index):
+		return 1
+	case a.Key < b.Key:
+		return -1
+	case a.Key > b.Key:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SortKeyValues sorts kvl in struct order (see CompareKeyValues), e.g. after merging or filtering
+// lists, to get back the StructToEnvVars order.
+func SortKeyValues(kvl []KeyValue) {
+	sort.SliceStable(kvl, func(i, j int) bool { return CompareKeyValues(kvl[i], kvl[j]) < 0 })
+}
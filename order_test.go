@@ -0,0 +1,45 @@
+package struct2env
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type orderInner struct {
+	X, Y int
+}
+
+type orderConfig struct {
+	A string
+	orderInner
+	Labels map[string]string
+	B      orderInner
+	C      string
+}
+
+func (orderConfig) EnvValues() map[string]string {
+	return map[string]string{"EXTRA": "e"}
+}
+
+func TestSortKeyValues(t *testing.T) {
+	kvl, errs := StructToEnvVars(orderConfig{Labels: map[string]string{"b": "2", "a": "1"}})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	expected := ToShell(kvl)
+	if len(kvl[1].Index) != 2 || kvl[1].Index[0] != 1 || kvl[1].Index[1] != 0 {
+		t.Errorf("unexpected index for %v: %v", kvl[1], kvl[1].Index)
+	}
+	shuffled := append([]KeyValue(nil), kvl...)
+	rand.New(rand.NewSource(42)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	SortKeyValues(shuffled)
+	if str := ToShell(shuffled); str != expected {
+		t.Errorf("got %q expected %q", str, expected)
+	}
+	if CompareKeyValues(kvl[3], kvl[4]) != -1 || CompareKeyValues(kvl[4], kvl[3]) != 1 ||
+		CompareKeyValues(kvl[0], kvl[0]) != 0 {
+		t.Errorf("unexpected comparisons of %v %v", kvl[3], kvl[4])
+	}
+}
@@ -0,0 +1,74 @@
+package struct2env
+
+import (
+	"os"
+)
+
+// Snapshot holds the values the variables changed by SetOSEnv had before, so they can be restored.
+type Snapshot struct {
+	saved []savedVar
+}
+
+type savedVar struct {
+	key   string
+	value string
+	found bool
+}
+
+// SetOSEnv sets the os environment variables from the fields of s (struct or pointer to struct), with
+// prefix prepended to the keys (except the noprefix ones), see StructToEnvVars. Nil pointers unset the
+// variable. The returned Snapshot restores the previous environment, for scoped changes in tests or
+// plugin hosts; it covers what was applied even when there are errors.
+func SetOSEnv(prefix string, s interface{}) (*Snapshot, []error) {
+	return NewEncoder().SetOSEnv(prefix, s)
+}
+
+// SetOSEnv is SetOSEnv using the Encoder's options.
+func (e *Encoder) SetOSEnv(prefix string, s interface{}) (*Snapshot, []error) {
+	kvl, allErrors := e.StructToEnvVars(s)
+	snapshot, err := SetOSEnvVars(prefix, kvl)
+	if err != nil {
+		allErrors = append(allErrors, err)
+	}
+	return snapshot, allErrors
+}
+
+// SetOSEnvVars sets the os environment variables from kvl, see SetOSEnv. Stops at the first error.
+func SetOSEnvVars(prefix string, kvl []KeyValue) (*Snapshot, error) {
+	snapshot := &Snapshot{saved: make([]savedVar, 0, len(kvl))}
+	for _, kv := range kvl {
+		key := kv.prefixedKey(prefix)
+		value, found := os.LookupEnv(key)
+		snapshot.saved = append(snapshot.saved, savedVar{key: key, value: value, found: found})
+		var err error
+		if kv.YamlQuotedVal == "null" {
+			err = os.Unsetenv(key)
+		} else {
+			err = os.Setenv(key, kv.Value)
+		}
+		if err != nil {
+			return snapshot, err
+		}
+	}
+	return snapshot, nil
+}
+
+// Restore sets back the variables to the values they had (or unsets them if they didn't exist) before
+// the SetOSEnv call. Calling it again is a no-op. Returns the first error encountered.
+func (s *Snapshot) Restore() error {
+	var firstErr error
+	for i := len(s.saved) - 1; i >= 0; i-- {
+		v := s.saved[i]
+		var err error
+		if v.found {
+			err = os.Setenv(v.key, v.value)
+		} else {
+			err = os.Unsetenv(v.key)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.saved = nil
+	return firstErr
+}
@@ -0,0 +1,53 @@
+package struct2env
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetOSEnv(t *testing.T) {
+	type Cfg struct {
+		Name  string
+		Port  int
+		Ptr   *int
+		Extra string `env:"OSENV_GLOBAL,noprefix"`
+	}
+	t.Setenv("OSENV_NAME", "before")
+	t.Setenv("OSENV_PTR", "42")
+	os.Unsetenv("OSENV_PORT")
+	snapshot, errs := SetOSEnv("OSENV_", Cfg{Name: "during", Port: 8080, Extra: "x"})
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if os.Getenv("OSENV_NAME") != "during" || os.Getenv("OSENV_PORT") != "8080" || os.Getenv("OSENV_GLOBAL") != "x" {
+		t.Errorf("variables not set: %v", os.Environ())
+	}
+	if _, found := os.LookupEnv("OSENV_PTR"); found {
+		t.Errorf("nil pointer should unset the variable")
+	}
+	cfg := Cfg{}
+	SetFromEnv("OSENV_", &cfg)
+	if cfg.Port != 8080 {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if err := snapshot.Restore(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if os.Getenv("OSENV_NAME") != "before" || os.Getenv("OSENV_PTR") != "42" {
+		t.Errorf("variables not restored")
+	}
+	for _, key := range []string{"OSENV_PORT", "OSENV_GLOBAL"} {
+		if _, found := os.LookupEnv(key); found {
+			t.Errorf("%s should have been unset", key)
+		}
+	}
+	os.Setenv("OSENV_NAME", "after")
+	defer os.Unsetenv("OSENV_NAME")
+	if err := snapshot.Restore(); err != nil || os.Getenv("OSENV_NAME") != "after" {
+		t.Errorf("second Restore should be a no-op")
+	}
+	snapshot, errs = SetOSEnv("OSENV_", 42)
+	if len(errs) != 1 || snapshot.Restore() != nil {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}
@@ -0,0 +1,71 @@
+package struct2env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OverridesSource is the Trace source name of the values coming from the overrides, see SetFromWithOverrides.
+const OverridesSource = "overrides"
+
+// SetFromWithOverrides is SetFrom where the overrides (full variable names, prefix included, to values; e.g. from
+// `--set KEY=VAL` command line flags, see ParseOverrides) win over envLookup, so tools can inject one-off values
+// without mutating the process environment. Overrides not matching any of the variables of s are reported as
+// errors, to catch typos.
+func SetFromWithOverrides(envLookup EnvLookup, prefix string, overrides map[string]string, s interface{}) []error {
+	return NewDecoder(envLookup).SetFromWithOverrides(prefix, overrides, s)
+}
+
+// SetFromWithOverrides is the Decoder version of SetFromWithOverrides: the overrides are tried before the
+// Decoder's Sources (or Lookup) and their keys added to its Keys for the map fields (so overrides of map
+// entries apply even without Keys).
+func (d *Decoder) SetFromWithOverrides(prefix string, overrides map[string]string, s interface{}) []error {
+	used := make(map[string]bool, len(overrides))
+	overridesLookup := func(key string) (string, bool) {
+		v, found := overrides[key]
+		if found {
+			used[key] = true
+		}
+		return v, found
+	}
+	od := *d
+	od.Sources = []NamedLookup{{OverridesSource, overridesLookup}}
+	if len(d.Sources) == 0 {
+		od.Sources = append(od.Sources, NamedLookup{"", d.Lookup})
+	} else {
+		od.Sources = append(od.Sources, d.Sources...)
+	}
+	od.Keys = func() []string {
+		keys := MapKeys(overrides)()
+		if d.Keys == nil {
+			return keys
+		}
+		for _, key := range d.Keys() {
+			if _, found := overrides[key]; !found {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	allErrors := od.SetFrom(prefix, s)
+	for _, key := range MapKeys(overrides)() {
+		if !used[key] {
+			allErrors = append(allErrors, fmt.Errorf("override %s doesn't match any variable", key))
+		}
+	}
+	return allErrors
+}
+
+// ParseOverrides converts a list of KEY=VAL strings (e.g. repeated `--set` flags) to an overrides map
+// for SetFromWithOverrides. Later entries win; entries without = or with an empty key are errors.
+func ParseOverrides(args []string) (map[string]string, error) {
+	res := make(map[string]string, len(args))
+	for _, arg := range args {
+		idx := strings.IndexByte(arg, '=')
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid override %q, expecting KEY=VAL", arg)
+		}
+		res[arg[:idx]] = arg[idx+1:]
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestSetFromWithOverrides(t *testing.T) {
+	type Cfg struct {
+		Name   string
+		Port   int
+		Labels map[string]string
+	}
+	env := map[string]string{"APP_NAME": "env", "APP_PORT": "80", "APP_LABELS_A": "1"}
+	overrides, err := ParseOverrides([]string{"APP_PORT=8080", "APP_LABELS_B=2=3", "APP_PROT=1"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cfg := Cfg{}
+	errs := SetFromWithOverrides(MapLookup(env), "APP_", overrides, &cfg)
+	if len(errs) != 1 || errs[0].Error() != "override APP_PROT doesn't match any variable" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	// Without Keys only the overridden map entries are known.
+	if cfg.Name != "env" || cfg.Port != 8080 || len(cfg.Labels) != 1 || cfg.Labels["b"] != "2=3" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	d := NewDecoder(MapLookup(env))
+	d.Keys = MapKeys(env)
+	d.Trace = &Trace{}
+	delete(overrides, "APP_PROT")
+	cfg = Cfg{}
+	if errs = d.SetFromWithOverrides("APP_", overrides, &cfg); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if cfg.Port != 8080 || cfg.Labels["a"] != "1" || cfg.Labels["b"] != "2=3" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	if e := d.Trace.Entries[1]; e.Source != OverridesSource || e.Value != "8080" {
+		t.Errorf("unexpected trace entry %+v", e)
+	}
+	if env["APP_PORT"] != "80" {
+		t.Errorf("lookup source shouldn't be changed")
+	}
+	for _, bad := range []string{"APP_PORT", "=1"} {
+		if _, err = ParseOverrides([]string{bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+module fortio.org/struct2env/protoenv
+
+// Separate module so the protobuf adapter stays optional for struct2env users.
+go 1.17
+
+require fortio.org/struct2env v0.4.0
+
+replace fortio.org/struct2env => ..
@@ -0,0 +1,264 @@
+// Package protoenv adapts struct2env to protobuf generated (config) messages: the keys are derived from
+// the proto field names (http_port gives HTTP_PORT) instead of the Go ones, nested messages are recursed
+// into and the generated internal fields are ignored. Enums are represented by their numbers.
+//
+// It works through reflection on the `protobuf:"..."` struct tags and doesn't depend on the protobuf
+// module. Repeated scalar fields are handled like the corresponding Go slices by struct2env ([]string
+// and numbers as separated lists, bytes as base64). Oneof, repeated message and recursive message
+// fields are not supported and are skipped.
+//
+// It is its own module (fortio.org/struct2env/protoenv), optional for the struct2env users.
+package protoenv
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"fortio.org/struct2env"
+)
+
+// StructToEnvVars converts msg (pointer to a protobuf generated struct) to a list of KeyValue,
+// like struct2env.StructToEnvVars does for regular structs. Nil nested messages produce no entries.
+func StructToEnvVars(msg interface{}) ([]struct2env.KeyValue, []error) {
+	return Encode(struct2env.NewEncoder(), msg)
+}
+
+// Encode is StructToEnvVars using the Options of e (KeyMapper and AutoPrefix excepted).
+func Encode(e *struct2env.Encoder, msg interface{}) ([]struct2env.KeyValue, []error) {
+	enc := *e
+	enc.AutoPrefix = false
+	v, err := messageValue(msg)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return encode(&enc, v)
+}
+
+// SetFromEnv sets the fields of msg from the os environment variables, see SetFrom.
+func SetFromEnv(prefix string, msg interface{}) []error {
+	d := struct2env.NewDecoder(os.LookupEnv)
+	d.Keys = struct2env.OSKeys
+	return Decode(d, prefix, msg)
+}
+
+// SetFrom is the reverse of StructToEnvVars: sets the fields of msg found through envLookup.
+// Nested messages are only allocated when at least one of their fields is found.
+func SetFrom(envLookup struct2env.EnvLookup, prefix string, msg interface{}) []error {
+	return Decode(struct2env.NewDecoder(envLookup), prefix, msg)
+}
+
+// Decode is SetFrom using the given Decoder (its Lookup, Keys, Options...; AutoPrefix excepted).
+func Decode(d *struct2env.Decoder, prefix string, msg interface{}) []error {
+	dec := *d
+	dec.AutoPrefix = false
+	v, err := messageValue(msg)
+	if err != nil {
+		return []error{err}
+	}
+	return decode(&dec, prefix, v)
+}
+
+func messageValue(msg interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(msg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return v, fmt.Errorf("unexpected %T, expected a pointer to a protobuf message struct", msg)
+	}
+	return v.Elem(), nil
+}
+
+// protoField is a field of a generated message with its env name.
+type protoField struct {
+	index   int
+	name    string // UPPER_SNAKE of the proto name.
+	isEnum  bool
+	message bool // pointer to a nested message.
+}
+
+// protoFields returns the fields of the message struct type t having a protobuf tag (so
+// skipping the internal state ones and oneofs).
+func protoFields(t reflect.Type) []protoField {
+	var res []protoField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, found := f.Tag.Lookup("protobuf")
+		if !found {
+			continue
+		}
+		pf := protoField{index: i}
+		for _, part := range strings.Split(tag, ",") {
+			switch {
+			case strings.HasPrefix(part, "name="):
+				pf.name = strings.ToUpper(part[len("name="):])
+			case strings.HasPrefix(part, "enum="):
+				pf.isEnum = true
+			}
+		}
+		if pf.name == "" {
+			pf.name = struct2env.CamelCaseToUpperSnakeCase(f.Name)
+		}
+		pf.message = f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct
+		res = append(res, pf)
+	}
+	return res
+}
+
+// leafType is the type used to encode/decode a (non message) field: enums are converted
+// to their underlying integer type so they don't use their String() name.
+func leafType(pf protoField, t reflect.Type) reflect.Type {
+	if !pf.isEnum {
+		return t
+	}
+	switch t.Kind() { //nolint: exhaustive // enums are only scalars, optional or repeated.
+	case reflect.Ptr: // proto2 or proto3 optional enum
+		return reflect.PtrTo(reflect.TypeOf(int32(0)))
+	case reflect.Slice: // repeated enum
+		return reflect.SliceOf(reflect.TypeOf(int32(0)))
+	default:
+		return reflect.TypeOf(int32(0))
+	}
+}
+
+// leaf is a non message field of a message or of its nested messages.
+type leaf struct {
+	path []int // indexes of the nested message fields leading to the field, then of the field itself.
+	key  string
+	t    reflect.Type // type of the holder field, see holderType.
+}
+
+// leaves appends the non message fields of the message struct type t and of its nested messages,
+// depth first. Recursive message fields (of a type being expanded) are skipped.
+func leaves(res []leaf, path []int, prefix string, t reflect.Type, seen map[reflect.Type]bool) []leaf {
+	seen[t] = true
+	for _, pf := range protoFields(t) {
+		fieldPath := append(append([]int(nil), path...), pf.index)
+		ft := t.Field(pf.index).Type
+		if pf.message {
+			if !seen[ft.Elem()] {
+				res = leaves(res, fieldPath, prefix+pf.name+"_", ft.Elem(), seen)
+			}
+			continue
+		}
+		ht := leafType(pf, ft)
+		switch ht.Kind() { //nolint: exhaustive // only the nil-able kinds are kept as is.
+		case reflect.Map, reflect.Ptr, reflect.Slice:
+		default:
+			ht = reflect.PtrTo(ht) // so we know whether the value was found.
+		}
+		res = append(res, leaf{path: fieldPath, key: prefix + pf.name, t: ht})
+	}
+	delete(seen, t)
+	return res
+}
+
+// holderType returns a struct type with one field, of the given type and env tag, per leaf. It is how
+// the core struct2env conversions are reused for a whole message, in a single StructToEnvVars or SetFrom
+// call (so the contract hash, schema and required checks happen once). It only depends on the message
+// type, so encoding and decoding agree on the ContractHash.
+func holderType(lvs []leaf) reflect.Type {
+	fields := make([]reflect.StructField, 0, len(lvs))
+	for i, l := range lvs {
+		fields = append(fields, reflect.StructField{
+			Name: "F" + strconv.Itoa(i), Type: l.t, Tag: reflect.StructTag(`env:"` + l.key + `"`),
+		})
+	}
+	return reflect.StructOf(fields)
+}
+
+// leafField returns the field at path of the message v. The nil nested messages along the path are
+// allocated when alloc is true, otherwise false is returned for them.
+func leafField(v reflect.Value, path []int, alloc bool) (reflect.Value, bool) {
+	for _, i := range path[:len(path)-1] {
+		msg := v.Field(i)
+		if msg.IsNil() {
+			if !alloc {
+				return msg, false
+			}
+			msg.Set(reflect.New(msg.Type().Elem()))
+		}
+		v = msg.Elem()
+	}
+	return v.Field(path[len(path)-1]), true
+}
+
+// assign sets dst from src, converting between the message and holder fields types: enums and their
+// underlying integers, values and pointers to them, slices of those.
+func assign(dst, src reflect.Value) {
+	switch {
+	case src.Kind() == reflect.Ptr && dst.Kind() != reflect.Ptr:
+		assign(dst, src.Elem())
+	case dst.Kind() == reflect.Ptr:
+		if src.Kind() == reflect.Ptr {
+			if src.IsNil() {
+				dst.Set(reflect.Zero(dst.Type()))
+				return
+			}
+			src = src.Elem()
+		}
+		ptr := reflect.New(dst.Type().Elem())
+		assign(ptr.Elem(), src)
+		dst.Set(ptr)
+	case dst.Kind() == reflect.Slice && dst.Type().Elem() != src.Type().Elem():
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		res := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			res.Index(i).Set(src.Index(i).Convert(dst.Type().Elem()))
+		}
+		dst.Set(res)
+	default:
+		dst.Set(src.Convert(dst.Type()))
+	}
+}
+
+func encode(e *struct2env.Encoder, v reflect.Value) ([]struct2env.KeyValue, []error) {
+	lvs := leaves(nil, nil, "", v.Type(), map[reflect.Type]bool{})
+	h := reflect.New(holderType(lvs))
+	absent := map[string]bool{}
+	for i, l := range lvs {
+		fieldValue, present := leafField(v, l.path, false)
+		if !present || (e.OmitEmpty && fieldValue.IsZero()) {
+			absent[l.key] = true // in a nil message: produces no entries.
+			continue
+		}
+		assign(h.Elem().Field(i), fieldValue)
+	}
+	kvl, allErrors := e.StructToEnvVars(h.Interface())
+	envVars := make([]struct2env.KeyValue, 0, len(kvl))
+	for _, kv := range kvl {
+		if !absent[kv.Key] {
+			envVars = append(envVars, kv)
+		}
+	}
+	return envVars, allErrors
+}
+
+// decode sets the fields found, allocating the nested messages only when one of their fields is.
+func decode(d *struct2env.Decoder, prefix string, v reflect.Value) []error {
+	lvs := leaves(nil, nil, "", v.Type(), map[reflect.Type]bool{})
+	h := reflect.New(holderType(lvs))
+	allErrors := d.SetFrom(prefix, h.Interface())
+	for i, l := range lvs {
+		res := h.Elem().Field(i)
+		if res.IsNil() {
+			continue
+		}
+		fieldValue, _ := leafField(v, l.path, true)
+		if res.Kind() != reflect.Map {
+			assign(fieldValue, res)
+			continue
+		}
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.MakeMap(fieldValue.Type()))
+		}
+		iter := res.MapRange()
+		for iter.Next() {
+			fieldValue.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+	return allErrors
+}
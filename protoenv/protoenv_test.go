@@ -0,0 +1,205 @@
+package protoenv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"fortio.org/struct2env"
+)
+
+// Hand written equivalents of protoc-gen-go output (minus the methods and json tags), to not depend on protobuf.
+
+type Level int32
+
+const (
+	Level_INFO  Level = 0 //nolint:revive,stylecheck // generated style.
+	Level_DEBUG Level = 1 //nolint:revive,stylecheck // generated style.
+)
+
+func (l Level) String() string {
+	if l == Level_DEBUG {
+		return "DEBUG"
+	}
+	return "INFO"
+}
+
+type messageState struct {
+	mu chan int //nolint:unused // mimics the internal state.
+}
+
+type TLS struct {
+	state         messageState
+	sizeCache     int32
+	unknownFields []byte
+
+	CertFile string `protobuf:"bytes,1,opt,name=cert_file,json=certFile,proto3"`
+}
+
+type ServerConfig struct {
+	state         messageState
+	sizeCache     int32
+	unknownFields []byte
+
+	HttpPort  int32             `protobuf:"varint,1,opt,name=http_port,json=httpPort,proto3"`
+	Http2Name string            `protobuf:"bytes,2,opt,name=http2_name,json=http2Name,proto3"`
+	Level     Level             `protobuf:"varint,3,opt,name=level,proto3,enum=test.Level"`
+	Tls       *TLS              `protobuf:"bytes,4,opt,name=tls,proto3"`
+	Labels    map[string]string `protobuf:"bytes,5,rep,name=labels,proto3"`
+	Debug     *bool             `protobuf:"varint,6,opt,name=debug,proto3,oneof"`
+	Blob      []byte            `protobuf:"bytes,7,opt,name=blob,proto3"`
+	Timeout   int64             `protobuf:"varint,8,opt,name=timeout,proto3"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	debug := true
+	in := &ServerConfig{
+		HttpPort:  8080,
+		Http2Name: "h2",
+		Level:     Level_DEBUG,
+		Tls:       &TLS{CertFile: "/etc/cert.pem"},
+		Labels:    map[string]string{"team": "infra"},
+		Debug:     &debug,
+		Blob:      []byte{0, 1, 2},
+		Timeout:   int64(time.Second),
+	}
+	kvl, errors := StructToEnvVars(in)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := struct2env.ToShellWithPrefix("P_", kvl, true)
+	expected := `P_HTTP_PORT='8080'
+P_HTTP2_NAME='h2'
+P_LEVEL='1'
+P_TLS_CERT_FILE='/etc/cert.pem'
+P_LABELS_TEAM='infra'
+P_DEBUG=true
+P_BLOB='AAEC'
+P_TIMEOUT='1000000000'
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	env := map[string]string{}
+	for _, kv := range kvl {
+		env["P_"+kv.Key] = kv.Value
+	}
+	d := struct2env.NewDecoder(struct2env.MapLookup(env))
+	d.Keys = struct2env.MapKeys(env)
+	out := &ServerConfig{}
+	if errors = Decode(d, "P_", out); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch\n%+v\n%+v", in, out)
+	}
+}
+
+func TestNestedPresence(t *testing.T) {
+	out := &ServerConfig{}
+	errors := SetFrom(struct2env.MapLookup(map[string]string{"HTTP_PORT": "x"}), "", out)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 parse error, got %v", errors)
+	}
+	if out.Tls != nil || out.Debug != nil {
+		t.Errorf("absent message/optional should stay nil: %+v", out)
+	}
+	kvl, _ := StructToEnvVars(out)
+	for _, kv := range kvl {
+		if kv.Key == "TLS_CERT_FILE" {
+			t.Errorf("nil message should not produce entries: %v", kvl)
+		}
+	}
+	existing := &ServerConfig{Tls: &TLS{CertFile: "keep"}}
+	errors = SetFrom(struct2env.MapLookup(map[string]string{"LEVEL": "1"}), "", existing)
+	if len(errors) != 0 || existing.Tls.CertFile != "keep" || existing.Level != Level_DEBUG {
+		t.Errorf("unexpected %v %+v", errors, existing)
+	}
+	if errors = SetFrom(struct2env.MapLookup(nil), "", ServerConfig{}); len(errors) != 1 {
+		t.Errorf("expected error for non pointer, got %v", errors)
+	}
+}
+
+type Node struct {
+	state         messageState
+	sizeCache     int32
+	unknownFields []byte
+
+	Name   string   `protobuf:"bytes,1,opt,name=name,proto3"`
+	Parent *Node    `protobuf:"bytes,2,opt,name=parent,proto3"`
+	Hosts  []string `protobuf:"bytes,3,rep,name=hosts,proto3"`
+	Ports  []int32  `protobuf:"varint,4,rep,packed,name=ports,proto3"`
+	Levels []Level  `protobuf:"varint,5,rep,packed,name=levels,proto3,enum=test.Level"`
+	Peers  []*TLS   `protobuf:"bytes,6,rep,name=peers,proto3"`
+	Tls    *TLS     `protobuf:"bytes,7,opt,name=tls,proto3"`
+}
+
+func TestRepeated(t *testing.T) {
+	in := &Node{
+		Name:   "n",
+		Hosts:  []string{"a", "b"},
+		Ports:  []int32{80, 443},
+		Levels: []Level{Level_DEBUG, Level_INFO},
+		Peers:  []*TLS{{CertFile: "x"}},
+	}
+	kvl, errors := StructToEnvVars(in)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := struct2env.ToShellWithPrefix("", kvl, true)
+	expected := "NAME='n'\nHOSTS='a,b'\nPORTS='80,443'\nLEVELS='1,0'\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	env := map[string]string{"NAME": "n", "HOSTS": "a,b", "PORTS": "80,443", "LEVELS": "1,0", "PARENT_NAME": "p"}
+	// Recursive message fields are skipped.
+	out := &Node{}
+	if errors = SetFrom(struct2env.MapLookup(env), "", out); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	in.Peers = nil
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("mismatch\n%+v\n%+v", in, out)
+	}
+}
+
+func TestContractHashOnce(t *testing.T) {
+	e := struct2env.NewEncoder()
+	e.EmitContractHash = true
+	e.OmitNilPointers = true
+	kvl, errors := Encode(e, &ServerConfig{Tls: &TLS{CertFile: "c"}})
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	env := map[string]string{}
+	hashes := 0
+	for _, kv := range kvl {
+		env["P_"+kv.Key] = kv.Value
+		if kv.Key == struct2env.ContractHashKey {
+			hashes++
+		}
+	}
+	if hashes != 1 {
+		t.Errorf("expected 1 %s, got %d in %v", struct2env.ContractHashKey, hashes, kvl)
+	}
+	// Same hash whether or not the nested messages are set.
+	kvl, _ = Encode(e, &ServerConfig{})
+	for _, kv := range kvl {
+		if kv.Key == struct2env.ContractHashKey && kv.Value != env["P_"+kv.Key] {
+			t.Errorf("hash changed: %s vs %s", kv.Value, env["P_"+kv.Key])
+		}
+	}
+	d := struct2env.NewDecoder(struct2env.MapLookup(env))
+	d.CheckContractHash = true
+	out := &ServerConfig{}
+	if errors = Decode(d, "P_", out); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if out.Tls == nil || out.Tls.CertFile != "c" {
+		t.Errorf("unexpected %+v", out)
+	}
+	env["P_"+struct2env.ContractHashKey] = "other"
+	if errors = Decode(d, "P_", &ServerConfig{}); len(errors) != 1 {
+		t.Errorf("expected 1 contract mismatch error, got %v", errors)
+	}
+}
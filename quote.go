@@ -0,0 +1,75 @@
+package struct2env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ANSICQuote returns input as a bash ANSI-C quoted string ($'...'), where control characters
+// are visible escapes (\n, \t, \x01...), for values meant to stay on one line. Like ShellQuote
+// it errors out on NUL which can't be represented.
+func ANSICQuote(input string) (string, error) {
+	if strings.ContainsRune(input, 0) {
+		return "", fmt.Errorf("string value %q should not contain NUL", input)
+	}
+	var sb strings.Builder
+	sb.Grow(len(input) + 3)
+	sb.WriteString("$'")
+	for _, r := range input {
+		switch {
+		case r == '\\' || r == '\'':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString("\\n")
+		case r == '\r':
+			sb.WriteString("\\r")
+		case r == '\t':
+			sb.WriteString("\\t")
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&sb, "\\x%02x", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune('\'')
+	return sb.String(), nil
+}
+
+// isBareSafe returns whether s can be used as is, unquoted, in shell assignments.
+func isBareSafe(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_@%+=:,./-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quoteAs applies the `quote=` tag option to the shell quoted value of result: `single` always single
+// quotes (even booleans and durations which are otherwise bare), `ansi` uses ANSICQuote and `bare` leaves
+// the value unquoted, which is an error if the value contains any character special to the shell.
+func quoteAs(result *KeyValue, opts tagOptions) error {
+	style, found := opts.Get("quote")
+	if !found || result.YamlQuotedVal == "null" {
+		return nil
+	}
+	var err error
+	switch style {
+	case "single":
+		result.ShellQuotedVal, err = ShellQuote(result.Value)
+	case "ansi":
+		result.ShellQuotedVal, err = ANSICQuote(result.Value)
+	case "bare":
+		if !isBareSafe(result.Value) {
+			return fmt.Errorf("quote=bare: value of %s (%q) needs quoting", result.Key, result.Value)
+		}
+		result.ShellQuotedVal = result.Value
+	default:
+		return fmt.Errorf("unknown quote style %q for %s, expecting single, ansi or bare", style, result.Key)
+	}
+	return err
+}
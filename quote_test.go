@@ -0,0 +1,41 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestANSICQuote(t *testing.T) {
+	str, err := ANSICQuote("it's a\tb\\c\n\x01é")
+	if err != nil || str != `$'it\'s a\tb\\c\n\x01é'` {
+		t.Errorf("unexpected %q %v", str, err)
+	}
+	if _, err = ANSICQuote("a\x00b"); err == nil {
+		t.Errorf("expected error for NUL")
+	}
+}
+
+func TestQuoteOption(t *testing.T) {
+	type Cfg struct {
+		Debug   bool   `env:",quote=single"`
+		Banner  string `env:",quote=ansi"`
+		Version string `env:",quote=bare"`
+		Path    string `env:",quote=bare"`
+		Ptr     *int   `env:",quote=single"`
+		Other   string `env:",quote=double"`
+	}
+	kvl, errs := StructToEnvVars(Cfg{Banner: "line1\nline2", Version: "v1.2.3-rc1", Path: "/a b"})
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := `DEBUG='false'
+BANNER=$'line1\nline2'
+VERSION=v1.2.3-rc1
+PATH='/a b'
+PTR=
+OTHER=''
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
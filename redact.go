@@ -0,0 +1,68 @@
+package struct2env
+
+// Redacted replaces the values of the secret fields in the Redact output.
+const Redacted = "***redacted***"
+
+// SecretPolicy is how the secret (`env:",secret"`) values are output by Render, see FormatOptions.
+type SecretPolicy int
+
+const (
+	// SecretsShown outputs the secrets as is - the default, e.g. for generating deployment files.
+	SecretsShown SecretPolicy = iota
+	// SecretsRedacted replaces the secrets' values by Redacted, see Redact.
+	SecretsRedacted
+	// SecretsOmitted skips the secrets, see OmitSecrets.
+	SecretsOmitted
+	// SecretsOnly skips everything but the secrets, see OnlySecrets.
+	SecretsOnly
+)
+
+// Redact returns a copy of kvl where the non empty values of the secret fields are replaced by
+// Redacted (nil pointers stay null), e.g. to log the effective configuration at startup.
+// The input list is unchanged so it can still be used.
+func Redact(kvl []KeyValue) []KeyValue {
+	res := make([]KeyValue, len(kvl))
+	for i, kv := range kvl {
+		if kv.Secret && kv.Value != "" {
+			_ = setString(&kv, Redacted)
+		}
+		res[i] = kv
+	}
+	return res
+}
+
+// OmitSecrets returns the entries of kvl which aren't secret.
+func OmitSecrets(kvl []KeyValue) []KeyValue {
+	res := make([]KeyValue, 0, len(kvl))
+	for _, kv := range kvl {
+		if !kv.Secret {
+			res = append(res, kv)
+		}
+	}
+	return res
+}
+
+// OnlySecrets returns the entries of kvl which are secret, e.g. for ToSecretWithPrefix.
+func OnlySecrets(kvl []KeyValue) []KeyValue {
+	res := make([]KeyValue, 0, len(kvl))
+	for _, kv := range kvl {
+		if kv.Secret {
+			res = append(res, kv)
+		}
+	}
+	return res
+}
+
+// apply returns kvl with the policy applied.
+func (p SecretPolicy) apply(kvl []KeyValue) []KeyValue {
+	switch p {
+	case SecretsRedacted:
+		return Redact(kvl)
+	case SecretsOmitted:
+		return OmitSecrets(kvl)
+	case SecretsOnly:
+		return OnlySecrets(kvl)
+	default:
+		return kvl
+	}
+}
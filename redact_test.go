@@ -0,0 +1,42 @@
+package struct2env
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	type Cfg struct {
+		User     string
+		Password string  `env:",secret"`
+		Token    *string `env:",secret"`
+		Empty    string  `env:",secret"`
+	}
+	kvl, errs := StructToEnvVars(Cfg{User: "joe", Password: "it's"})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	redacted := Redact(kvl)
+	expected := "USER='joe'\nPASSWORD='***redacted***'\nTOKEN=\nEMPTY=''\n"
+	if str := ToShellWithPrefix("", redacted, true); str != expected {
+		t.Errorf("unexpected %q", str)
+	}
+	if kvl[1].Value != "it's" {
+		t.Errorf("input shouldn't be modified: %+v", kvl[1])
+	}
+	if str := ToYamlWithPrefix(0, "", OmitSecrets(kvl)); str != "- name: USER\n  value: \"joe\"\n" {
+		t.Errorf("unexpected %q", str)
+	}
+	var sb strings.Builder
+	if err := Render(&sb, "dotenv", kvl, FormatOptions{Secrets: SecretsRedacted}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !strings.Contains(sb.String(), "PASSWORD='***redacted***'") {
+		t.Errorf("unexpected %q", sb.String())
+	}
+	sb.Reset()
+	_ = Render(&sb, "dotenv", kvl, FormatOptions{Secrets: SecretsOmitted})
+	if sb.String() != "USER='joe'\n" {
+		t.Errorf("unexpected %q", sb.String())
+	}
+}
@@ -0,0 +1,153 @@
+package struct2env
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// FormatOptions are the options passed to the Formatters, each uses the ones relevant to it.
+type FormatOptions struct {
+	Prefix     string // Output prefix applied to the keys (except the NoPrefix ones).
+	Indent     int    // YAML indentation.
+	SkipExport bool   // Omit the shell export line.
+	Scope      string // PowerShell variables scope.
+	Sections   bool   // Separate the nested structs' variables (shell, dotenv and markdown), see KeyValue.Section.
+	// Secrets is applied to the list before rendering it, to redact or omit the secret values.
+	Secrets SecretPolicy
+	// Name of the kubernetes resource (configmap and secret formats), defaults to DefaultResourceName.
+	Name string
+}
+
+// Formatter renders a KeyValue list in some output format.
+type Formatter interface {
+	Render(w io.Writer, kvl []KeyValue, opts FormatOptions) error
+}
+
+// FormatterFunc adapts a function to the Formatter interface.
+type FormatterFunc func(w io.Writer, kvl []KeyValue, opts FormatOptions) error
+
+// Render calls f.
+func (f FormatterFunc) Render(w io.Writer, kvl []KeyValue, opts FormatOptions) error {
+	return f(w, kvl, opts)
+}
+
+// stringFormatter adapts the ToXxxWithPrefix style functions.
+func stringFormatter(f func(kvl []KeyValue, opts FormatOptions) string) Formatter {
+	return FormatterFunc(func(w io.Writer, kvl []KeyValue, opts FormatOptions) error {
+		_, err := io.WriteString(w, f(kvl, opts))
+		return err
+	})
+}
+
+var (
+	formatsMutex sync.RWMutex
+	formats      = map[string]Formatter{
+		"shell": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			shellOpts := ShellOptions{Prefix: opts.Prefix, SkipExport: opts.SkipExport, Sections: opts.Sections}
+			return ToShellWithOptions(kvl, shellOpts)
+		}),
+		"yaml": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToYamlWithPrefix(opts.Indent, opts.Prefix, kvl)
+		}),
+		"dotenv": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return toDotEnv(opts.Prefix, kvl, opts.Sections)
+		}),
+		"ansible": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToAnsibleVarsWithPrefix(opts.Prefix, kvl)
+		}),
+		"gitlab": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToGitLabCIWithPrefix(opts.Prefix, kvl)
+		}),
+		"github": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToGitHubEnvWithPrefix(opts.Prefix, kvl)
+		}),
+		"json": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToJSONWithPrefix(opts.Prefix, kvl, false)
+		}),
+		"json-array": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToJSONWithPrefix(opts.Prefix, kvl, true)
+		}),
+		"python": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToPythonWithPrefix(opts.Prefix, kvl, false)
+		}),
+		"python-environ": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToPythonWithPrefix(opts.Prefix, kvl, true)
+		}),
+		"ruby": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToRubyHashWithPrefix(opts.Prefix, kvl, "")
+		}),
+		"starlark": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToStarlarkDictWithPrefix(opts.Prefix, kvl)
+		}),
+		"docker": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToDockerCommandLine(opts.Prefix, kvl) + "\n"
+		}),
+		"compose": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToComposeEnvironmentWithPrefix(opts.Indent, opts.Prefix, kvl, false)
+		}),
+		"compose-list": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToComposeEnvironmentWithPrefix(opts.Indent, opts.Prefix, kvl, true)
+		}),
+		"configmap": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToConfigMapWithPrefix(opts.Prefix, kvl, opts.Name)
+		}),
+		"secret": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToSecretWithPrefix(opts.Prefix, kvl, opts.Name)
+		}),
+		"powershell": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToPowerShellWithPrefix(opts.Prefix, kvl, opts.Scope)
+		}),
+		"markdown": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			if opts.Sections {
+				return ToMarkdownSections(describe(opts.Prefix, kvl))
+			}
+			return ToMarkdownTable(describe(opts.Prefix, kvl))
+		}),
+		"asciidoc": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToAsciiDocTable(describe(opts.Prefix, kvl))
+		}),
+		"rst": stringFormatter(func(kvl []KeyValue, opts FormatOptions) string {
+			return ToRSTTable(describe(opts.Prefix, kvl))
+		}),
+	}
+)
+
+// RegisterFormat makes a Formatter available by name (for Render and LookupFormat), replacing
+// any previous one of that name, built-ins included: see Formats for their list (e.g. shell, yaml,
+// dotenv, json, docker, compose, configmap, markdown).
+func RegisterFormat(name string, f Formatter) {
+	formatsMutex.Lock()
+	formats[name] = f
+	formatsMutex.Unlock()
+}
+
+// LookupFormat returns the Formatter registered under name.
+func LookupFormat(name string) (Formatter, bool) {
+	formatsMutex.RLock()
+	f, found := formats[name]
+	formatsMutex.RUnlock()
+	return f, found
+}
+
+// Formats returns the sorted names of the registered formats.
+func Formats() []string {
+	formatsMutex.RLock()
+	res := make([]string, 0, len(formats))
+	for name := range formats {
+		res = append(res, name)
+	}
+	formatsMutex.RUnlock()
+	sort.Strings(res)
+	return res
+}
+
+// Render writes kvl to w using the format registered under name, after applying opts.Secrets.
+func Render(w io.Writer, name string, kvl []KeyValue, opts FormatOptions) error {
+	f, found := LookupFormat(name)
+	if !found {
+		return fmt.Errorf("unknown format %q, available: %v", name, Formats())
+	}
+	return f.Render(w, opts.Secrets.apply(kvl), opts)
+}
@@ -0,0 +1,128 @@
+package struct2env
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDotEnv(t *testing.T) {
+	type Cfg struct {
+		Name  string
+		Multi string
+		Quote string
+		Ptr   *int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "a $b", Multi: "l1\nl2", Quote: `it's "q" \`})
+	str := ToDotEnvWithPrefix("E_", kvl)
+	expected := `E_NAME='a $b'
+E_MULTI="l1\nl2"
+E_QUOTE="it's \"q\" \\"
+E_PTR=
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	kvl, _ := StructToEnvVars(struct{ Name string }{"x"})
+	tests := []struct {
+		format   string
+		opts     FormatOptions
+		expected string
+	}{
+		{"shell", FormatOptions{Prefix: "P_"}, "P_NAME='x'\nexport P_NAME\n"},
+		{"shell", FormatOptions{SkipExport: true}, "NAME='x'\n"},
+		{"yaml", FormatOptions{Indent: 2}, "  - name: NAME\n    value: \"x\"\n"},
+		{"dotenv", FormatOptions{}, "NAME='x'\n"},
+		{"gitlab", FormatOptions{Prefix: "G_"}, "variables:\n  G_NAME: \"x\"\n"},
+		{"powershell", FormatOptions{Scope: "Process"}, "[Environment]::SetEnvironmentVariable('NAME', 'x', 'Process')\n"},
+	}
+	for _, test := range tests {
+		var sb strings.Builder
+		if err := Render(&sb, test.format, kvl, test.opts); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if sb.String() != test.expected {
+			t.Errorf("for %s expected %q got %q", test.format, test.expected, sb.String())
+		}
+	}
+	RegisterFormat("test-csv", FormatterFunc(func(w io.Writer, kvl []KeyValue, opts FormatOptions) error {
+		for _, kv := range kvl {
+			if _, err := fmt.Fprintf(w, "%s%s,%s\n", opts.Prefix, kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	var sb strings.Builder
+	if err := Render(&sb, "test-csv", kvl, FormatOptions{Prefix: "C_"}); err != nil || sb.String() != "C_NAME,x\n" {
+		t.Errorf("unexpected custom format result %q %v", sb.String(), err)
+	}
+	names := Formats()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("format names should be sorted: %v", names)
+	}
+	for _, name := range []string{
+		"ansible", "compose", "compose-list", "configmap", "docker", "dotenv", "github", "gitlab", "json", "json-array",
+		"powershell", "python", "python-environ", "ruby", "secret", "shell", "starlark", "test-csv", "yaml",
+	} {
+		if idx := sort.SearchStrings(names, name); idx == len(names) || names[idx] != name {
+			t.Errorf("%s missing from %v", name, names)
+		}
+	}
+	if err := Render(&sb, "nope", kvl, FormatOptions{}); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestSections(t *testing.T) {
+	type DB struct {
+		Host string
+		Port int
+	}
+	type Cfg struct {
+		Name  string
+		DB    DB
+		Debug bool
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "app", DB: DB{Host: "h", Port: 5432}})
+	if kvl[0].Section != "" || kvl[1].Section != "DB" || kvl[3].Section != "" {
+		t.Errorf("unexpected sections %+v", kvl)
+	}
+	var sb strings.Builder
+	if err := Render(&sb, "dotenv", kvl, FormatOptions{Prefix: "E_", Sections: true}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := `E_NAME='app'
+
+# --- DB ---
+E_DB_HOST='h'
+E_DB_PORT='5432'
+
+E_DEBUG='false'
+`
+	if sb.String() != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, sb.String())
+	}
+	str := ToShellWithOptions(kvl[1:3], ShellOptions{Sections: true})
+	expected = `# --- DB ---
+DB_HOST='h'
+DB_PORT='5432'
+
+export DB_HOST DB_PORT
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	sb.Reset()
+	if err := Render(&sb, "markdown", kvl, FormatOptions{Sections: true}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if strings.Count(sb.String(), "| Variable |") != 3 || !strings.Contains(sb.String(), "\n\n### DB\n\n| Variable") {
+		t.Errorf("unexpected markdown:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,50 @@
+package struct2env
+
+import (
+	"fmt"
+)
+
+// DefaultReservedNames are variables whose accidental setting (or reading as configuration) breaks the
+// process or its children, or is a security risk, to use as Options.ReservedNames.
+var DefaultReservedNames = []string{
+	"PATH", "HOME", "USER", "SHELL", "PWD", "IFS", "TERM", "LANG", "TMPDIR", "TZ",
+	"LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "DYLD_LIBRARY_PATH",
+}
+
+// ReservedError is the error for the fields whose variable is one of the ReservedNames, when they aren't
+// tagged `env:",allowreserved"`.
+type ReservedError struct {
+	EnvName string // Name of the variable, prefix included.
+	Field   string // Path of the field, e.g. Exec.Path.
+}
+
+func (e *ReservedError) Error() string {
+	return fmt.Sprintf("%s (for %s) is a reserved name, tag the field allowreserved to use it anyway", e.EnvName, e.Field)
+}
+
+// isReserved returns whether name is one of the ReservedNames and the field options don't allow it.
+func (o *Options) isReserved(name string, opts tagOptions) bool {
+	if len(o.ReservedNames) == 0 || opts.Contains("allowreserved") {
+		return false
+	}
+	for _, reserved := range o.ReservedNames {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// dropReserved removes the (fallback) names which are reserved from names, but the first one.
+func (o *Options) dropReserved(names []string, opts tagOptions) []string {
+	if len(names) < 2 || len(o.ReservedNames) == 0 {
+		return names
+	}
+	res := names[:1]
+	for _, name := range names[1:] {
+		if !o.isReserved(name, opts) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
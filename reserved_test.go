@@ -0,0 +1,76 @@
+package struct2env
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReservedNames(t *testing.T) {
+	type Exec struct {
+		Path  string `env:"PATH,noprefix"`
+		Home  string `env:"HOME,noprefix,allowreserved"`
+		Shell string
+	}
+	type Cfg struct {
+		Exec  Exec
+		Vars  map[string]string `env:"LD,noprefix"`
+		Shell string
+	}
+	e := NewEncoder()
+	in := Cfg{Exec: Exec{Path: "/bin", Home: "/root", Shell: "sh"}, Vars: map[string]string{"preload": "x.so", "ok": "1"}}
+	kvl, errs := e.StructToEnvVars(in)
+	if len(errs) != 0 || len(kvl) != 6 {
+		t.Errorf("unexpected %v %v", kvl, errs)
+	}
+	e.ReservedNames = DefaultReservedNames
+	kvl, errs = e.StructToEnvVars(in)
+	var reserved *ReservedError
+	// The keys are checked as produced: SHELL is reserved while APP_SHELL wouldn't be.
+	if len(errs) != 3 || !errors.As(errs[0], &reserved) || reserved.EnvName != "PATH" ||
+		errs[1].Error() != "LD_PRELOAD (for Vars) is a reserved name, tag the field allowreserved to use it anyway" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if len(kvl) != 3 || kvl[0].Key != "HOME" {
+		t.Errorf("unexpected %v", kvl)
+	}
+	e.AutoPrefix = true
+	if _, errs = e.StructToEnvVars(in); len(errs) != 2 {
+		t.Errorf("expected 2 errors with a prefix, got %v", errs)
+	}
+	env := map[string]string{"PATH": "/usr/bin", "HOME": "/home", "SHELL": "zsh", "APP_EXEC_SHELL": "bash"}
+	d := NewDecoder(MapLookup(env))
+	d.ReservedNames = DefaultReservedNames
+	d.PrefixFallback = true
+	out := Cfg{}
+	errs = d.SetFrom("APP_", &out)
+	if len(errs) != 1 || !errors.As(errs[0], &reserved) || reserved.Field != "Exec.Path" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	// SHELL isn't used as fallback of APP_SHELL as it's reserved.
+	if out.Exec.Path != "" || out.Exec.Home != "/home" || out.Exec.Shell != "bash" || out.Shell != "" {
+		t.Errorf("unexpected %+v", out)
+	}
+	// Same for the map entries.
+	env = map[string]string{"LD_PRELOAD": "/evil.so", "LD_OK": "1"}
+	d = NewDecoder(MapLookup(env))
+	d.Keys = MapKeys(env)
+	d.ReservedNames = DefaultReservedNames
+	type Vars struct {
+		Vars map[string]string `env:"LD,noprefix"`
+	}
+	vars := Vars{}
+	errs = d.SetFrom("", &vars)
+	if len(errs) != 1 || !errors.As(errs[0], &reserved) || reserved.EnvName != "LD_PRELOAD" || reserved.Field != "Vars" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if len(vars.Vars) != 1 || vars.Vars["ok"] != "1" {
+		t.Errorf("unexpected %v", vars.Vars)
+	}
+	type Allowed struct {
+		Vars map[string]string `env:"LD,noprefix,allowreserved"`
+	}
+	allowed := Allowed{}
+	if errs = d.SetFrom("", &allowed); len(errs) != 0 || allowed.Vars["preload"] != "/evil.so" {
+		t.Errorf("unexpected %v %v", errs, allowed.Vars)
+	}
+}
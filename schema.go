@@ -0,0 +1,195 @@
+package struct2env
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Schema is the subset of JSON Schema applicable to environment variables: an object whose properties
+// are the variable names, each with a "type" (string, integer, number, boolean, or a list of those),
+// "enum", "pattern", "minimum", "maximum", "minLength" and "maxLength"; plus the "required" list and
+// "additionalProperties": false. Other keywords are ignored.
+type Schema struct {
+	Properties           map[string]*SchemaProperty `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties *bool                      `json:"additionalProperties"`
+}
+
+// SchemaProperty is the schema of a single variable, see Schema.
+type SchemaProperty struct {
+	Type      json.RawMessage `json:"type"` // "string" or ["string", ...]
+	Enum      []interface{}   `json:"enum"`
+	Pattern   string          `json:"pattern"`
+	Minimum   *float64        `json:"minimum"`
+	Maximum   *float64        `json:"maximum"`
+	MinLength *int            `json:"minLength"`
+	MaxLength *int            `json:"maxLength"`
+
+	types   []string
+	pattern *regexp.Regexp
+}
+
+// ParseSchema parses and compiles a JSON Schema (see Schema for the supported subset).
+func ParseSchema(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	for name, p := range s.Properties {
+		if p == nil {
+			return nil, fmt.Errorf("invalid schema: null property %s", name)
+		}
+		if len(p.Type) > 0 {
+			var single string
+			if err := json.Unmarshal(p.Type, &single); err == nil {
+				p.types = []string{single}
+			} else if err = json.Unmarshal(p.Type, &p.types); err != nil {
+				return nil, fmt.Errorf("invalid schema type for %s: %w", name, err)
+			}
+		}
+		if p.Pattern != "" {
+			var err error
+			if p.pattern, err = regexp.Compile(p.Pattern); err != nil {
+				return nil, fmt.Errorf("invalid schema pattern for %s: %w", name, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// ValidateAgainstSchema checks the values of kvl (with their keys as is) against the JSON schema
+// and returns all the violations. Nil pointer entries count as absent.
+func ValidateAgainstSchema(schema []byte, kvl []KeyValue) []error {
+	s, err := ParseSchema(schema)
+	if err != nil {
+		return []error{err}
+	}
+	return s.ValidateEnvVars(kvl)
+}
+
+// ValidateEnvVars checks the values of kvl against the schema, see ValidateAgainstSchema.
+func (s *Schema) ValidateEnvVars(kvl []KeyValue) []error {
+	var allErrors []error
+	present := make(map[string]bool, len(kvl))
+	for _, kv := range kvl {
+		if kv.YamlQuotedVal == "null" {
+			continue
+		}
+		present[kv.Key] = true
+		if _, found := s.Properties[kv.Key]; !found {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				allErrors = append(allErrors, fmt.Errorf("%s is not allowed by the schema", kv.Key))
+			}
+			continue
+		}
+		if err := s.Validate(kv.Key, kv.Value); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+	for _, name := range s.Required {
+		if !present[name] {
+			allErrors = append(allErrors, fmt.Errorf("%s is required by the schema", name))
+		}
+	}
+	return allErrors
+}
+
+// Validate checks a single value against the schema of the name property, if any.
+func (s *Schema) Validate(name, value string) error {
+	p, found := s.Properties[name]
+	if !found {
+		return nil
+	}
+	var num float64
+	isNum := false
+	if len(p.types) > 0 {
+		matched := false
+		for _, t := range p.types {
+			var err error
+			switch t {
+			case "string":
+			case "integer":
+				var i int64
+				i, err = strconv.ParseInt(value, 10, 64)
+				num, isNum = float64(i), err == nil
+			case "number":
+				num, err = strconv.ParseFloat(value, 64)
+				isNum = err == nil
+			case "boolean":
+				_, err = strconv.ParseBool(value)
+			default:
+				continue // null, object, array: can't match a set variable.
+			}
+			if err == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s=%q is not of type %s", name, value, string(p.Type))
+		}
+	}
+	if len(p.Enum) > 0 && !enumContains(p.Enum, value) {
+		return fmt.Errorf("%s=%q is not one of %v", name, value, enumStrings(p.Enum))
+	}
+	if p.pattern != nil && !p.pattern.MatchString(value) {
+		return fmt.Errorf("%s=%q does not match %s", name, value, p.Pattern)
+	}
+	if isNum && p.Minimum != nil && num < *p.Minimum {
+		return fmt.Errorf("%s=%q is less than the minimum %v", name, value, *p.Minimum)
+	}
+	if isNum && p.Maximum != nil && num > *p.Maximum {
+		return fmt.Errorf("%s=%q is more than the maximum %v", name, value, *p.Maximum)
+	}
+	l := utf8.RuneCountInString(value)
+	if p.MinLength != nil && l < *p.MinLength {
+		return fmt.Errorf("%s=%q is shorter than %d", name, value, *p.MinLength)
+	}
+	if p.MaxLength != nil && l > *p.MaxLength {
+		return fmt.Errorf("%s=%q is longer than %d", name, value, *p.MaxLength)
+	}
+	return nil
+}
+
+// checkRequired returns an error for each of the schema's required names not found through the Decoder.
+func (s *Schema) checkRequired(d *Decoder, allErrors []error) []error {
+	for _, name := range s.Required {
+		if _, _, found := d.lookup(name); !found {
+			allErrors = append(allErrors, fmt.Errorf("%s is required by the schema", name))
+		}
+	}
+	return allErrors
+}
+
+func enumContains(enum []interface{}, value string) bool {
+	for _, e := range enum {
+		switch ev := e.(type) {
+		case string:
+			if ev == value {
+				return true
+			}
+		case float64:
+			if f, err := strconv.ParseFloat(value, 64); err == nil && f == ev {
+				return true
+			}
+		case bool:
+			if b, err := strconv.ParseBool(value); err == nil && b == ev {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func enumStrings(enum []interface{}) []string {
+	res := make([]string, 0, len(enum))
+	for _, e := range enum {
+		res = append(res, fmt.Sprint(e))
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,105 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+const testSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "APP_PORT": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "APP_LEVEL": {"type": "string", "enum": ["debug", "info", "warn"]},
+    "APP_NAME": {"type": "string", "pattern": "^[a-z-]+$", "minLength": 2, "maxLength": 10},
+    "APP_RATIO": {"type": ["number", "string"], "enum": [0.5, 1, "auto"]},
+    "APP_VERBOSE": {"type": "boolean"}
+  },
+  "required": ["APP_PORT", "APP_NAME"],
+  "additionalProperties": false
+}`
+
+func TestValidateAgainstSchema(t *testing.T) {
+	type Cfg struct {
+		Port    int
+		Level   string
+		Name    string
+		Ratio   string
+		Verbose *bool
+	}
+	kvl, _ := StructsToEnvVars("APP_", Cfg{Port: 8080, Level: "info", Name: "my-app", Ratio: "0.50"})
+	if errors := ValidateAgainstSchema([]byte(testSchema), kvl); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	type Bad struct {
+		Port  int
+		Level string
+		Ratio string
+		Extra string
+	}
+	kvl, _ = StructsToEnvVars("APP_", Bad{Port: 0, Level: "trace", Ratio: "2"})
+	errors := ValidateAgainstSchema([]byte(testSchema), kvl)
+	expected := []string{
+		`APP_PORT="0" is less than the minimum 1`,
+		`APP_LEVEL="trace" is not one of [debug info warn]`,
+		`APP_RATIO="2" is not one of [0.5 1 auto]`,
+		`APP_EXTRA is not allowed by the schema`,
+		`APP_NAME is required by the schema`,
+	}
+	if len(errors) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errors)
+	}
+	for i, e := range expected {
+		if errors[i].Error() != e {
+			t.Errorf("#%d expected %s got %v", i, e, errors[i])
+		}
+	}
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, test := range []struct {
+		name, value string
+		valid       bool
+	}{
+		{"APP_PORT", "x", false},
+		{"APP_PORT", "1.5", false},
+		{"APP_NAME", "x", false},
+		{"APP_NAME", "way-too-long-name", false},
+		{"APP_NAME", "Upper", false},
+		{"APP_RATIO", "auto", true},
+		{"APP_RATIO", "1", true},
+		{"APP_VERBOSE", "yes", false},
+		{"APP_VERBOSE", "true", true},
+		{"UNKNOWN", "anything", true},
+	} {
+		if err := s.Validate(test.name, test.value); (err == nil) != test.valid {
+			t.Errorf("%s=%q expected valid %v got %v", test.name, test.value, test.valid, err)
+		}
+	}
+	for _, bad := range []string{`{`, `{"properties": {"A": {"pattern": "("}}}`, `{"properties": {"A": {"type": 1}}}`} {
+		if errors := ValidateAgainstSchema([]byte(bad), nil); len(errors) != 1 {
+			t.Errorf("expected schema error for %s, got %v", bad, errors)
+		}
+	}
+}
+
+func TestDecoderSchema(t *testing.T) {
+	type Cfg struct {
+		Port int
+		Name string
+	}
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	d := NewDecoder(MapLookup(map[string]string{"APP_PORT": "70000"}))
+	d.Schema = s
+	cfg := Cfg{}
+	errors := d.SetFrom("APP_", &cfg)
+	if len(errors) != 2 { // maximum and required APP_NAME
+		t.Errorf("expected 2 errors, got %v", errors)
+	}
+	if cfg.Port != 0 {
+		t.Errorf("invalid value should not be set, got %d", cfg.Port)
+	}
+}
@@ -0,0 +1,90 @@
+package struct2env
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxShellLine is the line length limit ValidateShell uses when none is given: the minimum
+// LINE_MAX POSIX guarantees, some shells and tools (e.g. read, busybox) truncating longer lines.
+const DefaultMaxShellLine = 2048
+
+// ShellIssue is the kind of a ShellWarning.
+type ShellIssue string
+
+const (
+	// ShellInvalidName is for names which aren't portable shell identifiers (the assignment would run a command).
+	ShellInvalidName ShellIssue = "invalid name"
+	// ShellNUL is for values with NUL bytes, which the shell can't represent.
+	ShellNUL ShellIssue = "NUL byte"
+	// ShellLongLine is for lines of the output longer than the maximum.
+	ShellLongLine ShellIssue = "long line"
+	// ShellInvalidUTF8 is for values which aren't valid UTF-8, mangled by some shells and terminals.
+	ShellInvalidUTF8 ShellIssue = "invalid UTF-8"
+	// ShellNonASCII is for values with non ASCII characters, whose handling depends on the locale
+	// (e.g. ${#VAR} or pattern matching in the C locale).
+	ShellNonASCII ShellIssue = "non ASCII"
+	// ShellControl is for values with control characters other than tab and newline, which may corrupt
+	// the terminal when the script is displayed.
+	ShellControl ShellIssue = "control character"
+)
+
+// ShellWarning is a representability issue of a variable in the shell output, see ValidateShell.
+type ShellWarning struct {
+	Key    string // Variable name, prefix included.
+	Issue  ShellIssue
+	Detail string
+}
+
+func (w ShellWarning) String() string {
+	return w.Key + ": " + string(w.Issue) + ": " + w.Detail
+}
+
+// ValidateShell checks, without running a shell, that the ToShellWithPrefix output for kvl is a valid
+// and portable /bin/sh script: names are portable identifiers, values have no NUL byte, lines are at most
+// maxLine bytes (DefaultMaxShellLine when 0 or less) and values are valid UTF-8 without control characters
+// or (locale dependent) non ASCII ones. Returns the warnings found, for generators to fail before a broken
+// script ships.
+func ValidateShell(prefix string, kvl []KeyValue, maxLine int) []ShellWarning {
+	if maxLine <= 0 {
+		maxLine = DefaultMaxShellLine
+	}
+	var res []ShellWarning
+	for _, kv := range kvl {
+		key := kv.prefixedKey(prefix)
+		add := func(issue ShellIssue, format string, args ...interface{}) {
+			res = append(res, ShellWarning{Key: key, Issue: issue, Detail: fmt.Sprintf(format, args...)})
+		}
+		if err := PortableNames.Check(key); err != nil {
+			add(ShellInvalidName, "%v", err)
+		}
+		if idx := strings.IndexByte(kv.Value, 0); idx >= 0 {
+			add(ShellNUL, "at offset %d", idx)
+		}
+		for i, line := range strings.Split(key+"="+kv.ShellQuotedVal, "\n") {
+			if len(line) > maxLine {
+				add(ShellLongLine, "line %d is %d bytes long, more than %d", i+1, len(line), maxLine)
+			}
+		}
+		if !utf8.ValidString(kv.Value) {
+			add(ShellInvalidUTF8, "value %q", kv.Value)
+		} else if idx := strings.IndexFunc(kv.Value, isNonASCII); idx >= 0 {
+			r, _ := utf8.DecodeRuneInString(kv.Value[idx:])
+			add(ShellNonASCII, "%q at offset %d", r, idx)
+		}
+		if idx := strings.IndexFunc(kv.Value, isShellControl); idx >= 0 {
+			add(ShellControl, "%q at offset %d", kv.Value[idx], idx)
+		}
+	}
+	return res
+}
+
+func isNonASCII(r rune) bool {
+	return r >= utf8.RuneSelf
+}
+
+// isShellControl returns whether r is a control character other than tab, newline and NUL (reported on its own).
+func isShellControl(r rune) bool {
+	return (r < ' ' && r != '\t' && r != '\n' && r != 0) || r == 0x7f
+}
@@ -0,0 +1,46 @@
+package struct2env
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateShell(t *testing.T) {
+	kvl := []KeyValue{
+		{Key: "OK", Value: "plain\tvalue\nsecond line"},
+		{Key: "BAD-NAME", Value: "x"},
+		{Key: "LONG", Value: strings.Repeat("a", 20)},
+		{Key: "UTF8", Value: "café"},
+		{Key: "BIN", Value: "a\xffb"},
+		{Key: "ESC", Value: "\x1b[31mred"},
+		{Key: "NUL", Value: "a\x00b"},
+	}
+	for i := range kvl {
+		kvl[i].ShellQuotedVal = "'" + kvl[i].Value + "'"
+	}
+	warnings := ValidateShell("APP_", kvl, 24)
+	expected := []ShellIssue{
+		ShellInvalidName, ShellLongLine, ShellNonASCII, ShellInvalidUTF8, ShellControl, ShellNUL,
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	for i, w := range warnings {
+		if w.Issue != expected[i] {
+			t.Errorf("warning %d: got %v expected %v", i, w, expected[i])
+		}
+	}
+	if s := warnings[2].String(); s != `APP_UTF8: non ASCII: 'é' at offset 3` {
+		t.Errorf("unexpected %q", s)
+	}
+	if s := warnings[1].String(); s != "APP_LONG: long line: line 1 is 31 bytes long, more than 24" {
+		t.Errorf("unexpected %q", s)
+	}
+	kvl, _ = StructToEnvVars(struct {
+		Name string
+		Port int
+	}{"x", 80})
+	if warnings = ValidateShell("APP_", kvl, 0); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,103 @@
+package struct2env
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// byteSizeUnits are the multipliers for the (lower cased) units accepted by ParseByteSize.
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"e":   1e18,
+	"eb":  1e18,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+	"ei":  1 << 60,
+	"eib": 1 << 60,
+}
+
+// ParseByteSize parses human readable byte sizes like "10MB", "512KiB", "1.5 GB" or "42".
+// Units are case-insensitive; the ones with an i (KiB, MiB, GiB, TiB, PiB, EiB) are binary (powers of 1024)
+// while the others (kB, MB, GB, TB, PB, EB and their single letter forms k, M, G...) are SI (powers of 1000).
+// The result must be a whole number of bytes.
+func ParseByteSize(str string) (int64, error) {
+	s := strings.TrimSpace(str)
+	idx := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != '-' && r != '+'
+	})
+	num, unit := s, ""
+	if idx >= 0 {
+		num, unit = s[:idx], strings.TrimSpace(s[idx:])
+	}
+	mult, found := byteSizeUnits[strings.ToLower(unit)]
+	if !found || num == "" {
+		return 0, fmt.Errorf("invalid byte size %q", str)
+	}
+	if mult == 1 || !strings.Contains(num, ".") {
+		// Exact integer math when possible.
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid byte size %q: %w", str, err)
+		}
+		m := int64(mult)
+		if n > math.MaxInt64/m || n < math.MinInt64/m {
+			return 0, fmt.Errorf("byte size %q overflows int64", str)
+		}
+		return n * m, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q: %w", str, err)
+	}
+	f *= mult
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("byte size %q is not a whole number of bytes", str)
+	}
+	if f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("byte size %q overflows int64", str)
+	}
+	return int64(f), nil
+}
+
+// FormatByteSize is the reverse of ParseByteSize: it uses the largest unit, binary or SI, that
+// represents n exactly, e.g. 10MB, 512KiB or 1000 (bytes).
+func FormatByteSize(n int64) string {
+	if n == 0 {
+		return "0"
+	}
+	// In decreasing multiplier order so the first exact one is the largest.
+	for _, u := range []struct {
+		unit string
+		mult int64
+	}{
+		{"EiB", 1 << 60}, {"EB", 1e18}, {"PiB", 1 << 50}, {"PB", 1e15}, {"TiB", 1 << 40}, {"TB", 1e12},
+		{"GiB", 1 << 30}, {"GB", 1e9}, {"MiB", 1 << 20}, {"MB", 1e6}, {"KiB", 1 << 10}, {"kB", 1e3},
+	} {
+		if n%u.mult == 0 {
+			return strconv.FormatInt(n/u.mult, 10) + u.unit
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
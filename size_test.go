@@ -0,0 +1,95 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{"0", 0},
+		{"42", 42},
+		{"42B", 42},
+		{"10MB", 10000000},
+		{"10mb", 10000000},
+		{"10M", 10000000},
+		{"512KiB", 512 * 1024},
+		{"512 kib", 512 * 1024},
+		{"1.5GB", 1500000000},
+		{"1.5Gi", 1536 * 1024 * 1024},
+		{"2k", 2000},
+		{"-1KiB", -1024},
+		{"7EiB", 7 << 60},
+	}
+	for _, test := range tests {
+		got, err := ParseByteSize(test.in)
+		if err != nil || got != test.out {
+			t.Errorf("for %q expected %d got %d (%v)", test.in, test.out, got, err)
+		}
+	}
+	for _, in := range []string{"", "MB", "10XB", "1.5B", "0.1", "8EiB", "1e3", "abc"} {
+		if got, err := ParseByteSize(in); err == nil {
+			t.Errorf("expected error for %q, got %d", in, got)
+		}
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	tests := []struct {
+		in  int64
+		out string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{1000, "1kB"},
+		{1024, "1KiB"},
+		{10000000, "10MB"},
+		{512 * 1024, "512KiB"},
+		{1536 * 1024 * 1024, "1536MiB"},
+		{1001, "1001"},
+		{-2048, "-2KiB"},
+	}
+	for _, test := range tests {
+		got := FormatByteSize(test.in)
+		if got != test.out {
+			t.Errorf("for %d expected %q got %q", test.in, test.out, got)
+		}
+		if back, err := ParseByteSize(got); err != nil || back != test.in {
+			t.Errorf("round trip of %d gave %d (%v)", test.in, back, err)
+		}
+	}
+}
+
+func TestSizeTagOption(t *testing.T) {
+	type Cfg struct {
+		MaxBodySize int64  `env:",size"`
+		BufferSize  uint32 `env:",size"`
+		Small       int8   `env:",size"`
+		Plain       uint
+		BadSize     string `env:",size"`
+	}
+	kvl, errors := StructToEnvVars(Cfg{MaxBodySize: 10e6, BufferSize: 64 << 10, Small: 1, Plain: 7})
+	if len(errors) != 1 {
+		t.Errorf("expected 1 error for size on a string, got %v", errors)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := "MAX_BODY_SIZE='10MB'\nBUFFER_SIZE='64KiB'\nSMALL='1'\nPLAIN='7'\nBAD_SIZE=\n"
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	envs := map[string]string{"MAX_BODY_SIZE": "1.5GiB", "BUFFER_SIZE": "4kB", "SMALL": "1KiB", "PLAIN": "12"}
+	cfg := Cfg{}
+	errors = SetFrom(MapLookup(envs), "", &cfg)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 overflow error, got %v", errors)
+	}
+	if cfg.MaxBodySize != 1536<<20 || cfg.BufferSize != 4000 || cfg.Plain != 12 {
+		t.Errorf("unexpected decoding %+v", cfg)
+	}
+	envs = map[string]string{"BUFFER_SIZE": "-1kB"}
+	if errors = SetFrom(MapLookup(envs), "", &cfg); len(errors) != 1 {
+		t.Errorf("expected 1 error for negative size, got %v", errors)
+	}
+}
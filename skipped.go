@@ -0,0 +1,63 @@
+package struct2env
+
+import (
+	"reflect"
+)
+
+// SkipReason is why a field didn't produce a value.
+type SkipReason string
+
+const (
+	// SkipExcluded is for fields excluded with an `env:"-"` tag.
+	SkipExcluded SkipReason = "excluded by tag"
+	// SkipUnsupported is for fields of types not supported, e.g. chan or []bool.
+	SkipUnsupported SkipReason = "unsupported type"
+	// SkipCantInterface is for fields whose value can't be read, typically unexported ones
+	// (also reported as errors).
+	SkipCantInterface SkipReason = "can't interface"
+	// SkipEmpty is for fields tagged `env:",omitempty"` with their zero value.
+	SkipEmpty SkipReason = "empty"
+	// SkipGroup is for fields not in the Encoder's Groups.
+	SkipGroup SkipReason = "not in groups"
+	// SkipReadOnly is for fields tagged `env:",readonly"`, only read by SetFrom.
+	SkipReadOnly SkipReason = "read only"
+	// SkipWriteOnly is for fields tagged `env:",writeonly"`, not read by SetFrom, when describing the
+	// variables (see DescribeEnvVars).
+	SkipWriteOnly SkipReason = "write only"
+	// SkipNonFinite is for NaN and infinite float fields with the NonFiniteSkip policy.
+	SkipNonFinite SkipReason = "non finite"
+)
+
+// SkippedField describes a field missing from the StructToEnvVars output (or present without its value).
+type SkippedField struct {
+	Field  string       // Struct type and field name, e.g. "Config.Secret".
+	Type   reflect.Type // Type of the field.
+	Key    string       // Key the field would have had, empty when excluded by tag.
+	Reason SkipReason
+}
+
+// StructToEnvVarsReport is StructToEnvVars also returning the list of fields that were skipped and why,
+// to audit whether anything important is missing from the exported environment.
+func StructToEnvVarsReport(s interface{}) ([]KeyValue, []SkippedField, []error) {
+	return NewEncoder().StructToEnvVarsReport(s)
+}
+
+// StructToEnvVarsReport is the Encoder version of StructToEnvVarsReport.
+func (e *Encoder) StructToEnvVarsReport(s interface{}) ([]KeyValue, []SkippedField, []error) {
+	reporter := *e
+	var skipped []SkippedField
+	reporter.skipped = &skipped
+	kvl, errs := reporter.StructToEnvVars(s)
+	return kvl, skipped, errs
+}
+
+func (e *Encoder) skip(structType reflect.Type, field reflect.StructField, key string, reason SkipReason) {
+	if e.skipped == nil {
+		return
+	}
+	name := field.Name
+	if structType.Name() != "" {
+		name = structType.Name() + "." + name
+	}
+	*e.skipped = append(*e.skipped, SkippedField{Field: name, Type: field.Type, Key: key, Reason: reason})
+}
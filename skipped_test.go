@@ -0,0 +1,61 @@
+package struct2env
+
+import (
+	"testing"
+	"time"
+)
+
+type SkipInner struct {
+	Events chan int
+	Value  string
+}
+
+type SkipConfig struct {
+	Name     string
+	Password string `env:"-"`
+	Flags    []bool
+	Inner    SkipInner
+	hidden   string
+	when     time.Time
+	Bytes    []byte
+}
+
+func TestStructToEnvVarsReport(t *testing.T) {
+	cfg := SkipConfig{Name: "n", hidden: "h", when: time.Now()}
+	kvl, skipped, errors := StructToEnvVarsReport(cfg)
+	if len(errors) != 2 {
+		t.Errorf("expected 2 can't interface errors, got %v", errors)
+	}
+	if len(kvl) != 4 { // NAME, INNER_VALUE, HIDDEN (empty), BYTES
+		t.Errorf("unexpected kvl %v", kvl)
+	}
+	expected := []struct {
+		field  string
+		key    string
+		reason SkipReason
+	}{
+		{"SkipConfig.Password", "", SkipExcluded},
+		{"SkipConfig.Flags", "FLAGS", SkipUnsupported},
+		{"SkipInner.Events", "INNER_EVENTS", SkipUnsupported},
+		{"SkipConfig.hidden", "HIDDEN", SkipCantInterface},
+		{"SkipConfig.when", "WHEN", SkipCantInterface},
+	}
+	if len(skipped) != len(expected) {
+		t.Fatalf("expected %d skipped, got %+v", len(expected), skipped)
+	}
+	for i, e := range expected {
+		s := skipped[i]
+		if s.Field != e.field || s.Key != e.key || s.Reason != e.reason {
+			t.Errorf("#%d expected %+v got %+v", i, e, s)
+		}
+	}
+	if skipped[1].Type.String() != "[]bool" {
+		t.Errorf("unexpected type %v", skipped[1].Type)
+	}
+	// Regular calls don't collect anything.
+	e := NewEncoder()
+	e.StructToEnvVars(cfg)
+	if e.skipped != nil {
+		t.Errorf("skipped should only be set for reports")
+	}
+}
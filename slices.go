@@ -0,0 +1,129 @@
+package struct2env
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// separator returns the value of the `sep=` tag option, "," by default.
+func separator(opts tagOptions) string {
+	if sep, found := opts.Get("sep"); found && sep != "" {
+		return sep
+	}
+	return ","
+}
+
+// isStringSlice reports whether t is a []string (or has string elements, like []Host).
+func isStringSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String
+}
+
+// joinStrings sets result to the elements of the string slice v joined by sep. It's an error for an
+// element to contain sep as it wouldn't survive the round trip.
+func joinStrings(result *KeyValue, v reflect.Value, sep string) error {
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = v.Index(i).String()
+		if strings.Contains(parts[i], sep) {
+			return fmt.Errorf("element %q of %s contains the separator %q", parts[i], result.Key, sep)
+		}
+	}
+	return setString(result, strings.Join(parts, sep))
+}
+
+// splitStrings sets the string slice fieldValue to the sep separated elements of envVal,
+// an empty value giving an empty (not nil) slice.
+func splitStrings(fieldValue reflect.Value, envVal, sep string) {
+	var parts []string
+	if envVal != "" {
+		parts = strings.Split(envVal, sep)
+	}
+	res := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		res.Index(i).SetString(p)
+	}
+	fieldValue.Set(res)
+}
+
+// isNumberSlice reports whether t is a slice of integers (other than []byte) or floats, like []int,
+// []float64 or []Port. Not []time.Duration, which would otherwise be nanoseconds.
+func isNumberSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem() == durationType {
+		return false
+	}
+	switch t.Elem().Kind() { //nolint: exhaustive // only numbers
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// joinNumbers sets result to the elements of the number slice v joined by sep, floats being formatted
+// in the shortest form or, with FloatDecimal, without exponent. It's an error for an element to
+// contain sep (e.g. with sep=. or sep=-) as it wouldn't survive the round trip.
+func joinNumbers(result *KeyValue, v reflect.Value, sep string, floatFormat FloatFormat) error {
+	var sb strings.Builder
+	var scratch [32]byte
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		var b []byte
+		switch elem.Kind() { //nolint: exhaustive // isNumberSlice checked the kind
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			b = strconv.AppendInt(scratch[:0], elem.Int(), 10)
+		case reflect.Float32, reflect.Float64:
+			fmtByte := byte('g')
+			if floatFormat == FloatDecimal {
+				fmtByte = 'f'
+			}
+			b = strconv.AppendFloat(scratch[:0], elem.Float(), fmtByte, -1, elem.Type().Bits())
+		default:
+			b = strconv.AppendUint(scratch[:0], elem.Uint(), 10)
+		}
+		if strings.Contains(string(b), sep) {
+			return fmt.Errorf("element %s of %s contains the separator %q", b, result.Key, sep)
+		}
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		sb.Write(b)
+	}
+	return setString(result, sb.String())
+}
+
+// splitNumbers sets the number slice fieldValue to the sep separated elements of envVal (spaces around
+// them being ignored), an empty value giving an empty (not nil) slice.
+func splitNumbers(fieldValue reflect.Value, envName, envVal, sep string) error {
+	var parts []string
+	if strings.TrimSpace(envVal) != "" {
+		parts = strings.Split(envVal, sep)
+	}
+	res := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		elem := res.Index(i)
+		var err error
+		switch elem.Kind() { //nolint: exhaustive // isNumberSlice checked the kind
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var n int64
+			n, err = strconv.ParseInt(p, 10, elem.Type().Bits())
+			elem.SetInt(n)
+		case reflect.Float32, reflect.Float64:
+			var f float64
+			f, err = strconv.ParseFloat(p, elem.Type().Bits())
+			elem.SetFloat(f)
+		default:
+			var n uint64
+			n, err = strconv.ParseUint(p, 10, elem.Type().Bits())
+			elem.SetUint(n)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid element #%d %q of %s=%q: %w", i+1, p, envName, envVal, err)
+		}
+	}
+	fieldValue.Set(res)
+	return nil
+}
@@ -0,0 +1,132 @@
+package struct2env
+
+import (
+	"reflect"
+	"testing"
+)
+
+type Host string
+
+type SlicesConfig struct {
+	Hosts  []Host   `env:"HOSTS,sep=;"`
+	Tags   []string // comma separated by default.
+	Empty  []string
+	Paths  *[]string         `env:",sep=:"`
+	Labels map[string]string `env:",inline"`
+}
+
+func TestStringSlices(t *testing.T) {
+	paths := []string{"/bin", "/usr/bin"}
+	cfg := SlicesConfig{
+		Hosts:  []Host{"a.local", "b.local"},
+		Tags:   []string{"x", "y z"},
+		Paths:  &paths,
+		Labels: map[string]string{"tier": "1", "app": "a=b"},
+	}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := `HOSTS='a.local;b.local'
+TAGS='x,y z'
+EMPTY=''
+PATHS='/bin:/usr/bin'
+LABELS='app=a=b,tier=1'
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	env := map[string]string{}
+	for _, kv := range kvl {
+		env[kv.Key] = kv.Value
+	}
+	res := SlicesConfig{}
+	if errors = SetFrom(MapLookup(env), "", &res); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	cfg.Empty = []string{}
+	if !reflect.DeepEqual(res, cfg) {
+		t.Errorf("round trip mismatch %+v vs %+v", res, cfg)
+	}
+	bad := SlicesConfig{Tags: []string{"a,b"}, Labels: map[string]string{"k": "v,w"}}
+	if _, errors = StructToEnvVars(bad); len(errors) != 2 {
+		t.Errorf("expected 2 errors for values containing the separator, got %v", errors)
+	}
+	if errors = SetFrom(MapLookup(map[string]string{"LABELS": "a=1,b"}), "", &res); len(errors) != 1 {
+		t.Errorf("expected 1 error, got %v", errors)
+	}
+}
+
+type Port uint16
+
+type NumbersConfig struct {
+	Ports   []Port
+	IDs     []int64 `env:"IDS,sep= "`
+	Weights []float64
+	Small   []float32
+	Offsets *[]int
+	Empty   []int
+}
+
+func TestNumberSlices(t *testing.T) {
+	offsets := []int{-1, 0, 1}
+	cfg := NumbersConfig{
+		Ports:   []Port{80, 443},
+		IDs:     []int64{1 << 40, -7},
+		Weights: []float64{0.25, 1e-7, 3},
+		Small:   []float32{0.1},
+		Offsets: &offsets,
+	}
+	kvl, errors := StructToEnvVars(cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	str := ToShellWithPrefix("", kvl, true)
+	expected := `PORTS='80,443'
+IDS='1099511627776 -7'
+WEIGHTS='0.25,1e-07,3'
+SMALL='0.1'
+OFFSETS='-1,0,1'
+EMPTY=''
+`
+	if str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+	res := NumbersConfig{}
+	if errors = SetFrom(MapLookup(ToJSONMap(kvl)), "", &res); len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	cfg.Empty = []int{}
+	if !reflect.DeepEqual(res, cfg) {
+		t.Errorf("round trip mismatch %+v vs %+v", res, cfg)
+	}
+	env := map[string]string{"PORTS": " 8080 , 8443", "WEIGHTS": "1,x", "SMALL": "", "OFFSETS": "1,,2", "EMPTY": "70000"}
+	res = NumbersConfig{}
+	errors = SetFrom(MapLookup(env), "", &res)
+	if len(errors) != 2 || !reflect.DeepEqual(res.Ports, []Port{8080, 8443}) || res.Small == nil || len(res.Small) != 0 {
+		t.Errorf("unexpected %+v %v", res, errors)
+	}
+	expectedErr := `invalid element #2 "x" of WEIGHTS="1,x": strconv.ParseFloat: parsing "x": invalid syntax`
+	if len(errors) == 2 && errors[0].Error() != expectedErr {
+		t.Errorf("unexpected error %v", errors[0])
+	}
+	type Overflow struct {
+		Bytes []int8
+	}
+	if errors = SetFrom(MapLookup(map[string]string{"BYTES": "1,200"}), "", &Overflow{}); len(errors) != 1 {
+		t.Errorf("expected a range error, got %v", errors)
+	}
+	type BadSep struct {
+		Values []float64 `env:",sep=."`
+	}
+	if _, errors = StructToEnvVars(BadSep{Values: []float64{1.5}}); len(errors) != 1 {
+		t.Errorf("expected a separator error, got %v", errors)
+	}
+	e := NewEncoder()
+	e.FloatFormat = FloatDecimal
+	kvl, _ = e.StructToEnvVars(NumbersConfig{Weights: []float64{1e-7}})
+	if kvl[2].Value != "0.0000001" {
+		t.Errorf("unexpected decimal float %v", kvl[2])
+	}
+}
@@ -0,0 +1,92 @@
+package struct2env
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// ScanNUL is a bufio.SplitFunc for NUL separated records, as found in /proc/<pid>/environ
+// or the output of `env -0`.
+func ScanNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil // request more data.
+}
+
+// MaxRecordSize is the maximum size of a KEY=value record for SetFromReader, well above the 64KB default
+// of bufio.Scanner for the large values (e.g. base64 encoded []byte). Use sc.Buffer(nil, MaxRecordSize) to
+// get the same with SetFromScanner.
+const MaxRecordSize = 16 << 20
+
+// SetFromReader sets the fields of s from the KEY=value lines read from r (e.g. the output of `env`),
+// see Decoder.SetFromScanner. Lines can be up to MaxRecordSize long.
+func SetFromReader(r io.Reader, prefix string, s interface{}) []error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, MaxRecordSize)
+	return NewDecoder(nil).SetFromScanner(sc, prefix, s)
+}
+
+// SetFromScanner sets the fields of s from the KEY=value records produced by sc (lines by default,
+// use sc.Split(ScanNUL) for NUL separated ones); the Decoder's Lookup, Keys and Sources are not used.
+// Only the records for the variables s uses (or starting with prefix, for map fields) are kept as they
+// are read, so huge inputs like environment dumps can be processed. Like for exec.Cmd, when a key is
+// present more than once the last value wins. Malformed records (without =) are ignored.
+func (d *Decoder) SetFromScanner(sc *bufio.Scanner, prefix string, s interface{}) []error {
+	// Use an Encoder with the same options to find out the keys s uses (including the readonly fields).
+	keysFinder := Encoder{Options: d.Options, inputs: true, keepEmpty: true}
+	keysFinder.OmitEmpty = false
+	keysFinder.OmitNilPointers = false
+	keysFinder.AutoPrefix = false     // applied below, like SetFrom does.
+	kvl, _ := keysFinder.toEnvVars(s) // errors will be reported by SetFrom below if relevant.
+	if prefix == "" && d.AutoPrefix {
+		prefix = d.typePrefix(s)
+	}
+	known := make(map[string]bool, len(kvl))
+	sep := d.keyMapper().Sep
+	for _, kv := range kvl {
+		known[kv.prefixedKey(prefix)] = true
+		if d.PrefixFallback && !kv.NoPrefix {
+			// A superset of the names SetFrom tries (which only drops the levels of the prefix).
+			for _, name := range fallbackNames(prefix+kv.Key, "", sep) {
+				known[name] = true
+			}
+		}
+		for _, alias := range kv.Aliases {
+			if !kv.NoPrefix {
+				alias = prefix + alias
+			}
+			known[alias] = true
+		}
+	}
+	if d.CheckContractHash {
+		known[prefix+ContractHashKey] = true
+	}
+	matches := make(map[string]string)
+	for sc.Scan() {
+		k, v, ok := splitEnviron(strings.TrimSuffix(sc.Text(), "\r"))
+		if !ok {
+			continue
+		}
+		if known[k] || (prefix != "" && strings.HasPrefix(k, prefix)) {
+			matches[k] = v
+		}
+	}
+	var allErrors []error
+	if err := sc.Err(); err != nil {
+		allErrors = append(allErrors, err)
+	}
+	dec := *d
+	dec.Lookup = MapLookup(matches)
+	dec.Keys = MapKeys(matches)
+	dec.Sources = nil
+	return append(allErrors, dec.SetFrom(prefix, s)...)
+}
@@ -0,0 +1,157 @@
+package struct2env
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type StreamConfig struct {
+	Port   int
+	Proxy  string `env:"HTTP_PROXY,noprefix"`
+	Labels map[string]string
+}
+
+func TestSetFromReader(t *testing.T) {
+	input := "PATH=/bin\nS_PORT=1\r\nHTTP_PROXY=http://p\nmalformed\n\nS_LABELS_TEAM=infra\nS_PORT=8080\nOTHER=x=y\n"
+	cfg := StreamConfig{}
+	errs := SetFromReader(strings.NewReader(input), "S_", &cfg)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	expected := StreamConfig{Port: 8080, Proxy: "http://p", Labels: map[string]string{"team": "infra"}}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %+v got %+v", expected, cfg)
+	}
+}
+
+func TestSetFromReaderReadOnly(t *testing.T) {
+	type Cfg struct {
+		Token   string `env:",readonly"`
+		Started string `env:",writeonly"`
+	}
+	cfg := Cfg{}
+	errs := SetFromReader(strings.NewReader("TOKEN=secret\nSTARTED=now\n"), "", &cfg)
+	if len(errs) != 0 || cfg.Token != "secret" || cfg.Started != "" {
+		t.Errorf("unexpected %v %+v", errs, cfg)
+	}
+}
+
+func TestSetFromReaderAliases(t *testing.T) {
+	type Cfg struct {
+		Timeout int    `env:",alias=OLD_TIMEOUT"`
+		Proxy   string `env:"PROXY,noprefix,alias=LEGACY_PROXY"`
+	}
+	cfg := Cfg{}
+	errs := SetFromReader(strings.NewReader("APP_OLD_TIMEOUT=5\nLEGACY_PROXY=p\n"), "APP_", &cfg)
+	if len(errs) != 0 || cfg.Timeout != 5 || cfg.Proxy != "p" {
+		t.Errorf("unexpected %v %+v", errs, cfg)
+	}
+}
+
+func TestSetFromReaderOmitted(t *testing.T) {
+	type Cfg struct {
+		Name  string `env:",omitempty"`
+		Port  int
+		Proxy *string
+	}
+	d := NewDecoder(nil)
+	d.OmitEmpty = true
+	d.OmitNilPointers = true
+	cfg := Cfg{}
+	errs := d.SetFromScanner(bufio.NewScanner(strings.NewReader("NAME=n\nPORT=80\nPROXY=p\n")), "", &cfg)
+	if len(errs) != 0 || cfg.Name != "n" || cfg.Port != 80 || cfg.Proxy == nil || *cfg.Proxy != "p" {
+		t.Errorf("unexpected %v %+v", errs, cfg)
+	}
+}
+
+func TestSetFromReaderPrefixFallback(t *testing.T) {
+	type DB struct {
+		MaxConns int
+	}
+	type Cfg struct {
+		Timeout int
+		Port    int
+		DB      DB
+	}
+	input := "TIMEOUT=7\nSERVICE_PORT=8\nPORT=9\nDB_MAX_CONNS=3\nOTHER=x\n"
+	d := NewDecoder(nil)
+	d.PrefixFallback = true
+	cfg := Cfg{}
+	errs := d.SetFromScanner(bufio.NewScanner(strings.NewReader(input)), "APP_SERVICE_", &cfg)
+	if len(errs) != 0 || cfg.Timeout != 7 || cfg.Port != 8 || cfg.DB.MaxConns != 3 {
+		t.Errorf("unexpected %v %+v", errs, cfg)
+	}
+}
+
+func TestSetFromReaderContractHash(t *testing.T) {
+	type Cfg struct {
+		Port int
+	}
+	d := NewDecoder(nil)
+	d.CheckContractHash = true
+	cfg := Cfg{}
+	input := "PORT=80\n" + ContractHashKey + "=deadbeef\n"
+	errs := d.SetFromScanner(bufio.NewScanner(strings.NewReader(input)), "", &cfg)
+	if len(errs) != 1 || !errors.Is(errs[0], ErrContractMismatch) {
+		t.Errorf("expected a mismatch error, got %v", errs)
+	}
+	input = "PORT=80\n" + ContractHashKey + "=" + ContractHash(cfg) + "\n"
+	if errs = d.SetFromScanner(bufio.NewScanner(strings.NewReader(input)), "", &cfg); len(errs) != 0 || cfg.Port != 80 {
+		t.Errorf("unexpected %v %+v", errs, cfg)
+	}
+}
+
+func TestSetFromReaderLongRecord(t *testing.T) {
+	type Cfg struct {
+		Cert []byte
+		Port int
+	}
+	cert := bytes.Repeat([]byte{0xca, 0xfe}, 100_000)
+	input := "CERT=" + base64.StdEncoding.EncodeToString(cert) + "\nPORT=443\n"
+	if len(input) <= 64*1024 {
+		t.Fatalf("input should be over the default scanner limit: %d", len(input))
+	}
+	cfg := Cfg{}
+	errs := SetFromReader(strings.NewReader(input), "", &cfg)
+	if len(errs) != 0 || !bytes.Equal(cfg.Cert, cert) || cfg.Port != 443 {
+		t.Errorf("unexpected %v %d %d", errs, len(cfg.Cert), cfg.Port)
+	}
+}
+
+func TestSetFromScannerNUL(t *testing.T) {
+	type StreamConf struct {
+		Port int
+	}
+	input := "=C:=C:\\dir\x00STREAM_PORT=42\x00HOME=/root"
+	sc := bufio.NewScanner(strings.NewReader(input))
+	sc.Split(ScanNUL)
+	d := NewDecoder(nil)
+	d.AutoPrefix = true
+	d.TrimTypeSuffix = true
+	d.Trace = &Trace{}
+	cfg := StreamConf{}
+	if errs := d.SetFromScanner(sc, "", &cfg); len(errs) != 0 || cfg.Port != 42 {
+		t.Errorf("unexpected %v %+v", errs, cfg)
+	}
+	if len(d.Trace.Entries) != 1 || d.Trace.Entries[0].Names[0] != "STREAM_PORT" {
+		t.Errorf("unexpected trace %v", d.Trace)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failure")
+}
+
+func TestSetFromReaderError(t *testing.T) {
+	errs := SetFromReader(failingReader{}, "", &StreamConfig{})
+	if len(errs) != 1 || errs[0].Error() != "read failure" {
+		t.Errorf("expected read error, got %v", errs)
+	}
+}
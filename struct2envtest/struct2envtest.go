@@ -0,0 +1,32 @@
+// Package struct2envtest provides testing helpers on top of struct2env, to set up
+// realistic environments from typed fixtures.
+package struct2envtest
+
+import (
+	"os"
+	"testing"
+
+	"fortio.org/struct2env"
+)
+
+// Setenv serializes cfg (a struct or pointer to a struct) using struct2env.StructToEnvVars and sets
+// each resulting variable, with the prefix prepended (except for the noprefix ones), using t.Setenv;
+// so they get restored automatically at the end of the test. The variables of nil pointers are unset
+// instead. Like t.Setenv it can't be used in parallel tests. Serialization errors are fatal to the test.
+func Setenv(t testing.TB, prefix string, cfg interface{}) {
+	t.Helper()
+	kvl, errs := struct2env.StructToEnvVars(cfg)
+	if len(errs) != 0 {
+		t.Fatalf("struct2envtest.Setenv: unable to serialize %T: %v", cfg, errs)
+	}
+	for _, kv := range kvl {
+		key := kv.Key
+		if !kv.NoPrefix {
+			key = prefix + key
+		}
+		t.Setenv(key, kv.Value) // Also for unset ones, to get the previous value restored.
+		if kv.YamlQuotedVal == "null" {
+			os.Unsetenv(key)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package struct2envtest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"fortio.org/struct2env"
+)
+
+type fixture struct {
+	Name    string
+	Port    int
+	Debug   bool
+	Timeout time.Duration
+	Secret  []byte
+	Proxy   string `env:"S2ET_TEST_PROXY,noprefix"`
+	Opt     *string
+}
+
+func TestSetenv(t *testing.T) {
+	in := fixture{Name: "it's a test", Port: 8080, Debug: true, Timeout: 1500 * time.Millisecond, Secret: []byte{0, 1}}
+	in.Proxy = "http://proxy"
+	t.Setenv("S2ET_OPT", "previous")
+	Setenv(t, "S2ET_", in)
+	if v := os.Getenv("S2ET_NAME"); v != "it's a test" {
+		t.Errorf("unexpected S2ET_NAME %q", v)
+	}
+	if v := os.Getenv("S2ET_TEST_PROXY"); v != "http://proxy" {
+		t.Errorf("unexpected S2ET_TEST_PROXY %q", v)
+	}
+	if v, found := os.LookupEnv("S2ET_OPT"); found {
+		t.Errorf("nil pointer should be unset, got %q", v)
+	}
+	out := fixture{}
+	errors := struct2env.SetFromEnv("S2ET_", &out)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if out.Name != in.Name || out.Port != in.Port || out.Debug != in.Debug || out.Timeout != in.Timeout ||
+		string(out.Secret) != string(in.Secret) || out.Proxy != in.Proxy || out.Opt != nil {
+		t.Errorf("round trip mismatch: got %+v expected %+v", out, in)
+	}
+}
@@ -0,0 +1,157 @@
+package struct2env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TagOption describes one of the options which can follow the name in the env struct tag, as a flag
+// (`env:"NAME,secret"`) or a key=value pair (`env:"NAME,default=42"`). Options are independent of each
+// other and of their order, and unknown ones are ignored unless Options.StrictTags is set.
+type TagOption struct {
+	Name     string
+	HasValue bool   // Whether the option is a key=value one.
+	Help     string // One line description.
+}
+
+var (
+	tagOptionsMutex sync.RWMutex
+	knownTagOptions = map[string]TagOption{}
+)
+
+func init() {
+	for _, opt := range []TagOption{
+		{"noprefix", false, "the (non struct) field is a global variable, without the parent structs' or output prefix"},
+		{"omitempty", false, "the Encoder skips the field when it has its zero value"},
+		{"size", false, "integer number of bytes in human readable form, see FormatByteSize"},
+		{"percent", false, "float also accepting percentages, 75% is read as 0.75"},
+		{"precision", true, "float (or duration in seconds) written without exponent and with that many decimals"},
+		{"unix", false, "time.Time as an epoch timestamp in seconds"},
+		{"unixmilli", false, "time.Time as an epoch timestamp in milliseconds"},
+		{"duration", false, "int64 based type handled as a time.Duration"},
+		{"secret", false, "sensitive value, masked by HelpText, Redact and OmitSecrets"},
+		{"keepcase", false, "keep the case of the map keys"},
+		{"template", false, "string expanded as a text/template over the sibling fields by SetFrom"},
+		{"inline", false, "map as a single k1=v1,k2=v2 variable"},
+		{"allowreserved", false, "allow the name to be one of the Options ReservedNames"},
+		{"readonly", false, "only read by SetFrom, never emitted by the Encoder"},
+		{"writeonly", false, "only emitted by the Encoder, never read by SetFrom"},
+		{"required", false, "SetFrom error when the variable is unset or empty"},
+		{"when", true, "only read the field when the sibling field (Go or env name) is set"},
+		{"requires", true, "error if the field is set but the sibling field isn't, can be repeated"},
+		{"default", true, "value used by SetFrom when the variable isn't set (can't contain commas)"},
+		{"quote", true, "shell quoting of the value: single, ansi or bare"},
+		{"codec", true, "name of the registered Codec to use for the field"},
+		{"sep", true, "separator for []string, number slices and inline maps, defaults to a comma"},
+		{"alias", true, "previous name of the variable, still read by SetFrom, can be repeated"},
+	} {
+		knownTagOptions[opt.Name] = opt
+	}
+}
+
+// RegisterTagOption adds (or replaces) an option to the known ones, for code reading its own options
+// from the env tags to keep them valid when Options.StrictTags is used.
+func RegisterTagOption(opt TagOption) {
+	tagOptionsMutex.Lock()
+	knownTagOptions[opt.Name] = opt
+	tagOptionsMutex.Unlock()
+}
+
+// TagOptions returns the known tag options, sorted by name.
+func TagOptions() []TagOption {
+	tagOptionsMutex.RLock()
+	res := make([]TagOption, 0, len(knownTagOptions))
+	for _, opt := range knownTagOptions {
+		res = append(res, opt)
+	}
+	tagOptionsMutex.RUnlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// tagOptions is the string following a comma in a struct field's "env" tag, or the empty string.
+type tagOptions string
+
+// parseTag splits a struct field's env tag into its name and comma-separated options.
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.IndexByte(tag, ','); idx >= 0 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, ""
+}
+
+// Contains reports whether a comma-separated list of options contains a particular option.
+func (o tagOptions) Contains(optionName string) bool {
+	if len(o) == 0 {
+		return false
+	}
+	s := string(o)
+	for s != "" {
+		var name string
+		if idx := strings.IndexByte(s, ','); idx >= 0 {
+			name, s = s[:idx], s[idx+1:]
+		} else {
+			name, s = s, ""
+		}
+		if name == optionName {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the values of the optionName=value options, in order.
+func (o tagOptions) Values(optionName string) []string {
+	var res []string
+	s := string(o)
+	for s != "" {
+		var opt string
+		if idx := strings.IndexByte(s, ','); idx >= 0 {
+			opt, s = s[:idx], s[idx+1:]
+		} else {
+			opt, s = s, ""
+		}
+		if len(opt) > len(optionName) && opt[len(optionName)] == '=' && strings.HasPrefix(opt, optionName) {
+			res = append(res, opt[len(optionName)+1:])
+		}
+	}
+	return res
+}
+
+// Get returns the value of the (first) optionName=value option and whether it was found.
+func (o tagOptions) Get(optionName string) (string, bool) {
+	values := o.Values(optionName)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// check returns an error for the first unknown option, or known one used with(out) a value when it
+// shouldn't (not).
+func (o tagOptions) check(fieldName string) error {
+	tagOptionsMutex.RLock()
+	defer tagOptionsMutex.RUnlock()
+	for _, opt := range strings.Split(string(o), ",") {
+		name, hasValue := opt, false
+		if idx := strings.IndexByte(opt, '='); idx >= 0 {
+			name, hasValue = opt[:idx], true
+		}
+		known, found := knownTagOptions[name]
+		switch {
+		case opt == "":
+		case !found:
+			return fmt.Errorf("unknown tag option %q for %s", opt, fieldName)
+		case known.HasValue && !hasValue:
+			return fmt.Errorf("tag option %s of %s needs a value (%s=...)", name, fieldName, name)
+		case !known.HasValue && hasValue:
+			return fmt.Errorf("tag option %s of %s doesn't take a value", name, fieldName)
+		}
+	}
+	if o.Contains("readonly") && o.Contains("writeonly") {
+		return fmt.Errorf("tag options readonly and writeonly of %s are exclusive, use - instead", fieldName)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package struct2env
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	name, opts := parseTag("FOO,noprefix,other")
+	if name != "FOO" || !opts.Contains("noprefix") || !opts.Contains("other") || opts.Contains("nopre") {
+		t.Errorf("unexpected parse %q %q", name, opts)
+	}
+	name, opts = parseTag("BAR")
+	if name != "BAR" || opts != "" || opts.Contains("") {
+		t.Errorf("unexpected parse %q %q", name, opts)
+	}
+	name, opts = parseTag(",noprefix")
+	if name != "" || !opts.Contains("noprefix") {
+		t.Errorf("unexpected parse %q %q", name, opts)
+	}
+}
+
+func TestTagValues(t *testing.T) {
+	_, opts := parseTag("FOO,when=TLS_ENABLED,requires=A,secret,requires=B,requires")
+	if v, found := opts.Get("when"); !found || v != "TLS_ENABLED" {
+		t.Errorf("unexpected when %q %v", v, found)
+	}
+	if v := opts.Values("requires"); len(v) != 2 || v[0] != "A" || v[1] != "B" {
+		t.Errorf("unexpected requires %v", v)
+	}
+	if _, found := opts.Get("secret"); found {
+		t.Errorf("secret has no value")
+	}
+	if _, found := opts.Get("wh"); found {
+		t.Errorf("wh isn't an option")
+	}
+}
+
+func TestStrictTags(t *testing.T) {
+	type Cfg struct {
+		Name  string `env:",secrt"`
+		Port  int    `env:",default"`
+		Flag  bool   `env:",secret=yes"`
+		Other string `env:"OTHER,secret,,default=x"`
+	}
+	e := NewEncoder()
+	kvl, errs := e.StructToEnvVars(Cfg{})
+	if len(errs) != 0 || len(kvl) != 4 {
+		t.Errorf("non strict should ignore unknown options: %v %v", kvl, errs)
+	}
+	e.StrictTags = true
+	kvl, errs = e.StructToEnvVars(Cfg{})
+	if len(errs) != 3 || len(kvl) != 1 {
+		t.Fatalf("expected 3 errors, got %v %v", kvl, errs)
+	}
+	expected := []string{
+		`unknown tag option "secrt" for Name`,
+		"tag option default of Port needs a value (default=...)",
+		"tag option secret of Flag doesn't take a value",
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("unexpected error %q, expected %q", err, expected[i])
+		}
+	}
+	d := NewDecoder(MapLookup(nil))
+	d.StrictTags = true
+	if errs = d.SetFrom("", &Cfg{}); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+	RegisterTagOption(TagOption{Name: "secrt", Help: "test"})
+	if errs = d.SetFrom("", &Cfg{}); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+	type Both struct {
+		Name string `env:",readonly,writeonly"`
+	}
+	if errs = d.SetFrom("", &Both{}); len(errs) != 1 || !strings.Contains(errs[0].Error(), "exclusive") {
+		t.Errorf("expected exclusive options error, got %v", errs)
+	}
+	opts := TagOptions()
+	if opts[0].Name != "alias" || !opts[0].HasValue {
+		t.Errorf("unexpected first option %+v", opts[0])
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	type Cfg struct {
+		Name string `env:",omitempty"`
+		Port int    `env:",omitempty"`
+		Ptr  *int   `env:",omitempty"`
+	}
+	kvl, skipped, errs := StructToEnvVarsReport(Cfg{Port: 8080})
+	if len(errs) != 0 || len(kvl) != 1 || kvl[0].Key != "PORT" {
+		t.Errorf("unexpected %v %v", kvl, errs)
+	}
+	if len(skipped) != 2 || skipped[0].Reason != SkipEmpty || skipped[1].Key != "PTR" {
+		t.Errorf("unexpected skipped %+v", skipped)
+	}
+}
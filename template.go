@@ -0,0 +1,104 @@
+package struct2env
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs are quoting helpers for templates used with RenderTemplate, to add with
+// template.New(name).Funcs(struct2env.TemplateFuncs) before parsing:
+// shellQuote (errors on NUL), yamlQuote, dotEnvQuote and powerShellQuote.
+var TemplateFuncs = template.FuncMap{
+	"shellQuote":      ShellQuote,
+	"yamlQuote":       YamlQuote,
+	"dotEnvQuote":     DotEnvQuote,
+	"powerShellQuote": PowerShellQuote,
+}
+
+// RenderTemplate executes tmpl with kvl as data, so bespoke output formats can be produced, e.g.
+//
+//	{{range .}}{{.Key}}: {{.YamlQuotedVal}}{{"\n"}}{{end}}
+//
+// Each KeyValue exposes its Key, raw Value and pre quoted ShellQuotedVal and YamlQuotedVal (as well
+// as NoPrefix, Secret, Help and Section), see also TemplateFuncs.
+func RenderTemplate(tmpl *template.Template, kvl []KeyValue) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, kvl); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// expandTemplates executes, in field order, the value of the string fields of v (a struct) tagged with the
+// `template` option as a text/template with (a pointer to) v as data (see templateData), and replaces the value
+// by the result.
+// So a template can refer to the struct's fields, methods and the previous template fields.
+func (d *Decoder) expandTemplates(allErrors []error, path string, v reflect.Value, fields []int) []error {
+	for _, i := range fields {
+		fieldType := v.Type().Field(i)
+		fieldValue := v.Field(i)
+		if fieldValue.Kind() != reflect.String {
+			allErrors = append(allErrors, fmt.Errorf("template option is only valid for string fields, not %v (%s%s)",
+				fieldValue.Type(), path, fieldType.Name))
+			continue
+		}
+		text := fieldValue.String()
+		if !strings.Contains(text, "{{") || !fieldValue.CanSet() {
+			continue
+		}
+		tmpl, err := template.New(path + fieldType.Name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			allErrors = append(allErrors, fmt.Errorf("invalid template for %s%s: %w", path, fieldType.Name, err))
+			continue
+		}
+		var sb strings.Builder
+		if err = tmpl.Execute(&sb, templateData(v)); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("error expanding template for %s%s: %w", path, fieldType.Name, err))
+			continue
+		}
+		fieldValue.SetString(sb.String())
+	}
+	return allErrors
+}
+
+// templateData returns the data for the templates of the struct v: (a pointer to) v or, when v can't be
+// interfaced (e.g. an unexported embedded struct), a map of its accessible fields (promoted ones included)
+// by name, so without the methods.
+func templateData(v reflect.Value) interface{} {
+	if !v.CanInterface() {
+		data := make(map[string]interface{}, v.NumField())
+		addTemplateFields(data, v)
+		return data
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
+
+// addTemplateFields adds the accessible fields of the struct v to data, then the ones promoted from its
+// embedded structs which aren't shadowed by a shallower field.
+func addTemplateFields(data map[string]interface{}, v reflect.Value) {
+	var embedded []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		fieldType := v.Type().Field(i)
+		fieldValue := v.Field(i)
+		if fieldType.Anonymous && fieldValue.Kind() == reflect.Struct {
+			embedded = append(embedded, fieldValue)
+		}
+		if _, found := data[fieldType.Name]; !found && fieldValue.CanInterface() {
+			data[fieldType.Name] = fieldValue.Interface()
+		}
+	}
+	for _, e := range embedded {
+		promoted := make(map[string]interface{})
+		addTemplateFields(promoted, e)
+		for name, value := range promoted {
+			if _, found := data[name]; !found {
+				data[name] = value
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package struct2env
+
+import (
+	"testing"
+	"text/template"
+)
+
+type TemplateServer struct {
+	Host string
+	Port int
+	URL  string `env:"URL,template"`
+}
+
+type TemplateConfig struct {
+	Server  TemplateServer
+	Name    string
+	Banner  string `env:",template"`
+	Literal string `env:",template"`
+	Bad     int    `env:",template"`
+}
+
+func TestTemplate(t *testing.T) {
+	cfg := TemplateConfig{
+		Server:  TemplateServer{Host: "localhost", Port: 80, URL: "http://{{.Host}}:{{.Port}}/"},
+		Banner:  "{{.Name}} at {{.Server.URL}}",
+		Literal: "no template",
+	}
+	env := map[string]string{"SERVER_HOST": "example.com", "SERVER_PORT": "8080", "NAME": "app"}
+	errors := SetFrom(MapLookup(env), "", &cfg)
+	if len(errors) != 1 { // Bad isn't a string.
+		t.Errorf("expected 1 error, got %v", errors)
+	}
+	if cfg.Server.URL != "http://example.com:8080/" {
+		t.Errorf("unexpected url %q", cfg.Server.URL)
+	}
+	if cfg.Banner != "app at http://example.com:8080/" || cfg.Literal != "no template" {
+		t.Errorf("unexpected %+v", cfg)
+	}
+	// Template from the env.
+	cfg.Server.URL = ""
+	env["SERVER_URL"] = "https://{{.Host}}"
+	SetFrom(MapLookup(env), "", &cfg)
+	if cfg.Server.URL != "https://example.com" {
+		t.Errorf("unexpected url %q", cfg.Server.URL)
+	}
+	for _, bad := range []string{"{{.Nope}}", "{{.Host"} {
+		env["SERVER_URL"] = bad
+		if errors := SetFrom(MapLookup(env), "SERVER_", &cfg.Server); len(errors) != 1 {
+			t.Errorf("expected 1 error for %q, got %v", bad, errors)
+		}
+	}
+}
+
+type templateInner struct {
+	Host string
+	URL  string `env:",template"`
+	port int
+}
+
+type TemplateEmbedConfig struct {
+	templateInner
+	Name string
+}
+
+func TestTemplateUnexportedEmbedded(t *testing.T) {
+	env := map[string]string{"HOST": "example.com", "URL": "http://{{.Host}}/", "NAME": "app"}
+	var cfg TemplateEmbedConfig
+	if errors := SetFrom(MapLookup(env), "", &cfg); len(errors) != 0 || cfg.URL != "http://example.com/" {
+		t.Errorf("unexpected %+v %v", cfg, errors)
+	}
+	env["URL"] = "{{.port}}"
+	if errors := SetFrom(MapLookup(env), "", &cfg); len(errors) != 1 {
+		t.Errorf("expected an error for the unexported field, got %v", errors)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	type Cfg struct {
+		Name string
+		Port int
+	}
+	kvl, _ := StructToEnvVars(Cfg{Name: "it's", Port: 80})
+	tmpl := template.Must(template.New("crd").Funcs(TemplateFuncs).Parse(
+		`{{range .}}{{.Key}}: {{.YamlQuotedVal}} # {{.Value}} {{dotEnvQuote .Value}}{{"\n"}}{{end}}`))
+	str, err := RenderTemplate(tmpl, kvl)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if expected := "NAME: \"it's\" # it's \"it's\"\nPORT: \"80\" # 80 '80'\n"; str != expected {
+		t.Errorf("unexpected %q vs %q", str, expected)
+	}
+	tmpl = template.Must(template.New("bad").Parse("{{.Nope}}"))
+	if _, err = RenderTemplate(tmpl, kvl); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,105 @@
+package struct2env
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// NamedLookup is an EnvLookup with a name (e.g. "env", "config.env", "defaults") used to
+// report which source provided a value, see Decoder.Sources and Trace.
+type NamedLookup struct {
+	Name   string
+	Lookup EnvLookup
+}
+
+// lookup returns the value for key from the first of the Decoder's Sources having it,
+// along with that source's name, or from Lookup (with an empty source name) when there are no Sources.
+func (d *Decoder) lookup(key string) (string, string, bool) {
+	if len(d.Sources) == 0 {
+		val, found := d.Lookup(key)
+		return val, "", found && (val != "" || !d.EmptyAsUnset)
+	}
+	for _, s := range d.Sources {
+		if val, found := s.Lookup(key); found && (val != "" || !d.EmptyAsUnset) {
+			return val, s.Name, true
+		}
+	}
+	return "", "", false
+}
+
+// TraceEntry describes how a single field got resolved.
+type TraceEntry struct {
+	Field  string   // Go path of the field, e.g. "Server.Port".
+	Names  []string // Environment variable names probed, in order.
+	Source string   // Name of the source which had the value, see Decoder.Sources.
+	Found  bool     // Whether a value was found.
+	Value  string   // Raw value found.
+	Err    error    // Error setting the field from Value, if any.
+}
+
+// Result returns a short description of the outcome: "unset", "ok" or "error: ...".
+func (e TraceEntry) Result() string {
+	switch {
+	case e.Err != nil:
+		return "error: " + e.Err.Error()
+	case !e.Found:
+		return "unset"
+	default:
+		return "ok"
+	}
+}
+
+// Trace records the resolution of each field by a Decoder (when set as its Trace),
+// to diagnose where configuration values come from, or why they don't.
+type Trace struct {
+	Entries []TraceEntry
+}
+
+func (t *Trace) add(field string, names []string, source string, val *string, errs []error) {
+	e := TraceEntry{Field: field, Names: names, Source: source, Found: val != nil}
+	if val != nil {
+		e.Value = *val
+	}
+	if len(errs) == 1 {
+		e.Err = errs[0]
+	} else if len(errs) > 1 {
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		e.Err = errors.New(strings.Join(msgs, "; "))
+	}
+	t.Entries = append(t.Entries, e)
+}
+
+// Errors returns the entries which had an error.
+func (t *Trace) Errors() []TraceEntry {
+	var res []TraceEntry
+	for _, e := range t.Entries {
+		if e.Err != nil {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// String renders the trace as an aligned text table, one line per field.
+func (t *Trace) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tNAMES\tSOURCE\tVALUE\tRESULT")
+	for _, e := range t.Entries {
+		source, value := "-", "-"
+		if e.Found {
+			value = fmt.Sprintf("%q", e.Value)
+			if e.Source != "" {
+				source = e.Source
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Field, strings.Join(e.Names, ","), source, value, e.Result())
+	}
+	w.Flush()
+	return sb.String()
+}
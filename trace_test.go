@@ -0,0 +1,50 @@
+package struct2env
+
+import (
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	type Inner struct {
+		Port int
+	}
+	type Cfg struct {
+		Name    string
+		Count   int
+		Missing string
+		Server  Inner
+	}
+	env := map[string]string{"T_NAME": "from env", "T_COUNT": "x"}
+	defaults := map[string]string{"T_NAME": "default", "T_SERVER_PORT": "8080"}
+	d := NewDecoder(nil)
+	d.Sources = []NamedLookup{{"env", MapLookup(env)}, {"defaults", MapLookup(defaults)}}
+	d.Trace = &Trace{}
+	cfg := Cfg{}
+	errors := d.SetFrom("T_", &cfg)
+	if len(errors) != 1 {
+		t.Errorf("expected 1 error, got %v", errors)
+	}
+	if cfg.Name != "from env" || cfg.Server.Port != 8080 {
+		t.Errorf("unexpected result %+v", cfg)
+	}
+	entries := d.Trace.Entries
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %+v", entries)
+	}
+	e := entries[3]
+	if e.Field != "Server.Port" || e.Names[0] != "T_SERVER_PORT" || e.Source != "defaults" || e.Value != "8080" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if len(d.Trace.Errors()) != 1 || d.Trace.Errors()[0].Field != "Count" {
+		t.Errorf("unexpected errors entries %+v", d.Trace.Errors())
+	}
+	expected := `FIELD        NAMES          SOURCE    VALUE       RESULT
+Name         T_NAME         env       "from env"  ok
+Count        T_COUNT        env       "x"         error: ` + errors[0].Error() + `
+Missing      T_MISSING      -         -           unset
+Server.Port  T_SERVER_PORT  defaults  "8080"      ok
+`
+	if str := d.Trace.String(); str != expected {
+		t.Errorf("\n---expected:---\n%s\n---got:---\n%s", expected, str)
+	}
+}
@@ -0,0 +1,88 @@
+package struct2env
+
+import (
+	"reflect"
+	"sync"
+)
+
+// structInfo is what is known about a struct type independently of its values and of the Options:
+// the parsed env tags of its fields and the decoding order. Computed once per type (see Warmup) and
+// shared, read only, by all the Encoders and Decoders.
+type structInfo struct {
+	fields []fieldInfo
+	order  []int // Decoding order, see fieldsOrder.
+}
+
+// fieldInfo is the parsed env tag of a field.
+type fieldInfo struct {
+	tag   string // Name from the tag, empty when absent, "-" for excluded fields.
+	opts  tagOptions
+	snake string // The key segment with the default KeyMapper (UpperSnakeKeys), when there is no tag name.
+}
+
+var structInfos sync.Map // reflect.Type -> *structInfo
+
+// cachedStructInfo returns the structInfo of the struct type t, computing it on first use.
+func cachedStructInfo(t reflect.Type) *structInfo {
+	if si, found := structInfos.Load(t); found {
+		return si.(*structInfo)
+	}
+	si := &structInfo{fields: make([]fieldInfo, t.NumField())}
+	for i := range si.fields {
+		fieldType := t.Field(i)
+		f := &si.fields[i]
+		f.tag, f.opts = parseTag(fieldType.Tag.Get("env"))
+		if f.tag == "" {
+			f.snake = UpperSnakeKeys.Field(fieldType.Name)
+		}
+	}
+	si.order = fieldsOrder(si.fields)
+	actual, _ := structInfos.LoadOrStore(t, si)
+	return actual.(*structInfo)
+}
+
+// key returns the key segment of the field i (named name) with the KeyMapper keys, avoiding
+// the conversion for the default one.
+func (si *structInfo) key(i int, name string, o *Options) string {
+	f := &si.fields[i]
+	switch {
+	case f.tag != "":
+		return f.tag
+	case o.KeyMapper.Field == nil:
+		return f.snake
+	default:
+		return o.KeyMapper.Field(name)
+	}
+}
+
+// Warmup computes and caches the information about the types of the passed values (structs or
+// pointers to structs) and of their nested structs, so the first StructToEnvVars or SetFrom on
+// them, e.g. on a server's request path, doesn't pay for it. The cache is shared by all the Encoders
+// and Decoders, whatever their Options. Safe to call concurrently.
+func Warmup(types ...interface{}) {
+	for _, s := range types {
+		t := reflect.TypeOf(s)
+		if t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		warmup(t)
+	}
+}
+
+// warmup caches the structInfo of t (if a struct) and of its struct fields, recursively.
+func warmup(t reflect.Type) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return
+	}
+	if _, found := structInfos.Load(t); found {
+		return
+	}
+	cachedStructInfo(t)
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		warmup(ft)
+	}
+}
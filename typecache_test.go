@@ -0,0 +1,64 @@
+package struct2env
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type warmupNode struct {
+	Name  string `env:"NODE_NAME,secret"`
+	Inner struct{ A int }
+	Next  *warmupNode
+}
+
+func TestWarmup(t *testing.T) {
+	Warmup(&warmupNode{}, 42, nil)
+	si, found := structInfos.Load(reflect.TypeOf(warmupNode{}))
+	if !found {
+		t.Fatalf("warmupNode not cached")
+	}
+	info := si.(*structInfo)
+	if f := info.fields[0]; f.tag != "NODE_NAME" || !f.opts.Contains("secret") || f.snake != "" {
+		t.Errorf("unexpected %+v", f)
+	}
+	if f := info.fields[1]; f.snake != "INNER" {
+		t.Errorf("unexpected %+v", f)
+	}
+	if _, found = structInfos.Load(reflect.TypeOf(warmupNode{}.Inner)); !found {
+		t.Errorf("nested struct not cached")
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	type Cfg struct {
+		Name  string `groups:"public"`
+		Port  int
+		Inner struct {
+			A string
+		} `groups:"public"`
+	}
+	e := NewEncoder()
+	e.Groups = []string{"public"}
+	d := NewDecoder(MapLookup(map[string]string{"NAME": "n", "PORT": "80", "INNER_A": "a"}))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cfg := Cfg{}
+				if errs := d.SetFrom("", &cfg); len(errs) != 0 || cfg.Port != 80 {
+					t.Errorf("unexpected %+v %v", cfg, errs)
+					return
+				}
+				kvl, errs := e.StructToEnvVars(cfg)
+				if len(errs) != 0 || len(kvl) != 2 {
+					t.Errorf("unexpected %v %v", kvl, errs)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
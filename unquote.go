@@ -0,0 +1,244 @@
+package struct2env
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The unquoting functions below are the inverses of the quoting ones: for any valid UTF-8
+// input without NUL, XxxUnquote(XxxQuote(input)) == input (which the fuzz tests check). They
+// also accept the other common ways of writing values in their syntax, so they can be used to
+// parse human edited files.
+
+// ShellUnquote returns the value of a POSIX shell word as produced by ShellQuote: single quoted parts
+// are taken literally, double quoted ones with their \$ \` \" \\ escapes and unquoted characters
+// (backslash escaped or not) are concatenated. Words which the shell would expand or split ($, `,
+// globs, spaces... outside of quotes) are errors as their value can't be known without a shell.
+func ShellUnquote(input string) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(input))
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(input[i+1:], '\'')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated single quote in %q", input)
+			}
+			sb.WriteString(input[i+1 : i+1+end])
+			i += end + 2
+		case c == '"':
+			n, err := shellDoubleQuoted(&sb, input, i+1)
+			if err != nil {
+				return "", err
+			}
+			i = n
+		case c == '\\':
+			if i+1 >= len(input) {
+				return "", fmt.Errorf("trailing backslash in %q", input)
+			}
+			if input[i+1] != '\n' { // backslash newline is a line continuation.
+				sb.WriteByte(input[i+1])
+			}
+			i += 2
+		case c >= utf8.RuneSelf || isBareSafe(input[i:i+1]):
+			sb.WriteByte(c)
+			i++
+		default:
+			return "", fmt.Errorf("unquoted %q in %q would be interpreted by the shell", c, input)
+		}
+	}
+	return sb.String(), nil
+}
+
+// shellDoubleQuoted writes the content of the double quoted string of input starting at i (after the
+// opening quote) to sb and returns the index after the closing quote.
+func shellDoubleQuoted(sb *strings.Builder, input string, i int) (int, error) {
+	for i < len(input) {
+		c := input[i]
+		switch c {
+		case '"':
+			return i + 1, nil
+		case '\\':
+			if i+1 < len(input) && strings.IndexByte("$`\"\\\n", input[i+1]) >= 0 {
+				if input[i+1] != '\n' {
+					sb.WriteByte(input[i+1])
+				}
+				i += 2
+				continue
+			}
+		case '$', '`':
+			return 0, fmt.Errorf("%q in double quotes in %q would be expanded by the shell", c, input)
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return 0, fmt.Errorf("unterminated double quote in %q", input)
+}
+
+// DotEnvUnquote returns the value of a dotenv file entry as produced by DotEnvQuote: single quoted
+// values are literal, double quoted ones have their \n, \r, \" and \\ escapes interpreted (other
+// backslashes are kept as is) and $$ replaced by $ (like docker compose does), unquoted values are returned
+// as is.
+func DotEnvUnquote(input string) (string, error) {
+	if input == "" || (input[0] != '\'' && input[0] != '"') {
+		return input, nil
+	}
+	q := input[0]
+	if len(input) < 2 || input[len(input)-1] != q {
+		return "", fmt.Errorf("unterminated %c quote in %q", q, input)
+	}
+	inner := input[1 : len(input)-1]
+	if q == '\'' {
+		if strings.IndexByte(inner, '\'') >= 0 {
+			return "", fmt.Errorf("single quote inside single quoted %q", input)
+		}
+		return inner, nil
+	}
+	var sb strings.Builder
+	sb.Grow(len(inner))
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case c == '"':
+			return "", fmt.Errorf("unescaped double quote inside double quoted %q", input)
+		case c == '\\' && i+1 < len(inner):
+			i++
+			switch inner[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case '"', '\\':
+				sb.WriteByte(inner[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(inner[i])
+			}
+		case c == '\\':
+			return "", fmt.Errorf("trailing backslash in %q", input)
+		case c == '$' && i+1 < len(inner) && inner[i+1] == '$':
+			i++
+			sb.WriteByte('$')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// YamlUnquote returns the value of a YAML scalar as produced by YamlQuote (double quoted, with
+// backslash escapes) or the single quoted (where quotes are doubled) or plain forms.
+func YamlUnquote(input string) (string, error) {
+	switch {
+	case input == "":
+		return "", nil
+	case input[0] == '"':
+		return strconv.Unquote(input)
+	case input[0] == '\'':
+		if len(input) < 2 || input[len(input)-1] != '\'' {
+			return "", fmt.Errorf("unterminated single quote in %q", input)
+		}
+		inner := input[1 : len(input)-1]
+		if strings.Count(inner, "'") != 2*strings.Count(inner, "''") {
+			return "", fmt.Errorf("single quote not doubled inside single quoted %q", input)
+		}
+		return strings.ReplaceAll(inner, "''", "'"), nil
+	default:
+		return input, nil
+	}
+}
+
+// isPowerShellQuote returns whether r is one of the single quotes of PowerShell, see PowerShellQuote.
+func isPowerShellQuote(r rune) bool {
+	return r == '\'' || r == '‘' || r == '’' || r == '‚' || r == '‛'
+}
+
+// PowerShellUnquote returns the value of a PowerShell single quoted (verbatim) string as produced by
+// PowerShellQuote, where quotes are escaped by doubling them.
+func PowerShellUnquote(input string) (string, error) {
+	first, size := utf8.DecodeRuneInString(input)
+	last, lastSize := utf8.DecodeLastRuneInString(input)
+	if !isPowerShellQuote(first) || !isPowerShellQuote(last) || len(input) < size+lastSize {
+		return "", fmt.Errorf("%q isn't a PowerShell single quoted string", input)
+	}
+	inner := input[size : len(input)-lastSize]
+	var sb strings.Builder
+	sb.Grow(len(inner))
+	for i := 0; i < len(inner); {
+		r, n := utf8.DecodeRuneInString(inner[i:])
+		if isPowerShellQuote(r) {
+			next, m := utf8.DecodeRuneInString(inner[i+n:])
+			if i+n >= len(inner) || !isPowerShellQuote(next) {
+				return "", fmt.Errorf("quote not doubled inside PowerShell string %q", input)
+			}
+			n += m
+		}
+		sb.WriteRune(r)
+		i += n
+	}
+	return sb.String(), nil
+}
+
+// ANSICUnquote returns the value of a bash ANSI-C quoted string ($'...') as produced by ANSICQuote.
+// The \a \b \e \E \f \n \r \t \v \\ \' \" \? \xHH and \NNN (octal) escapes are supported. NUL, which
+// would truncate the value, is an error.
+func ANSICUnquote(input string) (string, error) {
+	if len(input) < 3 || !strings.HasPrefix(input, "$'") || input[len(input)-1] != '\'' {
+		return "", fmt.Errorf("%q isn't an ANSI-C quoted string", input)
+	}
+	inner := input[2 : len(input)-1]
+	var sb strings.Builder
+	sb.Grow(len(inner))
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		if c == '\'' {
+			return "", fmt.Errorf("unescaped single quote inside %q", input)
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(inner) {
+			return "", fmt.Errorf("trailing backslash in %q", input)
+		}
+		c = inner[i]
+		if idx := strings.IndexByte(`abeEfnrtv\'"?`, c); idx >= 0 {
+			sb.WriteByte("\a\b\x1b\x1b\f\n\r\t\v\\'\"?"[idx])
+			continue
+		}
+		base, maxDigits, start := 8, 3, i
+		if c == 'x' {
+			base, maxDigits, start = 16, 2, i+1
+		}
+		end := start
+		for end < len(inner) && end-start < maxDigits && isDigit(inner[end], base) {
+			end++
+		}
+		if end == start {
+			return "", fmt.Errorf("invalid escape \\%c in %q", c, input)
+		}
+		v, _ := strconv.ParseUint(inner[start:end], base, 16)
+		if v == 0 || v > 0xff {
+			return "", fmt.Errorf("invalid escape \\%s in %q", inner[i:end], input)
+		}
+		sb.WriteByte(byte(v))
+		i = end - 1
+	}
+	return sb.String(), nil
+}
+
+// isDigit returns whether c is a digit in base 8 or 16.
+func isDigit(c byte, base int) bool {
+	switch {
+	case c >= '0' && c <= '7':
+		return true
+	case base == 8:
+		return false
+	default:
+		return (c >= '8' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	}
+}
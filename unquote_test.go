@@ -0,0 +1,62 @@
+package struct2env
+
+import "testing"
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		name  string
+		fn    func(string) (string, error)
+		input string
+		want  string
+		err   bool
+	}{
+		{"shell", ShellUnquote, `'it'\''s'`, "it's", false},
+		{"shell", ShellUnquote, `"a \$b \"c\" \\ \x"`, `a $b "c" \ \x`, false},
+		{"shell", ShellUnquote, `foo\ bar'baz'"qux"`, "foo barbazqux", false},
+		{"shell", ShellUnquote, `abc/-_=:,.@%+é`, "abc/-_=:,.@%+é", false},
+		{"shell", ShellUnquote, "", "", false},
+		{"shell", ShellUnquote, `a b`, "", true},
+		{"shell", ShellUnquote, `$HOME`, "", true},
+		{"shell", ShellUnquote, `"$HOME"`, "", true},
+		{"shell", ShellUnquote, `'abc`, "", true},
+		{"shell", ShellUnquote, `"abc`, "", true},
+		{"shell", ShellUnquote, `abc\`, "", true},
+		{"dotenv", DotEnvUnquote, `'a "b" \n'`, `a "b" \n`, false},
+		{"dotenv", DotEnvUnquote, `"it's\n\"x\" \\ \t"`, "it's\n\"x\" \\ \\t", false},
+		{"dotenv", DotEnvUnquote, `"it's $$HOME $x $$$"`, "it's $HOME $x $$", false},
+		{"dotenv", DotEnvUnquote, `'$$HOME'`, "$$HOME", false},
+		{"dotenv", DotEnvUnquote, `bare`, "bare", false},
+		{"dotenv", DotEnvUnquote, `'a'b'`, "", true},
+		{"dotenv", DotEnvUnquote, `"a"b"`, "", true},
+		{"dotenv", DotEnvUnquote, `"abc`, "", true},
+		{"dotenv", DotEnvUnquote, `"abc\"`, "", true},
+		{"yaml", YamlUnquote, `"a\nbé"`, "a\nbé", false},
+		{"yaml", YamlUnquote, `'it''s'`, "it's", false},
+		{"yaml", YamlUnquote, `plain`, "plain", false},
+		{"yaml", YamlUnquote, `'it's'`, "", true},
+		{"yaml", YamlUnquote, `"abc`, "", true},
+		{"powershell", PowerShellUnquote, `'it''s ‘’x’’'`, "it's ‘x’", false},
+		{"powershell", PowerShellUnquote, `''`, "", false},
+		{"powershell", PowerShellUnquote, `'it's'`, "", true},
+		{"powershell", PowerShellUnquote, `'`, "", true},
+		{"powershell", PowerShellUnquote, `abc`, "", true},
+		{"ansi-c", ANSICUnquote, `$'a\nb\t\'\\\x01\x7fz\101\e'`, "a\nb\t'\\\x01\x7fzA\x1b", false},
+		{"ansi-c", ANSICUnquote, `$''`, "", false},
+		{"ansi-c", ANSICUnquote, `$'\0'`, "", true},
+		{"ansi-c", ANSICUnquote, `$'\q'`, "", true},
+		{"ansi-c", ANSICUnquote, `$'a'b'`, "", true},
+		{"ansi-c", ANSICUnquote, `'abc'`, "", true},
+	}
+	for _, tst := range tests {
+		got, err := tst.fn(tst.input)
+		if tst.err {
+			if err == nil {
+				t.Errorf("%s: expected error for %q, got %q", tst.name, tst.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tst.want {
+			t.Errorf("%s: for %q got %q, %v expected %q", tst.name, tst.input, got, err, tst.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package struct2env
+
+import (
+	"reflect"
+	"sort"
+)
+
+// EnvValuer can be implemented by (config) structs to expose additional variables to the Encoder,
+// typically values derived from normalized internal state kept in unexported fields.
+// The keys are used as is (like an `env:"KEY"` tag would be), prefixed like the struct's fields, and
+// are emitted in sorted order after them. The Decoder doesn't use it: the struct can read the same
+// variables itself, e.g. through exported fields tagged `env:"-"` or a normalization step after SetFrom.
+type EnvValuer interface {
+	EnvValues() map[string]string
+}
+
+var envValuerType = reflect.TypeOf((*EnvValuer)(nil)).Elem()
+
+// envValuer returns v (a struct), or its address, as an EnvValuer when it implements it.
+func envValuer(v reflect.Value) (EnvValuer, bool) {
+	if !v.Type().Implements(envValuerType) && v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.Type().Implements(envValuerType) || !v.CanInterface() {
+		return nil, false
+	}
+	return v.Interface().(EnvValuer), true
+}
+
+// addEnvValues adds the variables of v (a struct, at index) when it, or its address, implements EnvValuer.
+func (e *Encoder) addEnvValues(
+	envVars []KeyValue, allErrors []error, prefix, section string, index []int, v reflect.Value,
+) ([]KeyValue, []error) {
+	valuer, ok := envValuer(v)
+	if !ok {
+		return envVars, allErrors
+	}
+	values := valuer.EnvValues()
+	index = appendIndex(index, v.NumField()) // After all the fields.
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if e.stopped {
+			break
+		}
+		res := KeyValue{Key: prefix + k, Section: section, Index: index}
+		err := setString(&res, values[k])
+		envVars, allErrors = e.add(envVars, allErrors, &res, err)
+	}
+	return envVars, allErrors
+}
@@ -0,0 +1,73 @@
+package struct2env
+
+import (
+	"strings"
+	"testing"
+)
+
+type DBConfig struct {
+	URL  string
+	host string `env:"-"` // normalized from URL.
+}
+
+func (c *DBConfig) EnvValues() map[string]string {
+	return map[string]string{"HOST": c.host, "SCHEME": strings.SplitN(c.URL, ":", 2)[0]}
+}
+
+type ValuerConfig struct {
+	Name string
+	DB   DBConfig
+}
+
+func TestEnvValuer(t *testing.T) {
+	cfg := ValuerConfig{Name: "app", DB: DBConfig{URL: "postgres://db.local/app", host: "db.local"}}
+	kvl, errors := StructToEnvVars(&cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	expected := "NAME='app'\nDB_URL='postgres://db.local/app'\nDB_HOST='db.local'\nDB_SCHEME='postgres'\n"
+	if str := ToShellWithPrefix("", kvl, true); str != expected {
+		t.Errorf("unexpected %q", str)
+	}
+	if kvl[3].Section != "DB" {
+		t.Errorf("unexpected section %q", kvl[3].Section)
+	}
+	// Not addressable: pointer receiver method isn't available.
+	kvl, _ = StructToEnvVars(cfg)
+	if len(kvl) != 2 {
+		t.Errorf("unexpected %v", kvl)
+	}
+	cfg.DB.host = "a\x00b"
+	if _, errors = StructToEnvVars(&cfg); len(errors) != 1 {
+		t.Errorf("expected 1 error, got %v", errors)
+	}
+}
+
+type EmbeddingValuerConfig struct {
+	DBConfig
+	Port int
+}
+
+type OverridingValuerConfig struct {
+	DBConfig
+}
+
+func (c *OverridingValuerConfig) EnvValues() map[string]string {
+	return map[string]string{"OVERRIDE": "x"}
+}
+
+func TestEmbeddedEnvValuer(t *testing.T) {
+	cfg := EmbeddingValuerConfig{DBConfig: DBConfig{URL: "mysql://h", host: "h"}, Port: 1}
+	kvl, errors := StructToEnvVars(&cfg)
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	expected := "URL='mysql://h'\nPORT='1'\nHOST='h'\nSCHEME='mysql'\nexport URL PORT HOST SCHEME\n"
+	if str := ToShellWithPrefix("", kvl, false); str != expected {
+		t.Errorf("unexpected %q", str)
+	}
+	kvl, _ = StructToEnvVars(&OverridingValuerConfig{DBConfig: cfg.DBConfig})
+	if str := ToShellWithPrefix("", kvl, true); str != "URL='mysql://h'\nOVERRIDE='x'\n" {
+		t.Errorf("unexpected %q", str)
+	}
+}